@@ -0,0 +1,255 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Options configures ziti-ci when it's used as a library. Each field corresponds to the global command line flag
+// of the same name. Fields left empty keep the flag's default. Operations act on the git repository in the
+// current working directory, as the commands do
+type Options struct {
+	Language                  string
+	BaseVersion               string
+	BaseVersionFile           string
+	VersionSource             string
+	BumpStrategy              string
+	PrereleaseChannel         string
+	AllowedPrereleaseChannels []string
+	ModuleDir                 string
+	TagPrefix                 string
+	GitBackend                string
+	// ConfigFile is the repository configuration file. Defaults to .ziti-ci.yaml at the repository root
+	ConfigFile         string
+	ReleaseBranchLines bool
+	UseCurrentTag      bool
+	DryRun             bool
+	Verbose            bool
+
+	// Out receives command output, such as release notes, and informational messages if Verbose is set
+	Out io.Writer
+	// Err receives warnings and errors
+	Err io.Writer
+}
+
+func (o *Options) flagValues() map[string]string {
+	result := map[string]string{
+		"language":           o.Language,
+		"base-version":       o.BaseVersion,
+		"base-version-file":  o.BaseVersionFile,
+		"version-source":     o.VersionSource,
+		"bump-strategy":      o.BumpStrategy,
+		"prerelease-channel": o.PrereleaseChannel,
+		"module-dir":         o.ModuleDir,
+		"tag-prefix":         o.TagPrefix,
+		"git-backend":        o.GitBackend,
+		"config":             o.ConfigFile,
+		"quiet":              strconv.FormatBool(!o.Verbose),
+		"verbose":            strconv.FormatBool(o.Verbose),
+	}
+	if len(o.AllowedPrereleaseChannels) > 0 {
+		result["allowed-prerelease-channels"] = strings.Join(o.AllowedPrereleaseChannels, ",")
+	}
+	if o.ReleaseBranchLines {
+		result["release-branch-lines"] = "true"
+	}
+	if o.UseCurrentTag {
+		result["use-current-tag"] = "true"
+	}
+	if o.DryRun {
+		result["dry-run"] = "true"
+	}
+	return result
+}
+
+// newBaseCommand returns an initialized command configured from the options, as if the flags had been given
+// on the command line
+func (o *Options) newBaseCommand(use string) (*BaseCommand, error) {
+	root := newRootCommand()
+	cobraCmd := &cobra.Command{Use: use}
+	root.RootCobraCmd.AddCommand(cobraCmd)
+	if o.Out != nil {
+		root.RootCobraCmd.SetOut(o.Out)
+	}
+	if o.Err != nil {
+		root.RootCobraCmd.SetErr(o.Err)
+	}
+
+	flags := root.RootCobraCmd.PersistentFlags()
+	for name, value := range o.flagValues() {
+		if value == "" {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return nil, configErrorf("invalid value %v for %v: %w", value, name, err)
+		}
+	}
+
+	// makes the persistent flags visible to the commands' flag sets, as executing the command would
+	if err := root.RootCobraCmd.ParseFlags(nil); err != nil {
+		return nil, configErrorf("%w", err)
+	}
+	if err := cobraCmd.ParseFlags(nil); err != nil {
+		return nil, configErrorf("%w", err)
+	}
+
+	result := &BaseCommand{
+		RootCommand: root,
+		Cmd:         cobraCmd,
+	}
+	if err := result.Init(nil); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VersionInfo is the outcome of evaluating the current and next version of a repository
+type VersionInfo struct {
+	Current     *version.Version
+	Next        *version.Version
+	CurrentTag  string
+	NextTag     string
+	BumpReasons []string
+}
+
+// EvalVersion computes the current and next version of the repository in the working directory, as
+// get-current-version and get-next-version do
+func EvalVersion(opts *Options) (*VersionInfo, error) {
+	cmd, err := opts.newBaseCommand("eval-version")
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.EvalCurrentAndNextVersion(); err != nil {
+		return nil, err
+	}
+
+	result := &VersionInfo{
+		Current: cmd.CurrentVersion,
+		Next:    cmd.NextVersion,
+		NextTag: cmd.getVersionTag(cmd.NextVersion),
+	}
+	if cmd.CurrentVersion != nil {
+		result.CurrentTag = cmd.getVersionTag(cmd.CurrentVersion)
+	}
+	for _, reason := range cmd.BumpReasons {
+		result.BumpReasons = append(result.BumpReasons, reason.String())
+	}
+	return result, nil
+}
+
+// ReleaseNotesOptions holds the settings specific to the release notes commands
+type ReleaseNotesOptions struct {
+	AllCommits    bool
+	ShowUnchanged bool
+	// Sdk selects the layout of build-sdk-release-notes, rather than build-release-notes
+	Sdk bool
+	// Concurrency is the number of repositories whose changes are collected at the same time. Defaults to
+	// DefaultReleaseNotesConcurrency
+	Concurrency int
+	// MaxCommits limits the commits listed for a repository. Defaults to DefaultReleaseNotesMaxCommits, and negative
+	// values remove the limit
+	MaxCommits int
+	// Format is one of markdown, json or yaml. Defaults to markdown
+	Format string
+	// Template is a text/template file used to render markdown, instead of the built-in layout
+	Template string
+	// Categorized groups all commits into sections by conventional commit type, or issue and pull request labels
+	Categorized bool
+	// GoModDiff adds every change to go.mod, including indirect dependencies and directives, to the notes
+	GoModDiff bool
+	// ApiDiff adds the changes to the module's exported API to the notes, with Sdk
+	ApiDiff bool
+	// Contributors adds the authors of the changes to the module being released to the notes
+	Contributors bool
+	// From and To are the tags or commits to build the notes between, instead of the current version and the
+	// working tree. To requires From
+	From string
+	To   string
+}
+
+func (o *Options) newReleaseNotesCommand(notesOpts *ReleaseNotesOptions) (*baseBuildReleaseNotesCmd, error) {
+	cmd, err := o.newBaseCommand("build-release-notes")
+	if err != nil {
+		return nil, err
+	}
+	result := &baseBuildReleaseNotesCmd{
+		BaseCommand:   *cmd,
+		AllCommits:    notesOpts.AllCommits,
+		ShowUnchanged: notesOpts.ShowUnchanged,
+		Concurrency:   notesOpts.Concurrency,
+		MaxCommits:    notesOpts.MaxCommits,
+		Format:        notesOpts.Format,
+		Template:      notesOpts.Template,
+		Categorized:   notesOpts.Categorized,
+		GoModDiff:     notesOpts.GoModDiff,
+		ApiDiff:       notesOpts.ApiDiff,
+		Contributors:  notesOpts.Contributors,
+		From:          notesOpts.From,
+		To:            notesOpts.To,
+	}
+	if result.Concurrency == 0 {
+		result.Concurrency = DefaultReleaseNotesConcurrency
+	}
+	if result.MaxCommits == 0 {
+		result.MaxCommits = DefaultReleaseNotesMaxCommits
+	}
+	if result.Format == "" {
+		result.Format = ReleaseNotesFormatMarkdown
+	}
+	return result, nil
+}
+
+// BuildReleaseNotes writes the release notes for the next version to Options.Out, as build-release-notes or
+// build-sdk-release-notes do
+func BuildReleaseNotes(opts *Options, notesOpts *ReleaseNotesOptions) error {
+	base, err := opts.newReleaseNotesCommand(notesOpts)
+	if err != nil {
+		return err
+	}
+	if notesOpts.Sdk {
+		return (&buildSdkReleaseNotesCmd{baseBuildReleaseNotesCmd: *base}).Execute()
+	}
+	return (&buildReleaseNotesCmd{baseBuildReleaseNotesCmd: *base}).Execute()
+}
+
+// EvalReleaseNotes returns the content of the release notes for the next version, as build-release-notes or
+// build-sdk-release-notes would write them
+func EvalReleaseNotes(opts *Options, notesOpts *ReleaseNotesOptions) (*ReleaseNotes, error) {
+	base, err := opts.newReleaseNotesCommand(notesOpts)
+	if err != nil {
+		return nil, err
+	}
+	if notesOpts.Sdk {
+		return (&buildSdkReleaseNotesCmd{baseBuildReleaseNotesCmd: *base}).evalReleaseNotes()
+	}
+	return (&buildReleaseNotesCmd{baseBuildReleaseNotesCmd: *base}).evalReleaseNotes()
+}
+
+// Package writes the given files into a gzipped tar archive, as the package command does
+func Package(opts *Options, archiveFile string, files ...string) error {
+	cmd, err := opts.newBaseCommand("package")
+	if err != nil {
+		return err
+	}
+	return cmd.tarGzSimple(archiveFile, files...)
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	ApiChangeAdded   = "added"
+	ApiChangeRemoved = "removed"
+	ApiChangeChanged = "changed"
+)
+
+// apiDiffTemplateDefinitions render an ApiDiff, and are available to all release notes templates
+const apiDiffTemplateDefinitions = `
+{{- define "apiChange" -}}
+{{- if not .Name}}* {{.Package}}: package {{.Status}}
+{{else if eq .Status "added"}}* {{.Package}}.{{.Name}}: ` + "`{{.New}}`" + `
+{{else if eq .Status "removed"}}* {{.Package}}.{{.Name}}: removed ` + "`{{.Old}}`" + `
+{{else}}* {{.Package}}.{{.Name}}: ` + "`{{.Old}}` -> `{{.New}}`" + `
+{{end}}
+{{- end}}
+
+{{- define "apiDiff" -}}
+{{- if .Changes}}
+## API Changes
+{{with .IncompatibleChanges}}
+### Incompatible Changes
+
+{{range .}}{{template "apiChange" .}}{{end}}{{end}}
+{{- with .Additions}}
+### Additions
+
+{{range .}}{{template "apiChange" .}}{{end}}{{end}}
+{{- end}}
+{{- end}}`
+
+// ApiDiff lists the changes to the exported identifiers of a module's packages between two versions
+type ApiDiff struct {
+	Changes []*ApiChange `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// ApiChange is an exported identifier, or a whole package, which was added, removed or changed. Name is empty for a
+// package, and is qualified by its type for fields and methods, ex: Client.Dial
+type ApiChange struct {
+	Package string `json:"package" yaml:"package"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Status  string `json:"status" yaml:"status"`
+	Old     string `json:"old,omitempty" yaml:"old,omitempty"`
+	New     string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// IncompatibleChanges returns the removals and changes, which may break code using the module
+func (d *ApiDiff) IncompatibleChanges() []*ApiChange {
+	var result []*ApiChange
+	for _, change := range d.Changes {
+		if change.Status != ApiChangeAdded {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// Additions returns the added packages and identifiers
+func (d *ApiDiff) Additions() []*ApiChange {
+	var result []*ApiChange
+	for _, change := range d.Changes {
+		if change.Status == ApiChangeAdded {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// verifyApiCompatibility fails if the exported API has incompatible changes since the current version, unless the
+// next version allows them: a major version bump, or a minor bump before v1
+func (cmd *BaseCommand) verifyApiCompatibility() error {
+	currentTag := cmd.getVersionTag(cmd.CurrentVersion)
+	diff, err := cmd.evalApiDiff(currentTag, "")
+	if err != nil {
+		return err
+	}
+	incompatible := diff.IncompatibleChanges()
+	if len(incompatible) == 0 {
+		return nil
+	}
+	current, next := cmd.CurrentVersion.Segments(), cmd.NextVersion.Segments()
+	if next[0] > current[0] || (current[0] == 0 && next[1] > current[1]) {
+		cmd.Infof("%v incompatible API changes since %v, allowed by the bump to %v\n", len(incompatible), currentTag, cmd.NextVersion)
+		return nil
+	}
+	for _, change := range incompatible {
+		cmd.Errorf("%v\n", formatApiChange(change))
+	}
+	return versionErrorf("%v incompatible API changes since %v need a major version bump, but the next version is %v",
+		len(incompatible), currentTag, cmd.NextVersion)
+}
+
+func formatApiChange(change *ApiChange) string {
+	marker := map[string]string{ApiChangeAdded: "+", ApiChangeRemoved: "-", ApiChangeChanged: "~"}[change.Status]
+	if change.Name == "" {
+		return fmt.Sprintf("%v %v (package)", marker, change.Package)
+	}
+	switch change.Status {
+	case ApiChangeAdded:
+		return fmt.Sprintf("%v %v.%v: %v", marker, change.Package, change.Name, change.New)
+	case ApiChangeRemoved:
+		return fmt.Sprintf("%v %v.%v: %v", marker, change.Package, change.Name, change.Old)
+	}
+	return fmt.Sprintf("%v %v.%v: %v -> %v", marker, change.Package, change.Name, change.Old, change.New)
+}
+
+// moduleApi maps the packages of a module, by path relative to the module, to their exported identifiers and
+// declarations
+type moduleApi struct {
+	modulePath string
+	packages   map[string]map[string]string
+}
+
+// evalApiDiff compares the exported API of the current module at two revisions. An empty newRev is the working tree
+func (cmd *BaseCommand) evalApiDiff(oldRev, newRev string) (*ApiDiff, error) {
+	oldDir, cleanup, err := cmd.exportRevision(oldRev)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	oldApi, err := loadModuleApi(oldDir)
+	if err != nil {
+		return nil, versionErrorf("unable to load packages at %v: %w", oldRev, err)
+	}
+
+	newDir := valueOr(cmd.moduleDir, ".")
+	if newRev != "" {
+		if newDir, cleanup, err = cmd.exportRevision(newRev); err != nil {
+			return nil, err
+		}
+		defer cleanup()
+	}
+	newApi, err := loadModuleApi(newDir)
+	if err != nil {
+		return nil, versionErrorf("unable to load packages at %v: %w", valueOr(newRev, "working tree"), err)
+	}
+
+	return diffModuleApi(oldApi, newApi), nil
+}
+
+// exportRevision writes the files of the repository at the given revision into a temporary directory, returning
+// the directory of the current module within it, and a function which removes it
+func (cmd *BaseCommand) exportRevision(rev string) (string, func(), error) {
+	root, err := findRepoRoot()
+	if err != nil {
+		return "", nil, gitErrorf("unable to find repository root: %w", err)
+	}
+	moduleDir, err := filepath.Abs(valueOr(cmd.moduleDir, "."))
+	if err != nil {
+		return "", nil, configErrorf("invalid module directory %v: %w", cmd.moduleDir, err)
+	}
+	rel, err := filepath.Rel(root, moduleDir)
+	if err != nil {
+		return "", nil, configErrorf("module directory %v is not in the repository: %w", cmd.moduleDir, err)
+	}
+
+	r, err := git.PlainOpen(root)
+	if err != nil {
+		return "", nil, gitErrorf("unable to open repository at %v: %w", root, err)
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", nil, gitErrorf("unable to resolve %v: %w", rev, err)
+	}
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return "", nil, gitErrorf("unable to read commit %v: %w", rev, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", nil, gitErrorf("unable to read tree of %v: %w", rev, err)
+	}
+
+	dir, err := os.MkdirTemp("", "ziti-ci-api-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode == filemode.Symlink || f.Mode == filemode.Submodule {
+			return nil
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, []byte(contents), 0644)
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, gitErrorf("unable to export %v: %w", rev, err)
+	}
+	return filepath.Join(dir, rel), cleanup, nil
+}
+
+// loadModuleApi type checks the packages of the module in the given directory, and lists their exported
+// identifiers. Internal, main and test packages are left out
+func loadModuleApi(dir string) (*moduleApi, error) {
+	// dependencies are type checked from source, rather than from export data, which is tied to the Go release
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedModule | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &moduleApi{packages: map[string]map[string]string{}}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, pkg.Errors[0]
+		}
+		if pkg.Module != nil {
+			result.modulePath = pkg.Module.Path
+		}
+	}
+	for _, pkg := range pkgs {
+		rel := strings.TrimPrefix(strings.TrimPrefix(pkg.PkgPath, result.modulePath), "/")
+		if pkg.Name == "main" || slices.Contains(strings.Split(rel, "/"), "internal") {
+			continue
+		}
+		result.packages[rel] = exportedApi(pkg.Types, result.modulePath)
+	}
+	return result, nil
+}
+
+// exportedApi returns the declarations of a package's exported identifiers, including the fields and methods of its
+// types. Types from the same module are qualified by their path within it, so the declarations match across
+// major versions
+func exportedApi(pkg *types.Package, modulePath string) map[string]string {
+	qualifier := func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		if rel, found := strings.CutPrefix(other.Path(), modulePath); found {
+			return "." + rel
+		}
+		return other.Path()
+	}
+	typeString := func(t types.Type) string {
+		return types.TypeString(t, qualifier)
+	}
+
+	result := map[string]string{}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Func:
+			if obj.Exported() {
+				result[name] = "func " + name + strings.TrimPrefix(typeString(obj.Type()), "func")
+			}
+		case *types.Const:
+			if obj.Exported() {
+				result[name] = "const " + name + " " + typeString(obj.Type())
+			}
+		case *types.Var:
+			if obj.Exported() {
+				result[name] = "var " + name + " " + typeString(obj.Type())
+			}
+		case *types.TypeName:
+			if !obj.Exported() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if obj.IsAlias() || !ok {
+				result[name] = "type " + name + " = " + typeString(obj.Type())
+				continue
+			}
+			if s, ok := named.Underlying().(*types.Struct); ok {
+				// fields may be added without breaking users, so they're listed separately
+				result[name] = "type " + name + " struct"
+				for i := 0; i < s.NumFields(); i++ {
+					if field := s.Field(i); field.Exported() {
+						result[name+"."+field.Name()] = "field " + field.Name() + " " + typeString(field.Type())
+					}
+				}
+			} else {
+				result[name] = "type " + name + " " + typeString(named.Underlying())
+			}
+			methods := types.NewMethodSet(types.NewPointer(named))
+			for i := 0; i < methods.Len(); i++ {
+				method := methods.At(i).Obj()
+				if !method.Exported() {
+					continue
+				}
+				sig := method.Type().(*types.Signature)
+				receiver := name
+				if _, isPointer := sig.Recv().Type().(*types.Pointer); isPointer {
+					receiver = "*" + name
+				}
+				result[name+"."+method.Name()] = fmt.Sprintf("func (%v) %v%v", receiver, method.Name(), strings.TrimPrefix(typeString(sig), "func"))
+			}
+		}
+	}
+	return result
+}
+
+func diffModuleApi(oldApi, newApi *moduleApi) *ApiDiff {
+	result := &ApiDiff{}
+	packagePath := func(api *moduleApi, rel string) string {
+		return path.Join(api.modulePath, rel)
+	}
+	for rel, newIdents := range newApi.packages {
+		oldIdents, found := oldApi.packages[rel]
+		if !found {
+			result.Changes = append(result.Changes, &ApiChange{Package: packagePath(newApi, rel), Status: ApiChangeAdded})
+			continue
+		}
+		for name, decl := range newIdents {
+			if oldDecl, found := oldIdents[name]; !found {
+				result.Changes = append(result.Changes, &ApiChange{Package: packagePath(newApi, rel), Name: name, Status: ApiChangeAdded, New: decl})
+			} else if oldDecl != decl {
+				result.Changes = append(result.Changes, &ApiChange{Package: packagePath(newApi, rel), Name: name, Status: ApiChangeChanged, Old: oldDecl, New: decl})
+			}
+		}
+		for name, decl := range oldIdents {
+			if _, found := newIdents[name]; !found {
+				result.Changes = append(result.Changes, &ApiChange{Package: packagePath(newApi, rel), Name: name, Status: ApiChangeRemoved, Old: decl})
+			}
+		}
+	}
+	for rel := range oldApi.packages {
+		if _, found := newApi.packages[rel]; !found {
+			result.Changes = append(result.Changes, &ApiChange{Package: packagePath(oldApi, rel), Status: ApiChangeRemoved})
+		}
+	}
+	sort.Slice(result.Changes, func(i, j int) bool {
+		a, b := result.Changes[i], result.Changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+	return result
+}
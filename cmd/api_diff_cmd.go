@@ -0,0 +1,96 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ApiDiffFormatText = "text"
+	ApiDiffFormatJson = "json"
+)
+
+type apiDiffCmd struct {
+	BaseCommand
+	format string
+	check  bool
+}
+
+func (cmd *apiDiffCmd) Execute() error {
+	if cmd.format != ApiDiffFormatText && cmd.format != ApiDiffFormatJson {
+		return configErrorf("invalid format %v, expected %v or %v", cmd.format, ApiDiffFormatText, ApiDiffFormatJson)
+	}
+
+	var oldRev, newRev string
+	if len(cmd.Args) > 0 {
+		oldRev = cmd.Args[0]
+	}
+	if len(cmd.Args) > 1 {
+		newRev = cmd.Args[1]
+	}
+	if oldRev == "" {
+		if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+			return err
+		}
+		oldRev = cmd.getVersionTag(cmd.CurrentVersion)
+	}
+
+	diff, err := cmd.evalApiDiff(oldRev, newRev)
+	if err != nil {
+		return err
+	}
+
+	if cmd.format == ApiDiffFormatJson {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%v\n", string(data))
+	} else {
+		for _, change := range diff.Changes {
+			cmd.Printf("%v\n", formatApiChange(change))
+		}
+	}
+
+	if incompatible := diff.IncompatibleChanges(); cmd.check && len(incompatible) > 0 {
+		return versionErrorf("%v incompatible API changes since %v", len(incompatible), oldRev)
+	}
+	return nil
+}
+
+func newApiDiffCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "api-diff [old] [new]",
+		Short: "List the changes to the module's exported API between two tags or commits, by default the current version and the working tree",
+		Args:  cobra.MaximumNArgs(2),
+	}
+
+	result := &apiDiffCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&result.format, "format", "o", ApiDiffFormatText, "output format. Valid values: [text,json]")
+	cobraCmd.Flags().BoolVar(&result.check, "check", false, "fail if there are incompatible changes, ex: removed or changed identifiers")
+
+	return FinalizeErroringCmd(result)
+}
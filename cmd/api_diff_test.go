@@ -0,0 +1,117 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApiDiff(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3")
+
+	write := func(name, contents string) {
+		req.NoError(os.MkdirAll(filepath.Dir(name), 0755))
+		req.NoError(os.WriteFile(name, []byte(contents), 0644))
+	}
+	write("go.mod", "module example.com/widgets\n\ngo 1.22\n")
+	write("widgets.go", `package widgets
+
+type Widget struct {
+	Name string
+}
+
+func (w *Widget) Spin(times int) error { return nil }
+
+func New(name string) *Widget { return &Widget{Name: name} }
+
+func Old() {}
+
+const Max = 10
+`)
+	write("internal/helper/helper.go", "package helper\n\nfunc Help() {}\n")
+	write("legacy/legacy.go", "package legacy\n\nfunc Legacy() {}\n")
+
+	r.commitAll("add widgets", "v0.3.0")
+
+	write("widgets.go", `package widgets
+
+import "context"
+
+type Widget struct {
+	Name  string
+	Color string
+}
+
+func (w *Widget) Spin(ctx context.Context, times int) error { return nil }
+
+func New(name string) *Widget { return &Widget{Name: name} }
+
+const Max = 10
+`)
+	write("internal/helper/helper.go", "package helper\n\nfunc Help(n int) {}\n")
+	req.NoError(os.RemoveAll("legacy"))
+	write("gadgets/gadgets.go", "package gadgets\n\nfunc Gadget() {}\n")
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("api-diff")
+	req.NoError(err)
+	diff, err := cmd.evalApiDiff("v0.3.0", "")
+	req.NoError(err)
+
+	var changes []string
+	for _, change := range diff.Changes {
+		changes = append(changes, formatApiChange(change))
+	}
+	req.Equal([]string{
+		"- example.com/widgets.Old: func Old()",
+		"+ example.com/widgets.Widget.Color: field Color string",
+		"~ example.com/widgets.Widget.Spin: func (*Widget) Spin(times int) error -> func (*Widget) Spin(ctx context.Context, times int) error",
+		"+ example.com/widgets/gadgets (package)",
+		"- example.com/widgets/legacy (package)",
+	}, changes)
+	req.Len(diff.IncompatibleChanges(), 3)
+	req.Len(diff.Additions(), 2)
+
+	cmd.CurrentVersion = version.Must(version.NewVersion("0.3.0"))
+	cmd.NextVersion = version.Must(version.NewVersion("0.3.1"))
+	err = cmd.verifyApiCompatibility()
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+
+	cmd.NextVersion = version.Must(version.NewVersion("0.4.0"))
+	req.NoError(cmd.verifyApiCompatibility())
+
+	notesCmd := &buildSdkReleaseNotesCmd{baseBuildReleaseNotesCmd: baseBuildReleaseNotesCmd{BaseCommand: *cmd}}
+	notesCmd.quiet = true
+	out.Reset()
+	req.NoError(notesCmd.writeMarkdown(out, &ReleaseNotes{Version: "0.4.0", Api: diff}, sdkReleaseNotesTemplate))
+	req.Equal("# Release notes 0.4.0\n\n## Issues Fixed and Dependency Updates\n\n\n## API Changes\n\n"+
+		"### Incompatible Changes\n\n"+
+		"* example.com/widgets.Old: removed `func Old()`\n"+
+		"* example.com/widgets.Widget.Spin: `func (*Widget) Spin(times int) error` -> `func (*Widget) Spin(ctx context.Context, times int) error`\n"+
+		"* example.com/widgets/legacy: package removed\n\n"+
+		"### Additions\n\n"+
+		"* example.com/widgets.Widget.Color: `field Color string`\n"+
+		"* example.com/widgets/gadgets: package added\n", out.String())
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal(0, ExitCode(nil))
+	req.Equal(ExitCodeGeneral, ExitCode(errors.New("boom")))
+	req.Equal(ExitCodeConfig, ExitCode(configErrorf("bad flag")))
+	req.Equal(ExitCodeVersion, ExitCode(versionErrorf("bad version")))
+	req.Equal(ExitCodeGit, ExitCode(gitErrorf("bad repo")))
+	req.Equal(ExitCodeNetwork, ExitCode(networkErrorf("bad gateway")))
+	req.Equal(ExitCodeVulnerability, ExitCode(vulnerabilityErrorf("vulnerable dependency")))
+
+	wrapped := fmt.Errorf("while tagging: %w", gitErrorf("unable to list tags: %w", os.ErrNotExist))
+	req.Equal(ErrorKindGit, KindOf(wrapped))
+	req.True(errors.Is(wrapped, os.ErrNotExist))
+
+	// an error which already has a kind keeps it
+	req.Equal(ErrorKindVersion, KindOf(newCiError(ErrorKindNetwork, versionErrorf("bad version"))))
+}
+
+// chdirTestRepo creates a git repository with a version file and the given tags, one commit each, and makes it
+// the working directory for the rest of the test. The repository is its own origin, so tags can be fetched
+func chdirTestRepo(t *testing.T, baseVersion string, tags ...string) *testRepo {
+	r := newDiskTestRepo(t)
+	_, err := r.repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{r.dir()}})
+	r.req.NoError(err)
+
+	r.req.NoError(os.WriteFile(filepath.Join(r.dir(), "version"), []byte(baseVersion+"\n"), 0644))
+	for i, tag := range append([]string{""}, tags...) {
+		message := fmt.Sprintf("change %v", i)
+		if tag == "" {
+			r.commit("change.txt", message+"\n", message)
+		} else {
+			r.commit("change.txt", message+"\n", message, tag)
+		}
+	}
+
+	wd, err := os.Getwd()
+	r.req.NoError(err)
+	r.req.NoError(os.Chdir(r.dir()))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+	return r
+}
+
+func TestEvalVersion(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.2.4", "v0.3.0", "v0.3.1")
+
+	out := &bytes.Buffer{}
+	info, err := EvalVersion(&Options{Out: out, Err: out})
+	req.NoError(err)
+	req.Equal("0.3.1", info.Current.String())
+	req.Equal("0.3.2", info.Next.String())
+	req.Equal("v0.3.1", info.CurrentTag)
+	req.Equal("v0.3.2", info.NextTag)
+	req.Len(info.BumpReasons, 1)
+
+	info, err = EvalVersion(&Options{Out: out, Err: out, PrereleaseChannel: "rc"})
+	req.NoError(err)
+	req.Equal("v0.3.2-rc.1", info.NextTag)
+
+	// even a configured channel must be a valid pre-release identifier
+	_, err = EvalVersion(&Options{Out: out, Err: out, PrereleaseChannel: "rc/1", AllowedPrereleaseChannels: []string{"rc/1"}})
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+
+	_, err = EvalVersion(&Options{Out: out, Err: out, BumpStrategy: "fibonacci"})
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+
+	_, err = EvalVersion(&Options{Out: out, Err: out, BaseVersion: "not-a-version"})
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+}
+
+func TestEvalVersionCurrentTagPrefix(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "1.4", "sdk/v1.4.1", "v0.3.0", "sdk/v1.4.2")
+	head, err := r.repo.Head()
+	req.NoError(err)
+	// the root module is released from the same commit
+	r.tag("v0.3.1", head.Hash(), false)
+	req.NoError(os.MkdirAll("sdk", 0755))
+	req.NoError(os.WriteFile(filepath.Join("sdk", "version"), []byte("1.4\n"), 0644))
+
+	out := &bytes.Buffer{}
+	opts := &Options{Out: out, Err: out, ModuleDir: "sdk", UseCurrentTag: true}
+
+	// git describe finds the module tag on HEAD
+	info, err := EvalVersion(opts)
+	req.NoError(err)
+	req.Equal("1.4.2", info.Current.String())
+	req.Equal("sdk/v1.4.2", info.CurrentTag)
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REF_NAME", "sdk/v1.4.1")
+	info, err = EvalVersion(opts)
+	req.NoError(err)
+	req.Equal("1.4.1", info.Current.String())
+
+	// a tag of another module doesn't describe this one
+	t.Setenv("GITHUB_REF_NAME", "v0.3.0")
+	_, err = EvalVersion(opts)
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+	req.Contains(err.Error(), "doesn't have tag prefix sdk/")
+}
+
+// chdirTwoModuleRepo creates a repository with the root module at v0.3.0 and an sdk module at sdk/v1.4.0, followed
+// by a feature for the root module and a fix for the sdk. Bob contributed to the root module before the sdk release
+func chdirTwoModuleRepo(t *testing.T) *testRepo {
+	r := chdirTestRepo(t, "0.3", "v0.3.0")
+	r.commit("change.txt", "gadgets\n", "fix: gadgets\n\nCo-authored-by: Bob <bob@example.com>")
+	r.commit("sdk/version", "1.4\n", "feat: add sdk", "sdk/v1.4.0")
+	r.commit("change.txt", "widgets\n", "feat: add widgets")
+	r.commit("sdk/dial.go", "package sdk\n", "fix: sdk dial\n\nCo-authored-by: Bob <bob@example.com>")
+	return r
+}
+
+func TestEvalVersionModuleDirConventional(t *testing.T) {
+	req := require.New(t)
+	chdirTwoModuleRepo(t)
+
+	// the root module's feature doesn't bump the sdk
+	out := &bytes.Buffer{}
+	info, err := EvalVersion(&Options{Out: out, Err: out, ModuleDir: "sdk", BumpStrategy: BumpStrategyConventional})
+	req.NoError(err)
+	req.Equal("sdk/v1.4.0", info.CurrentTag)
+	req.Equal("sdk/v1.4.1", info.NextTag)
+}
+
+// chdirReleaseLineRepo creates a repository whose master has moved on to v0.36.0, with a release-v0.34
+// maintenance branch which has released v0.34.1, and leaves master checked out
+func chdirReleaseLineRepo(t *testing.T) *testRepo {
+	r := chdirTestRepo(t, "0.34", "v0.34.0")
+	head, err := r.repo.Head()
+	r.req.NoError(err)
+	r.req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release-v0.34"), Hash: head.Hash(), Create: true}))
+	r.commit("fix.txt", "fix\n", "fix: backport leak fix", "v0.34.1")
+
+	r.req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}))
+	r.commit("version", "0.35\n", "feat: first feature", "v0.35.0")
+	r.commit("version", "0.36\n", "feat: second feature", "v0.36.0")
+	return r
+}
+
+func TestEvalVersionReleaseBranchLine(t *testing.T) {
+	req := require.New(t)
+	r := chdirReleaseLineRepo(t)
+	out := &bytes.Buffer{}
+
+	// main keeps releasing from the newest line
+	info, err := EvalVersion(&Options{Out: out, Err: out, ReleaseBranchLines: true})
+	req.NoError(err)
+	req.Equal("0.36.0", info.Current.String())
+	req.Equal("0.36.1", info.Next.String())
+
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release-v0.34")}))
+	info, err = EvalVersion(&Options{Out: out, Err: out, ReleaseBranchLines: true})
+	req.NoError(err)
+	req.Equal("0.34.1", info.Current.String())
+	req.Equal("0.34.2", info.Next.String())
+	req.Len(info.BumpReasons, 1)
+	req.Contains(info.BumpReasons[0], "branch release-v0.34 maintains the 0.34 line")
+}
+
+func TestTagExistingVersionOnAnotherLine(t *testing.T) {
+	req := require.New(t)
+	r := chdirReleaseLineRepo(t)
+	// v0.34.2 was tagged on main, so it isn't reachable from the maintenance branch
+	r.commit("fix.txt", "fix\n", "fix: leak fix", "v0.34.2")
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release-v0.34")}))
+	r.commit("fix.txt", "fix\nanother fix\n", "fix: another backport")
+
+	out := &bytes.Buffer{}
+	// off by default, where every tag of the line counts, reachable or not
+	info, err := EvalVersion(&Options{Out: out, Err: out})
+	req.NoError(err)
+	req.Equal("0.34.3", info.Next.String())
+
+	base, err := (&Options{Out: out, Err: out, ReleaseBranchLines: true}).newBaseCommand("tag")
+	req.NoError(err)
+	err = (&tagCmd{BaseCommand: *base}).Execute()
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+	req.Equal("tag v0.34.2 already exists on another line", err.Error())
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
+	"slices"
+)
+
+type auditDepsCmd struct {
+	BaseCommand
+	directOnly bool
+	ignore     []string
+}
+
+func (cmd *auditDepsCmd) Execute() error {
+	goModPath := cmd.getModuleFile("go.mod")
+	if len(cmd.Args) > 0 {
+		goModPath = cmd.Args[0]
+	}
+	goMod, err := cmd.getGoMod(goModPath)
+	if err != nil {
+		return err
+	}
+
+	source := cmd.Config.Vulnerabilities.Db
+	if source == "" {
+		source = DefaultVulnDb
+	}
+	db, err := cmd.openVulnDb(source)
+	if err != nil {
+		return err
+	}
+
+	// replace directives decide which module versions are shipped. Those pointing at directories aren't checked
+	replacements := map[module.Version]module.Version{}
+	for _, r := range goMod.Replace {
+		replacements[r.Old] = r.New
+	}
+
+	vulnerable := 0
+	for _, r := range goMod.Require {
+		if r.Indirect && cmd.directOnly {
+			continue
+		}
+		shipped := r.Mod
+		if replacement, found := replacements[r.Mod]; found {
+			shipped = replacement
+		} else if replacement, found = replacements[module.Version{Path: r.Mod.Path}]; found {
+			shipped = replacement
+		}
+		if shipped.Version == "" {
+			cmd.Infof("skipping %v, replaced by directory %v\n", r.Mod.Path, shipped.Path)
+			continue
+		}
+
+		_, advisories, err := db.checkUpdate(shipped.Path, "", shipped.Version)
+		if err != nil {
+			return err
+		}
+		for _, advisory := range advisories {
+			if cmd.isIgnored(advisory) {
+				cmd.Infof("ignoring %v in %v %v\n", advisory.ID, shipped.Path, shipped.Version)
+				continue
+			}
+			vulnerable++
+			cmd.Printf("%v %v: %v\n", shipped.Path, shipped.Version, formatAdvisory(advisory))
+		}
+	}
+
+	if vulnerable > 0 {
+		return vulnerabilityErrorf("%v known vulnerabilities affect the dependencies in %v", vulnerable, goModPath)
+	}
+	cmd.Infof("no known vulnerabilities affect the dependencies in %v\n", goModPath)
+	return nil
+}
+
+// isIgnored returns true if the advisory, or one of its aliases, was acknowledged with --ignore
+func (cmd *auditDepsCmd) isIgnored(advisory *Advisory) bool {
+	if slices.Contains(cmd.ignore, advisory.ID) {
+		return true
+	}
+	for _, alias := range advisory.Aliases {
+		if slices.Contains(cmd.ignore, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func newAuditDepsCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "audit-deps [go.mod]",
+		Short: "Fail if a dependency of the module is a version with known vulnerabilities",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	result := &auditDepsCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().String("vuln-db", DefaultVulnDb, "OSV vulnerability database url or directory")
+	cobraCmd.Flags().BoolVar(&result.directOnly, "direct-only", false, "only check direct dependencies")
+	cobraCmd.Flags().StringSliceVar(&result.ignore, "ignore", nil, "advisory ids, or aliases such as CVE ids, which have been reviewed and don't fail the audit")
+
+	return FinalizeErroringCmd(result)
+}
@@ -20,9 +20,6 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"golang.org/x/mod/modfile"
-	"os"
-	"time"
 )
 
 type GoBuildFlagsCmd struct {
@@ -30,34 +27,38 @@ type GoBuildFlagsCmd struct {
 	nextVersion bool
 }
 
-func (cmd *GoBuildFlagsCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *GoBuildFlagsCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	tagVersion := fmt.Sprintf("v%s", cmd.CurrentVersion)
 	if cmd.nextVersion {
 		tagVersion = fmt.Sprintf("v%v", cmd.NextVersion)
 	}
 
-	revision := cmd.GetCmdOutputOneLine("get git SHA", "git", "rev-parse", "--short=12", "HEAD")
-	buildDate := time.Now().Format(time.RFC3339)
-
-	data, err := os.ReadFile("go.mod")
+	revision, err := cmd.getRevision()
+	if err != nil {
+		return err
+	}
+	buildDate, err := cmd.getBuildDate()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	newGoMod, err := modfile.Parse("go.mod", data, nil)
+	goMod, err := cmd.getGoMod(cmd.getModuleFile("go.mod"))
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	modulePath := newGoMod.Module.Mod.Path
-	buildInfoPath := "common/version"
+	modulePath := goMod.Module.Mod.Path
+	buildInfoPath := cmd.Config.Build.VersionPackage
 
 	versionFlag := fmt.Sprintf("%s/%s.Version=%s", modulePath, buildInfoPath, tagVersion)
 	revisionFlag := fmt.Sprintf("%s/%s.Revision=%s", modulePath, buildInfoPath, revision)
 	buildDateFlag := fmt.Sprintf("%s/%s.BuildDate=%s", modulePath, buildInfoPath, buildDate)
-	fmt.Printf(`-X '%s' -X '%s' -X '%s'`, versionFlag, revisionFlag, buildDateFlag)
+	cmd.Printf(`-X '%s' -X '%s' -X '%s'`, versionFlag, revisionFlag, buildDateFlag)
+	return nil
 }
 
 func newGoBuildFlagsCmd(root *RootCommand) *cobra.Command {
@@ -76,5 +77,5 @@ func newGoBuildFlagsCmd(root *RootCommand) *cobra.Command {
 
 	cobraCmd.Flags().BoolVarP(&result.nextVersion, "next-version", "n", false, "use the next version instead of the current version")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
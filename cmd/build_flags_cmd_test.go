@@ -0,0 +1,41 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoBuildFlagsModuleDir(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0", "sdk/v1.4.0")
+	req.NoError(os.WriteFile("go.mod", []byte("module example.com/foo\n"), 0644))
+	req.NoError(os.MkdirAll("sdk", 0755))
+	req.NoError(os.WriteFile(filepath.Join("sdk", "go.mod"), []byte("module example.com/foo/sdk\n"), 0644))
+	req.NoError(os.WriteFile(filepath.Join("sdk", "version"), []byte("1.4\n"), 0644))
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: &bytes.Buffer{}, ModuleDir: "sdk"}).newBaseCommand("go-build-flags")
+	req.NoError(err)
+	req.NoError((&GoBuildFlagsCmd{BaseCommand: *base}).Execute())
+	req.Contains(out.String(), "-X 'example.com/foo/sdk/common/version.Version=v1.4.0'")
+}
@@ -22,7 +22,6 @@ import (
 	"github.com/spf13/cobra"
 	"html/template"
 	"os"
-	"time"
 )
 
 var goBuildInfoTemplate = `/*
@@ -69,8 +68,10 @@ type GoBuildInfoCmd struct {
 	noAddNoCommit bool
 }
 
-func (cmd *GoBuildInfoCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *GoBuildInfoCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	var tagVersion string
 	if cmd.useV {
@@ -79,37 +80,55 @@ func (cmd *GoBuildInfoCmd) Execute() {
 		tagVersion = fmt.Sprintf("%v", cmd.NextVersion)
 	}
 
+	revision, err := cmd.getRevision()
+	if err != nil {
+		return err
+	}
+
+	buildDate, err := cmd.getBuildDate()
+	if err != nil {
+		return err
+	}
+
 	buildInfo := &GoBuildInfo{
 		PackageName: cmd.Args[1],
 		Version:     tagVersion,
-		Revision:    cmd.GetCmdOutputOneLine("get git SHA", "git", "rev-parse", "--short=12", "HEAD"),
-		BuildDate:   time.Now().Format(time.RFC3339),
+		Revision:    revision,
+		BuildDate:   buildDate,
 	}
 
 	compiledTemplate, err := template.New("buildInfo").Parse(goBuildInfoTemplate)
 	if err != nil {
-		cmd.Failf("failure compiling build info template %+v\n", err)
+		return fmt.Errorf("failure compiling build info template: %w", err)
 	}
 
 	file, err := os.Create(cmd.Args[0])
 	if err != nil {
-		cmd.Failf("failure opening build info output file %v. err: %+v\n", cmd.Args[0], err)
+		return fmt.Errorf("failure opening build info output file %v. err: %w", cmd.Args[0], err)
 	}
+	defer cmd.close(file, cmd.Args[0])
 
 	err = compiledTemplate.Execute(file, buildInfo)
 	if err != nil {
-		cmd.Failf("failure executing build template to output file %v. err: %+v\n", cmd.Args[0], err)
+		return fmt.Errorf("failure executing build template to output file %v. err: %w", cmd.Args[0], err)
 	}
 
-	cmd.RunGitCommand("set git username", "config", "user.name", DefaultGitUsername)
-	cmd.RunGitCommand("set git password", "config", "user.email", DefaultGitEmail)
+	err = cmd.runGitCommands(
+		[]string{"set git username", "config", "user.name", cmd.Config.Git.Username},
+		[]string{"set git password", "config", "user.email", cmd.Config.Git.Email},
+	)
+	if err != nil {
+		return err
+	}
 
 	if cmd.noAddNoCommit {
 		cmd.Infof("--noAddNoCommit specified - not committing %s", cmd.Args[0])
-	} else {
-		cmd.RunGitCommand("add build info file to git", "add", cmd.Args[0])
-		cmd.RunGitCommand("commit build info file", "commit", "-m", fmt.Sprintf("Release %v", tagVersion))
+		return nil
 	}
+	return cmd.runGitCommands(
+		[]string{"add build info file to git", "add", cmd.Args[0]},
+		[]string{"commit build info file", "commit", "-m", fmt.Sprintf("Release %v", tagVersion)},
+	)
 }
 
 func newGoBuildInfoCmd(root *RootCommand) *cobra.Command {
@@ -129,5 +148,5 @@ func newGoBuildInfoCmd(root *RootCommand) *cobra.Command {
 	cobraCmd.Flags().BoolVar(&result.useV, "useVersion", true, "include a 'v' in the version or not, default is true")
 	cobraCmd.Flags().BoolVar(&result.noAddNoCommit, "noAddNoCommit", false, "do not add nor commit the version file in this action, default is false")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
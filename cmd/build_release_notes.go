@@ -22,22 +22,68 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/pkg/errors"
+	"github.com/hashicorp/go-version"
 	"github.com/spf13/cobra"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
-	"io"
-	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+const (
+	DefaultReleaseNotesConcurrency = 4
+	DefaultReleaseNotesMaxCommits  = 1000
+)
+
+// versionTagRegex matches the start of a version tag, so commit hashes aren't taken for versions
+var versionTagRegex = regexp.MustCompile(`^v?\d+\.\d+`)
+
 type baseBuildReleaseNotesCmd struct {
 	BaseCommand
 	AllCommits    bool
 	ShowUnchanged bool
+	// Concurrency is the number of repositories whose changes are collected at the same time
+	Concurrency int
+	// MaxCommits limits the commits listed for a repository, guarding against ranges which are far larger than a
+	// release. Zero is no limit
+	MaxCommits int
+	// Format is one of markdown, json or yaml
+	Format string
+	// Template is a file holding the text/template markdown release notes are rendered with
+	Template string
+	// Categorized groups commits into sections by conventional commit type, or issue and pull request labels
+	Categorized bool
+	// GoModDiff adds every change to go.mod, including indirect dependencies and directives, to the notes
+	GoModDiff bool
+	// ApiDiff adds the changes to the exported API of the module to the sdk release notes
+	ApiDiff bool
+	// Contributors adds the authors of the changes to the module being released to the notes
+	Contributors bool
+	// From is the tag or commit the notes start after. Defaults to the current version's tag
+	From string
+	// To is the tag or commit the notes end with. Defaults to the working tree's go.mod and the HEAD commit
+	To string
+}
+
+// releaseRange holds the revisions release notes are built between, and the versions they're labelled with
+type releaseRange struct {
+	// from and to are the revisions compared. An empty to selects the working tree's go.mod and HEAD's commits
+	from, to string
+	// toTag labels the new version of the current module, ex: the next version's tag
+	toTag           string
+	previousVersion string
+	version         string
+}
+
+// head returns the revision the current module's commits are listed up to
+func (r *releaseRange) head() string {
+	if r.to == "" {
+		return "HEAD"
+	}
+	return r.to
 }
 
 type buildReleaseNotesCmd struct {
@@ -46,223 +92,305 @@ type buildReleaseNotesCmd struct {
 
 func (cmd *baseBuildReleaseNotesCmd) getUnversionedPath(m module.Version) string {
 	parts := strings.Split(m.Path, "/")
-	lastElement := parts[len(parts)-1]
-	match, err := regexp.Match(`v(\d+)`, []byte(lastElement))
-	if err != nil {
-		panic(err)
-	}
-	if match {
+	if majorVersionDirRegex.MatchString(parts[len(parts)-1]) {
 		return strings.Join(parts[:len(parts)-1], "/")
 	}
 	return m.Path
 }
 
-func (cmd *baseBuildReleaseNotesCmd) getPreviousVersion(path string) *string {
+// getPreviousVersion returns the path of the module's previous major version, ex: github.com/openziti/foo/v2 for
+// github.com/openziti/foo/v3, or nil if it's the first
+func (cmd *baseBuildReleaseNotesCmd) getPreviousVersion(path string) (*string, error) {
 	parts := strings.Split(path, "/")
 	lastElement := parts[len(parts)-1]
-	match, err := regexp.Match(`^v(\d+)`, []byte(lastElement))
-	if err != nil {
-		panic(err)
-	}
-	if match {
+	if majorVersionDirRegex.MatchString(lastElement) {
 		base := strings.Join(parts[:len(parts)-1], "/")
-		versionStr := lastElement[1:]
-		version, err := strconv.Atoi(versionStr)
+		version, err := strconv.Atoi(lastElement[1:])
 		if err != nil {
-			panic(err)
+			return nil, versionErrorf("invalid major version in module path %v: %w", path, err)
 		}
 		if version == 2 {
-			return &base
+			return &base, nil
 		}
 		base = fmt.Sprintf("%v/v%v", base, version-1)
-		return &base
+		return &base, nil
 	}
-	return nil
+	return nil, nil
 }
 
-func (cmd *buildReleaseNotesCmd) Execute() {
-	if !cmd.RootCobraCmd.Flags().Changed("quiet") {
-		cmd.quiet = true
+// findPreviousRequire returns the old requirement of the module, or of one of its previous major versions
+func (cmd *baseBuildReleaseNotesCmd) findPreviousRequire(oldVersions map[string]*modfile.Require, path string) (*modfile.Require, bool, error) {
+	if prev, found := oldVersions[path]; found {
+		return prev, true, nil
 	}
-
-	cmd.EvalCurrentAndNextVersion()
-	if !cmd.quiet {
-		fmt.Printf("Release notes %v -> %v\n", cmd.CurrentVersion, cmd.NextVersion)
+	prevVersion, err := cmd.getPreviousVersion(path)
+	for prevVersion != nil && err == nil {
+		if prev, found := oldVersions[*prevVersion]; found {
+			return prev, true, nil
+		}
+		prevVersion, err = cmd.getPreviousVersion(*prevVersion)
 	}
+	return nil, false, err
+}
 
-	data, err := os.ReadFile("go.mod")
-	if err != nil {
-		panic(err)
+// getModuleTagPrefix returns the tag prefix used for a module nested in a repository, ex: sdk/ for
+// github.com/openziti/foo/sdk/v2
+func getModuleTagPrefix(modulePath string) string {
+	parts := strings.Split(modulePath, "/")
+	if len(parts) <= 3 {
+		return ""
 	}
+	return moduleTagPrefix(strings.Join(parts[3:], "/"))
+}
 
-	newGoMod, err := modfile.Parse("go.mod", data, nil)
-	if err != nil {
-		panic(err)
+// getModuleProject returns the name of the repository holding the given module, ex: foo for
+// github.com/openziti/foo/sdk/v2
+func getModuleProject(modulePath string) (string, error) {
+	parts := strings.Split(modulePath, "/")
+	if len(parts) < 3 || parts[2] == "" {
+		return "", configErrorf("unable to derive the project of module %v, it isn't in a host/owner/project repository. Check release-notes.dependency-filter", modulePath)
 	}
+	return parts[2], nil
+}
 
-	output := cmd.runCommandWithOutput("get go.mod contents", "git", "show", fmt.Sprintf("v%v:go.mod", cmd.CurrentVersion))
-	data = []byte(strings.Join(output, "\n"))
-	oldGoMod, err := modfile.Parse("go.mod", data, nil)
-	if err != nil {
-		panic(err)
+// setRangeArgs takes the optional [from] [to] arguments of the release notes commands
+func (cmd *baseBuildReleaseNotesCmd) setRangeArgs() {
+	if len(cmd.Args) > 0 {
+		cmd.From = cmd.Args[0]
 	}
+	if len(cmd.Args) > 1 {
+		cmd.To = cmd.Args[1]
+	}
+}
 
-	oldVersions := map[string]*modfile.Require{}
-
-	for _, m := range oldGoMod.Require {
-		if strings.Contains(m.Mod.Path, "openziti") {
-			oldVersions[m.Mod.Path] = m
+// evalReleaseRange returns the range release notes are built for. Without a to revision, the notes are for the next
+// version, and the current and next versions are evaluated
+func (cmd *baseBuildReleaseNotesCmd) evalReleaseRange() (*releaseRange, error) {
+	if cmd.From == "" && cmd.To != "" {
+		return nil, configErrorf("a from revision is required along with the to revision %v", cmd.To)
+	}
+	result := &releaseRange{from: cmd.From, to: cmd.To, toTag: cmd.To}
+	if cmd.To == "" {
+		if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+			return nil, err
 		}
+		result.toTag = cmd.getVersionTag(cmd.NextVersion)
+		result.version = cmd.NextVersion.String()
+	} else {
+		result.version = cmd.getRevisionVersion(cmd.To)
 	}
-
-	for _, m := range newGoMod.Require {
-		if strings.Contains(m.Mod.Path, "openziti") {
-			project := strings.Split(m.Mod.Path, "/")[2]
-			prev, found := oldVersions[m.Mod.Path]
-			if !found {
-				path := m.Mod.Path
-				prevVersion := cmd.getPreviousVersion(path)
-				for prevVersion != nil {
-					prev, found = oldVersions[*prevVersion]
-					if found {
-						break
-					}
-					prevVersion = cmd.getPreviousVersion(*prevVersion)
-				}
-			}
-			if !found {
-				fmt.Printf("* %v: %v (new)\n", m.Mod.Path, m.Mod.Version)
-			} else if m.Mod.Version != prev.Mod.Version {
-				fmt.Printf("* %v: [%v -> %v](https://github.com/openziti/%v/compare/%v...%v)\n", m.Mod.Path, prev.Mod.Version, m.Mod.Version, project, prev.Mod.Version, m.Mod.Version)
-				if err = cmd.GetChanges(project, prev.Mod.Version, m.Mod.Version); err != nil {
-					panic(err)
-				}
-			} else if cmd.ShowUnchanged {
-				fmt.Printf("* %v: %v (unchanged)\n", m.Mod.Path, m.Mod.Version)
-			}
+	if cmd.From == "" {
+		result.from = cmd.getVersionTag(cmd.CurrentVersion)
+		if cmd.CurrentVersion != nil {
+			result.previousVersion = cmd.CurrentVersion.String()
 		}
+	} else {
+		result.previousVersion = cmd.getRevisionVersion(cmd.From)
 	}
+	return result, nil
+}
 
-	fmt.Printf("* %v: [v%v -> v%v](https://github.com/openziti/ziti/compare/v%v...v%v)\n",
-		newGoMod.Module.Mod.Path, cmd.CurrentVersion, cmd.NextVersion, cmd.CurrentVersion, cmd.NextVersion)
-	if err = cmd.GetChanges("ziti", "v"+cmd.CurrentVersion.String(), "HEAD"); err != nil {
-		panic(err)
+// getRevisionVersion returns the version named by a version tag, ex: 1.2.3 for v1.2.3, or else the revision itself
+func (cmd *baseBuildReleaseNotesCmd) getRevisionVersion(rev string) string {
+	name := strings.TrimPrefix(rev, cmd.getTagPrefix())
+	if !versionTagRegex.MatchString(name) {
+		return rev
 	}
-
+	if v, err := version.NewVersion(name); err == nil {
+		return v.String()
+	}
+	return rev
 }
 
-func (cmd *baseBuildReleaseNotesCmd) GetChanges(project string, oldVersion string, newVersion string) error {
-	dir, err := os.Getwd()
+// getGoModChanges returns the go.mod of the current module at the end and at the start of the given range
+func (cmd *baseBuildReleaseNotesCmd) getGoModChanges(r *releaseRange) (*modfile.File, *modfile.File, error) {
+	goModPath := cmd.getModuleFile("go.mod")
+	var newGoMod *modfile.File
+	var err error
+	if r.to == "" {
+		newGoMod, err = cmd.getGoMod(goModPath)
+	} else {
+		newGoMod, err = cmd.getRevisionGoMod(r.to, goModPath)
+	}
 	if err != nil {
-		return errors.Wrapf(err, "unable to get working directory")
+		return nil, nil, err
 	}
 
-	defer func() {
-		if err := os.Chdir(dir); err != nil {
-			panic(errors.Wrapf(err, "unable to restore working directory to %v", dir))
-		}
-	}()
+	oldGoMod, err := cmd.getRevisionGoMod(r.from, goModPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newGoMod, oldGoMod, nil
+}
 
-	if err := os.Chdir("../" + project); err != nil {
-		return errors.Wrapf(err, "")
+// getRevisionGoMod reads and parses the go.mod at the given path, as of the given tag or commit
+func (cmd *baseBuildReleaseNotesCmd) getRevisionGoMod(rev, goModPath string) (*modfile.File, error) {
+	g, err := cmd.getGit()
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.Show(rev, filepath.ToSlash(goModPath))
+	if err != nil {
+		return nil, gitErrorf("unable to read go.mod at %v: %w", rev, err)
 	}
+	goMod, err := modfile.Parse(fmt.Sprintf("%v:%v", rev, filepath.ToSlash(goModPath)), data, nil)
+	if err != nil {
+		return nil, versionErrorf("unable to parse go.mod at %v: %w", rev, err)
+	}
+	return goMod, nil
+}
 
-	cmd.runGitCommandAlways("fetch latest tags", "fetch", "--tags")
+func (cmd *buildReleaseNotesCmd) Execute() error {
+	if err := cmd.validateFormat(); err != nil {
+		return err
+	}
+	if !cmd.RootCobraCmd.Flags().Changed("quiet") {
+		cmd.quiet = true
+	}
+	cmd.setRangeArgs()
 
-	r, err := git.PlainOpen(".")
+	notes, err := cmd.evalReleaseNotes()
 	if err != nil {
 		return err
 	}
 
-	newTagHash, err := r.ResolveRevision(plumbing.Revision(newVersion))
-	if err != nil {
-		// check if we're pointing to git hash
-		parts := strings.Split(newVersion, "-")
-		if len(parts) == 3 {
-			gitHash := parts[2]
-			newTagHash, err = r.ResolveRevision(plumbing.Revision(gitHash))
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
+	if cmd.Categorized {
+		return cmd.outputReleaseNotes(notes, categorizedReleaseNotesTemplate)
 	}
+	return cmd.outputReleaseNotes(notes, defaultReleaseNotesTemplate)
+}
 
-	oldTagHash, err := r.ResolveRevision(plumbing.Revision(oldVersion))
+// evalReleaseNotes lists the dependencies matching the dependency filter, followed by the module being released
+func (cmd *buildReleaseNotesCmd) evalReleaseNotes() (*ReleaseNotes, error) {
+	r, err := cmd.evalReleaseRange()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	oldTagIter, err := r.Log(&git.LogOptions{Order: git.LogOrderCommitterTime, From: *oldTagHash})
+	newGoMod, oldGoMod, err := cmd.getGoModChanges(r)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer oldTagIter.Close()
 
-	tagCommit, err := oldTagIter.Next()
-	if err != nil {
-		return err
+	oldVersions := map[string]*modfile.Require{}
+
+	for _, m := range oldGoMod.Require {
+		if strings.Contains(m.Mod.Path, cmd.Config.ReleaseNotes.DependencyFilter) {
+			oldVersions[m.Mod.Path] = m
+		}
 	}
 
-	// The old tag may be a tag commit not in the main-line, so we'll have to find the parent
-	if project == "ziti" || project == "sdk-golang" {
-		if tagCommit.NumParents() == 1 && tagCommit.Author.Name == "ziti-ci" {
-			tagCommit, err = tagCommit.Parent(0)
+	notes := newReleaseNotes(r)
+
+	for _, m := range newGoMod.Require {
+		if strings.Contains(m.Mod.Path, cmd.Config.ReleaseNotes.DependencyFilter) {
+			project, err := getModuleProject(m.Mod.Path)
 			if err != nil {
-				return err
+				return nil, err
 			}
-		}
-		// find first non-merge commit
-		for tagCommit.NumParents() > 1 {
-			var parent *object.Commit
-			err = tagCommit.Parents().ForEach(func(commit *object.Commit) error {
-				if parent == nil || commit.Author.When.After(parent.Author.When) {
-					parent = commit
-				}
-				return nil
-			})
+			prev, found, err := cmd.findPreviousRequire(oldVersions, m.Mod.Path)
 			if err != nil {
-				panic(err)
+				return nil, err
+			}
+			if !found {
+				notes.Components = append(notes.Components, newComponent(m.Mod.Path, ComponentStatusNew, "", m.Mod.Version))
+			} else if m.Mod.Version != prev.Mod.Version {
+				tagPrefix := getModuleTagPrefix(m.Mod.Path)
+				component := newComponent(m.Mod.Path, ComponentStatusChanged, prev.Mod.Version, m.Mod.Version)
+				component.CompareUrl = cmd.Config.compareUrl(project, tagPrefix+prev.Mod.Version, tagPrefix+m.Mod.Version)
+				component.setChanges(m.Mod.Path, tagPrefix+prev.Mod.Version, tagPrefix+m.Mod.Version)
+				notes.Components = append(notes.Components, component)
+			} else if cmd.ShowUnchanged {
+				notes.Components = append(notes.Components, newComponent(m.Mod.Path, ComponentStatusUnchanged, m.Mod.Version, m.Mod.Version))
 			}
-			tagCommit = parent
 		}
-		oldTagHash = &tagCommit.Hash
-	} else if tagCommit.NumParents() == 1 && tagCommit.Author.Name == "ziti-ci" {
-		tagCommit, err = tagCommit.Parent(0)
-		if err != nil {
-			return err
+	}
+
+	component := newComponent(newGoMod.Module.Mod.Path, ComponentStatusChanged, r.from, r.toTag)
+	component.Current = true
+	component.CompareUrl = cmd.Config.compareUrl(cmd.Config.ReleaseNotes.Project, r.from, r.toTag)
+	component.setChanges("", r.from, r.head())
+	notes.Components = append(notes.Components, component)
+
+	if cmd.GoModDiff {
+		notes.GoMod = cmd.diffGoMod(oldGoMod, newGoMod)
+	}
+	if err = cmd.annotateVulnerabilities(notes); err != nil {
+		return nil, err
+	}
+	if err = cmd.collectChanges(notes); err != nil {
+		return nil, err
+	}
+	if cmd.Contributors {
+		if notes.Contributors, err = cmd.collectContributors(notes); err != nil {
+			return nil, err
 		}
-		oldTagHash = &tagCommit.Hash
 	}
+	return notes, nil
+}
+
+// GetChanges returns the commits made in the given repository between two revisions, newest first, leaving out
+// merges and commits by ignored authors. The commits are those reachable from newVersion but not from oldVersion,
+// whichever branches the two are on. repoPath identifies a dependency's repository, ex: github.com/openziti/edge,
+// and is empty for the repository being built, in which case only commits touching --module-dir are returned
+func (cmd *baseBuildReleaseNotesCmd) GetChanges(r *git.Repository, repoPath string, oldVersion string, newVersion string) ([]*ReleaseNotesCommit, error) {
+	commits, _, err := cmd.getChanges(r, repoPath, oldVersion, newVersion)
+	return commits, err
+}
 
-	iter, err := r.Log(&git.LogOptions{Order: git.LogOrderCommitterTime, From: *newTagHash})
+// getChanges returns the commits as GetChanges does, and whether they were cut off at --max-commits
+func (cmd *baseBuildReleaseNotesCmd) getChanges(r *git.Repository, repoPath string, oldVersion string, newVersion string) ([]*ReleaseNotesCommit, bool, error) {
+	newCommit, err := resolveChangeCommit(r, newVersion)
 	if err != nil {
-		return err
+		return nil, false, err
+	}
+	oldCommit, err := resolveChangeCommit(r, oldVersion)
+	if err != nil {
+		return nil, false, err
 	}
 
-	showedChange := false
+	excluded := map[plumbing.Hash]bool{}
+	err = object.NewCommitPreorderIter(oldCommit, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
 
-	defer iter.Close()
-	defer func() {
-		if showedChange {
-			fmt.Println()
-		}
-	}()
+	dir := ""
+	if repoPath == "" {
+		dir = cmd.getModuleRepoDir()
+	}
 
-	for {
-		c, err := iter.Next()
-		if err == io.EOF {
-			return nil
-		}
-		if c == nil {
+	var commits []*object.Commit
+	iter := object.NewCommitPreorderIter(newCommit, excluded, nil)
+	defer iter.Close()
+	err = iter.ForEach(func(c *object.Commit) error {
+		if touches, err := commitTouchesDir(c, dir); err != nil || !touches {
 			return err
 		}
-		if c.Hash == *oldTagHash {
-			return nil
-		}
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
 
-		if c.Author.Name == "ziti-ci" || c.Author.Name == "dependabot[bot]" {
+	truncated := cmd.MaxCommits > 0 && len(commits) > cmd.MaxCommits
+	if truncated {
+		cmd.Warnf("%v has %v commits between %v and %v, only the newest %v are listed. Check the versions, or raise --max-commits\n",
+			valueOr(repoPath, "the repository"), len(commits), oldVersion, newVersion, cmd.MaxCommits)
+		commits = commits[:cmd.MaxCommits]
+	}
+
+	var result []*ReleaseNotesCommit
+	for _, c := range commits {
+		if cmd.Config.isIgnoredAuthor(c.Author.Name) {
 			continue
 		}
 
@@ -271,53 +399,39 @@ func (cmd *baseBuildReleaseNotesCmd) GetChanges(project string, oldVersion strin
 			continue
 		}
 
-		issueFound := false
-		for _, issue := range cmd.extractIssues(c) {
-			cmd.outputIssue(issue)
-			showedChange = true
-			issueFound = true
-		}
-
-		if !issueFound && cmd.AllCommits {
-			lines := strings.Split(c.Message, "\n")
-			fmt.Printf("    * %v: %v (%v)\n", c.Hash.String()[:7], lines[0], c.Author.Email)
-			showedChange = true
+		commit := &ReleaseNotesCommit{
+			Hash:        c.Hash.String(),
+			Subject:     strings.Split(c.Message, "\n")[0],
+			Author:      c.Author.Name,
+			AuthorEmail: c.Author.Email,
 		}
-
-	}
-}
-
-func (cmd *baseBuildReleaseNotesCmd) extractIssues(c *object.Commit) []string {
-	r, err := regexp.Compile(`(fix(e[sd])?|close[sd]?|resolve[sd]?)\s*#(\d+)`)
-	if err != nil {
-		panic(err)
-	}
-
-	matches := r.FindAllStringSubmatch(strings.ToLower(c.Message), -1)
-	var result []string
-	for _, match := range matches {
-		result = append(result, match[3])
+		commit.parseMessage(c.Message)
+		result = append(result, commit)
 	}
-	return result
+	return result, truncated, nil
 }
 
-func (cmd *baseBuildReleaseNotesCmd) outputIssue(issue string) {
-	bin, err := exec.LookPath("gh")
+// resolveChangeCommit returns the commit a tag or commit names. A pseudo-version, ex:
+// v0.0.0-20240101000000-0123456789ab, names the commit with its hash
+func resolveChangeCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
 	if err != nil {
-		panic(errors.Wrap(err, "gh (github CLI) not found. Please make sure it's installed an you are authenticated"))
-	}
-	out, err := cmd.runCommandWithOutputFailOptional(false, "Get Issue", bin,
-		"issue", "view", issue, "--json", "number,title,url", "--jq", `"[Issue #" + (.number|tostring) + "](" + .url + ") - " + .title`)
-	if err == nil {
-		fmt.Printf("    * %v\n", out[0])
+		parts := strings.Split(rev, "-")
+		if len(parts) != 3 {
+			return nil, err
+		}
+		if hash, err = r.ResolveRevision(plumbing.Revision(parts[2])); err != nil {
+			return nil, err
+		}
 	}
+	return r.CommitObject(*hash)
 }
 
 func newBuildReleaseNotesCmd(root *RootCommand) *cobra.Command {
 	cobraCmd := &cobra.Command{
-		Use:   "build-release-notes",
-		Short: "Prints out the release notes for the latest or a given version",
-		Args:  cobra.MaximumNArgs(1),
+		Use:   "build-release-notes [from] [to]",
+		Short: "Prints out the release notes for the next version, or between two tags or commits",
+		Args:  cobra.MaximumNArgs(2),
 	}
 
 	result := &buildReleaseNotesCmd{
@@ -330,7 +444,15 @@ func newBuildReleaseNotesCmd(root *RootCommand) *cobra.Command {
 	}
 
 	cobraCmd.Flags().BoolVarP(&result.AllCommits, "all-commits", "a", false, "Show all commits, not just closed issues")
-	cobraCmd.Flags().BoolVarP(&result.ShowUnchanged, "show-unchanged", "u", false, "Show OpenZiti upstream libraries, even if unchanged")
-
-	return Finalize(result)
+	cobraCmd.Flags().BoolVarP(&result.ShowUnchanged, "show-unchanged", "u", false, "Show upstream libraries matching release-notes.dependency-filter, even if unchanged")
+	cobraCmd.Flags().IntVarP(&result.Concurrency, "concurrency", "j", DefaultReleaseNotesConcurrency, "number of repositories whose changes are collected at the same time")
+	cobraCmd.Flags().IntVar(&result.MaxCommits, "max-commits", DefaultReleaseNotesMaxCommits, "maximum number of commits listed for a repository, or 0 for no limit")
+	cobraCmd.Flags().StringVarP(&result.Format, "format", "o", ReleaseNotesFormatMarkdown, "output format. Valid values: [markdown,json,yaml]")
+	cobraCmd.Flags().StringVar(&result.Template, "template", "", "text/template file used to render markdown release notes, instead of the built-in layout")
+	cobraCmd.Flags().BoolVarP(&result.Categorized, "categorized", "c", false, "group all commits into sections, such as Features and Fixes, by conventional commit type or issue labels")
+	cobraCmd.Flags().BoolVar(&result.GoModDiff, "go-mod-diff", false, "add a section listing every go.mod change, including indirect dependencies, replace directives and go version")
+	cobraCmd.Flags().BoolVar(&result.Contributors, "contributors", false, "add a section listing the authors of the release's commits, marking first-time contributors")
+	cobraCmd.Flags().String("vuln-db", "", "OSV vulnerability database url or directory, ex: https://vuln.go.dev, used to list the advisories fixed by dependency updates")
+
+	return FinalizeErroringCmd(result)
 }
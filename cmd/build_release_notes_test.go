@@ -18,26 +18,391 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestExtractIssues(t *testing.T) {
+func TestReleaseNotesFormats(t *testing.T) {
 	req := require.New(t)
-	a := func(s ...string) []string {
-		return s
+	chdirTestRepo(t, "0.3")
+
+	notes := &ReleaseNotes{Version: "0.3.1", PreviousVersion: "0.3.0"}
+	component := newComponent("github.com/openziti/edge", ComponentStatusChanged, "v0.1.0", "v0.1.1")
+	component.CompareUrl = "https://github.com/openziti/edge/compare/v0.1.0...v0.1.1"
+	component.Commits = []*ReleaseNotesCommit{
+		{Hash: "1234567890", Subject: "Fix widgets. Fixes #12", Author: "Jane Doe", AuthorEmail: "jane@example.com", References: []*ReleaseNotesReference{
+			{Kind: ReferenceKindIssue, Number: 12, Closes: true, Title: "Widgets are broken", Url: "https://github.com/openziti/edge/issues/12"},
+		}},
+		{Hash: "abcdef0123", Subject: "Document widgets", Author: "Jane Doe", AuthorEmail: "jane@example.com"},
+	}
+	component.Issues = []*ReleaseNotesIssue{{Number: 12, Title: "Widgets are broken", Url: "https://github.com/openziti/edge/issues/12"}}
+	notes.Components = append(notes.Components, component,
+		newComponent("github.com/openziti/foundation", ComponentStatusNew, "", "v2.0.0"))
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd}
+
+	notesCmd.Format = ReleaseNotesFormatMarkdown
+	req.NoError(notesCmd.outputReleaseNotes(notes, sdkReleaseNotesTemplate))
+	req.Equal("# Release notes 0.3.1\n\n## Issues Fixed and Dependency Updates\n\n"+
+		"* github.com/openziti/edge: [v0.1.0 -> v0.1.1](https://github.com/openziti/edge/compare/v0.1.0...v0.1.1)\n"+
+		"    * [Issue #12](https://github.com/openziti/edge/issues/12) - Widgets are broken\n\n"+
+		"* github.com/openziti/foundation: v2.0.0 (new)\n", out.String())
+
+	notesCmd.AllCommits = true
+	out.Reset()
+	req.NoError(notesCmd.writeMarkdown(out, notes, defaultReleaseNotesTemplate))
+	req.True(strings.HasPrefix(out.String(), "* github.com/openziti/edge"))
+	req.Contains(out.String(), "    * abcdef0: Document widgets (jane@example.com)\n")
+
+	notesCmd.Format = ReleaseNotesFormatJson
+	out.Reset()
+	req.NoError(notesCmd.outputReleaseNotes(notes, "ignored"))
+	parsed := &ReleaseNotes{}
+	req.NoError(json.Unmarshal(out.Bytes(), parsed))
+	req.Equal(notes, parsed)
+
+	notesCmd.Format = ReleaseNotesFormatYaml
+	out.Reset()
+	req.NoError(notesCmd.outputReleaseNotes(notes, "ignored"))
+	parsed = &ReleaseNotes{}
+	req.NoError(yaml.Unmarshal(out.Bytes(), parsed))
+	req.Equal(notes, parsed)
+
+	notesCmd.Format = "html"
+	req.Error(notesCmd.validateFormat())
+}
+
+func TestReleaseNotesTemplate(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	notes := &ReleaseNotes{Version: "0.3.1", PreviousVersion: "0.3.0"}
+	edge := newComponent("github.com/openziti/edge", ComponentStatusChanged, "v0.1.0", "v0.1.1")
+	edge.Commits = []*ReleaseNotesCommit{
+		{Hash: "1234567890", Subject: "fix: broken widgets"},
+		{Hash: "abcdef0123", Subject: "feat(api): add widgets"},
+		{Hash: "0123456789", Subject: "Document widgets"},
+		{Hash: "9876543210", Subject: "fix: more widgets"},
+	}
+	notes.Components = append(notes.Components,
+		newComponent("github.com/openziti/foundation", ComponentStatusNew, "", "v2.0.0"),
+		edge,
+		newComponent("github.com/openziti/agent", ComponentStatusUnchanged, "v1.0.0", "v1.0.0"))
+
+	templateFile := filepath.Join(t.TempDir(), "notes.tmpl")
+	req.NoError(os.WriteFile(templateFile, []byte(`{{range groupBy "Status" .Components}}{{.Key}}:{{range .Items}} {{repoName .Module}}{{end}}
+{{end}}{{range sortBy "Module" .Components}}{{template "component" .}}{{end}}
+{{- range groupByType (index .Components 1).Commits}}{{.Key}}:{{range .Items}} {{shortHash .Hash}}{{end}}
+{{end}}{{commitUrl "github.com/openziti/edge/v2" "abc"}}
+`), 0644))
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd, Format: ReleaseNotesFormatMarkdown, Template: templateFile}
+
+	req.NoError(notesCmd.outputReleaseNotes(notes, defaultReleaseNotesTemplate))
+	req.Equal("new: foundation\nchanged: edge\nunchanged: agent\n"+
+		"* github.com/openziti/agent: v1.0.0 (unchanged)\n"+
+		"* github.com/openziti/edge: v0.1.0 -> v0.1.1\n"+
+		"* github.com/openziti/foundation: v2.0.0 (new)\n"+
+		"fix: 1234567 9876543\nfeat: abcdef0\nother: 0123456\n"+
+		"https://github.com/openziti/edge/commit/abc\n", out.String())
+
+	_, err = sortBy("Missing", notes.Components)
+	req.Error(err)
+
+	req.NoError(os.WriteFile(templateFile, []byte("{{range .Components}"), 0644))
+	err = notesCmd.outputReleaseNotes(notes, defaultReleaseNotesTemplate)
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+}
+
+func TestCategorizedReleaseNotes(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	newCommit := func(hash, message string, labels ...string) *ReleaseNotesCommit {
+		commit := &ReleaseNotesCommit{Hash: hash, Subject: strings.Split(message, "\n")[0]}
+		commit.parseMessage(message)
+		commit.Category = categorizeCommit(commit, labels)
+		return commit
+	}
+
+	commit := newCommit("1", "feat(api)!: drop v1 endpoints (#40)\n\nBREAKING CHANGE: the v1 api is gone")
+	req.Equal("feat", commit.Type)
+	req.Equal("api", commit.Scope)
+	req.True(commit.Breaking)
+	req.Equal("the v1 api is gone", commit.BreakingChange)
+	req.Equal(40, commit.PullRequest)
+	req.Equal("drop v1 endpoints", commit.Title)
+	req.Equal(ReleaseNotesCategoryFeatures, commit.Category)
+
+	req.Equal(ReleaseNotesCategoryDependencies, newCommit("2", "chore(deps): bump yaml").Category)
+	req.Equal(ReleaseNotesCategoryFixes, newCommit("3", "Widgets crash", "bug").Category)
+	req.Equal(ReleaseNotesCategoryOther, newCommit("4", "Tidy up").Category)
+
+	// the pull request title replaces the squash-merged subject and provides the type
+	squashed := newCommit("5", "Speed up widgets (#41)")
+	pr := &ReleaseNotesIssue{Number: 41, Title: "perf: cache widgets", Url: "https://github.com/openziti/ziti/pull/41"}
+	squashed.applyPullRequest(pr)
+	squashed.Category = categorizeCommit(squashed, pr.Labels)
+	req.Equal("cache widgets", squashed.Title)
+	req.Equal(ReleaseNotesCategoryPerformance, squashed.Category)
+
+	notes := &ReleaseNotes{Version: "0.3.1", PreviousVersion: "0.3.0"}
+	current := newComponent("github.com/openziti/ziti", ComponentStatusChanged, "v0.3.0", "v0.3.1")
+	current.Current = true
+	fixed := newCommit("6", "fix: widget leak. Fixes #12")
+	fixed.References = []*ReleaseNotesReference{{Kind: ReferenceKindIssue, Number: 12, Url: "https://github.com/openziti/ziti/issues/12"}}
+	current.Commits = []*ReleaseNotesCommit{commit, squashed, fixed}
+	pr.PullRequest = true
+	current.Issues = []*ReleaseNotesIssue{pr, {Number: 12, Title: "Leak", Url: "https://github.com/openziti/ziti/issues/12"}}
+	edge := newComponent("github.com/openziti/edge", ComponentStatusChanged, "v0.1.0", "v0.1.1")
+	edge.Commits = []*ReleaseNotesCommit{newCommit("7", "Tidy up")}
+	notes.Components = append(notes.Components, edge, current)
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd, Format: ReleaseNotesFormatMarkdown, Categorized: true}
+	req.NoError(notesCmd.outputReleaseNotes(notes, categorizedSdkReleaseNotesTemplate))
+	req.Equal("# Release notes 0.3.1\n\n"+
+		"## Breaking Changes\n\n"+
+		"* the v1 api is gone\n\n"+
+		"## Features\n\n"+
+		"* drop v1 endpoints (#40)\n\n"+
+		"## Fixes\n\n"+
+		"* widget leak. Fixes #12 ([Issue #12](https://github.com/openziti/ziti/issues/12))\n\n"+
+		"## Performance\n\n"+
+		"* cache widgets ([#41](https://github.com/openziti/ziti/pull/41))\n\n"+
+		"## Other\n\n"+
+		"* edge: Tidy up\n\n"+
+		"## Component Versions\n\n"+
+		"* github.com/openziti/edge: v0.1.0 -> v0.1.1\n", out.String())
+}
+
+func TestReleaseNotesRange(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3")
+
+	widgets := newDiskTestRepo(t)
+	widgets.commit("change.txt", "initial\n", "widgets initial commit", "v1.0.0")
+	widgets.commit("change.txt", "add\n", "add widgets", "v1.0.1")
+	widgets.commit("change.txt", "fix\n", "fix widgets", "v1.0.2")
+
+	for i, widgetsVersion := range []string{"v1.0.0", "v1.0.1", "v1.0.2"} {
+		goMod := fmt.Sprintf("module github.com/openziti/ziti\n\nrequire github.com/openziti/widgets %v\n", widgetsVersion)
+		r.commit("go.mod", goMod, "update widgets to "+widgetsVersion, fmt.Sprintf("v0.%v.0", i+1))
+	}
+
+	eval := func(from, to string) (*ReleaseNotes, error) {
+		out := &bytes.Buffer{}
+		base, err := (&Options{Out: out, Err: out}).newReleaseNotesCommand(&ReleaseNotesOptions{AllCommits: true, From: from, To: to})
+		req.NoError(err)
+		base.Config.ReleaseNotes.CacheDir = t.TempDir()
+		base.Config.ReleaseNotes.IssueCacheDir = ""
+		base.Config.ReleaseNotes.Repositories = map[string]string{"github.com/openziti/widgets": widgets.dir()}
+		return (&buildReleaseNotesCmd{baseBuildReleaseNotesCmd: *base}).evalReleaseNotes()
+	}
+	subjects := func(component *ReleaseNotesComponent) []string {
+		var result []string
+		for _, commit := range component.Commits {
+			result = append(result, commit.Subject)
+		}
+		return result
+	}
+
+	// cumulative notes across two historical releases
+	notes, err := eval("v0.1.0", "v0.3.0")
+	req.NoError(err)
+	req.Equal("0.3.0", notes.Version)
+	req.Equal("0.1.0", notes.PreviousVersion)
+	req.Len(notes.Components, 2)
+	req.Equal("github.com/openziti/widgets", notes.Components[0].Module)
+	req.Equal("v1.0.0", notes.Components[0].OldVersion)
+	req.Equal("v1.0.2", notes.Components[0].NewVersion)
+	req.Equal([]string{"fix widgets", "add widgets"}, subjects(notes.Components[0]))
+	current := notes.Components[1]
+	req.True(current.Current)
+	req.Equal("v0.1.0", current.OldVersion)
+	req.Equal("v0.3.0", current.NewVersion)
+	req.Equal("https://github.com/openziti/ziti/compare/v0.1.0...v0.3.0", current.CompareUrl)
+	req.Equal([]string{"update widgets to v1.0.2", "update widgets to v1.0.1"}, subjects(current))
+
+	// a single historical release, from a commit
+	hash, err := r.repo.ResolveRevision("v0.1.0")
+	req.NoError(err)
+	notes, err = eval(hash.String(), "v0.2.0")
+	req.NoError(err)
+	req.Equal(hash.String(), notes.PreviousVersion)
+	req.Equal([]string{"add widgets"}, subjects(notes.Components[0]))
+	req.Equal([]string{"update widgets to v1.0.1"}, subjects(notes.Components[1]))
+
+	// from a given tag up to the working tree, for the next version
+	notes, err = eval("v0.2.0", "")
+	req.NoError(err)
+	req.Equal("0.3.1", notes.Version)
+	req.Equal("0.2.0", notes.PreviousVersion)
+	req.Equal("v0.3.1", notes.Components[1].NewVersion)
+	req.Equal([]string{"fix widgets"}, subjects(notes.Components[0]))
+
+	_, err = eval("", "v0.2.0")
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+
+	_, err = eval("v9.9.9", "v0.2.0")
+	req.Error(err)
+	req.Equal(ExitCodeGit, ExitCode(err))
+}
+
+func TestGetChangesModuleDir(t *testing.T) {
+	req := require.New(t)
+	r := chdirTwoModuleRepo(t)
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out, ModuleDir: "sdk"}).newBaseCommand("test")
+	req.NoError(err)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base, Concurrency: 1}
+
+	commits, err := cmd.GetChanges(r.repo, "", "sdk/v1.4.0", "HEAD")
+	req.NoError(err)
+	req.Len(commits, 1)
+	req.Equal("fix: sdk dial", commits[0].Subject)
+
+	// dependencies are listed in full, whichever module is being built
+	commits, err = cmd.GetChanges(r.repo, "example.com/acme/widgets", "sdk/v1.4.0", "HEAD")
+	req.NoError(err)
+	req.Len(commits, 2)
+
+	// Bob's earlier commit was to the root module, so this is his first to the sdk
+	component := newComponent("github.com/openziti/ziti/sdk", ComponentStatusChanged, "sdk/v1.4.0", "sdk/v1.4.1")
+	component.Current = true
+	component.setChanges("", "sdk/v1.4.0", "HEAD")
+	notes := &ReleaseNotes{Version: "1.4.1", Components: []*ReleaseNotesComponent{component}}
+	req.NoError(cmd.collectChanges(notes))
+	contributors, err := cmd.collectContributors(notes)
+	req.NoError(err)
+	req.Equal([]*ReleaseNotesContributor{
+		{Name: "Bob", Email: "bob@example.com", Commits: 1, FirstTime: true},
+		{Name: "Jane Doe", Email: "jane@example.com", Commits: 1},
+	}, contributors)
+}
+
+func TestGetChangesSideBranch(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	r := newTestRepo(t)
+	initial := r.commit("change.txt", "initial\n", "initial commit")
+	r.commit("change.txt", "add\n", "add widgets")
+
+	// the previous release was tagged on a release branch, off the main line
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Hash: initial, Branch: "refs/heads/release-v0.9", Create: true}))
+	r.tag("v0.9.0", r.commit("version", "0.9.0\n", "release v0.9.0"), false)
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: "refs/heads/master"}))
+	r.commit("change.txt", "fix\n", "fix widgets")
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: errOut}).newBaseCommand("test")
+	req.NoError(err)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base}
+
+	commits, err := cmd.GetChanges(r.repo, "", "v0.9.0", "master")
+	req.NoError(err)
+	var subjects []string
+	for _, commit := range commits {
+		subjects = append(subjects, commit.Subject)
 	}
-	req.Equal(a("10"), getIssues("Fixes #10"))
-	req.Equal(a("12"), getIssues("This commit fixed #12"))
-	req.Equal(a("13", "521"), getIssues("This commit fix #13 and FiXed #521"))
-	req.Equal(a("20", "10", "5"), getIssues("This commit fixes #20, closes #10 and resolves #5"))
-	req.Equal(a("20", "10", "5"), getIssues("This commit fix #20, close #10 and resolve #5"))
-	req.Equal(a("20", "10", "5"), getIssues("This commit fixed #20, closed #10 and resolved #5"))
+	req.Equal([]string{"fix widgets", "add widgets"}, subjects)
+
+	cmd.MaxCommits = 1
+	commits, truncated, err := cmd.getChanges(r.repo, "example.com/acme/widgets", "v0.9.0", "master")
+	req.NoError(err)
+	req.Len(commits, 1)
+	req.True(truncated)
+	// the warning mustn't end up in the notes written to stdout
+	req.Empty(out.String())
+	req.Contains(errOut.String(), "example.com/acme/widgets has 2 commits between v0.9.0 and master, only the newest 1 are listed")
+
+	// exactly --max-commits commits are all listed
+	cmd.MaxCommits = 2
+	commits, truncated, err = cmd.getChanges(r.repo, "example.com/acme/widgets", "v0.9.0", "master")
+	req.NoError(err)
+	req.Len(commits, 2)
+	req.False(truncated)
 }
 
-func getIssues(s string) []string {
-	return (&buildReleaseNotesCmd{}).extractIssues(&object.Commit{
-		Message: s,
-	})
+func TestGetChangesMaxCommitsKeepsNewest(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	r := newTestRepo(t)
+	initial := r.commit("change.txt", "initial\n", "initial commit", "v1.0.0")
+	r.commit("change.txt", "add\n", "add widgets")
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Hash: initial, Branch: "refs/heads/feature", Create: true}))
+	feature := r.commit("feature.txt", "feature\n", "feature work")
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Branch: "refs/heads/master"}))
+	fix := r.commit("change.txt", "fix\n", "fix widgets")
+
+	// the merge's first parent line is walked before the older feature commit
+	f, err := r.wt.Filesystem.Create("feature.txt")
+	req.NoError(err)
+	_, err = f.Write([]byte("feature\n"))
+	req.NoError(err)
+	req.NoError(f.Close())
+	req.NoError(r.wt.AddWithOptions(&git.AddOptions{All: true}))
+	r.when = r.when.Add(time.Minute)
+	signature := &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: r.when}
+	_, err = r.wt.Commit("merge feature", &git.CommitOptions{Author: signature, Committer: signature, Parents: []plumbing.Hash{fix, feature}})
+	req.NoError(err)
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base, MaxCommits: 3}
+
+	commits, truncated, err := cmd.getChanges(r.repo, "", "v1.0.0", "master")
+	req.NoError(err)
+	req.True(truncated)
+	var subjects []string
+	for _, commit := range commits {
+		subjects = append(subjects, commit.Subject)
+	}
+	req.Equal([]string{"fix widgets", "feature work"}, subjects)
+}
+
+func TestFindPreviousRequire(t *testing.T) {
+	req := require.New(t)
+	cmd := &baseBuildReleaseNotesCmd{}
+	old := &modfile.Require{Mod: module.Version{Path: "github.com/openziti/foo", Version: "v1.2.0"}}
+	oldVersions := map[string]*modfile.Require{old.Mod.Path: old}
+
+	prev, found, err := cmd.findPreviousRequire(oldVersions, "github.com/openziti/foo/v3")
+	req.NoError(err)
+	req.True(found)
+	req.Equal(old, prev)
+
+	_, found, err = cmd.findPreviousRequire(oldVersions, "github.com/openziti/bar/v2")
+	req.NoError(err)
+	req.False(found)
+
+	_, _, err = cmd.findPreviousRequire(oldVersions, "github.com/openziti/foo/v99999999999999999999")
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
 }
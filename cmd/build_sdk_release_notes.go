@@ -18,10 +18,8 @@
 package cmd
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
 	"golang.org/x/mod/modfile"
-	"os"
 	"strings"
 )
 
@@ -29,39 +27,47 @@ type buildSdkReleaseNotesCmd struct {
 	baseBuildReleaseNotesCmd
 }
 
-func (cmd *buildSdkReleaseNotesCmd) Execute() {
+func (cmd *buildSdkReleaseNotesCmd) Execute() error {
+	if err := cmd.validateFormat(); err != nil {
+		return err
+	}
 	if !cmd.RootCobraCmd.Flags().Changed("quiet") {
 		cmd.quiet = true
 	}
+	cmd.setRangeArgs()
 
-	cmd.EvalCurrentAndNextVersion()
-	fmt.Printf("# Release notes %v\n", cmd.NextVersion)
-	fmt.Println("\n## Issues Fixed and Dependency Updates")
-	fmt.Println()
-
-	data, err := os.ReadFile("go.mod")
+	notes, err := cmd.evalReleaseNotes()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	newGoMod, err := modfile.Parse("go.mod", data, nil)
-	if err != nil {
-		panic(err)
+	if cmd.Categorized {
+		return cmd.outputReleaseNotes(notes, categorizedSdkReleaseNotesTemplate)
 	}
+	return cmd.outputReleaseNotes(notes, sdkReleaseNotesTemplate)
+}
 
-	output := cmd.runCommandWithOutput("get go.mod contents", "git", "show", fmt.Sprintf("v%v:go.mod", cmd.CurrentVersion))
-	data = []byte(strings.Join(output, "\n"))
-	oldGoMod, err := modfile.Parse("go.mod", data, nil)
+// evalReleaseNotes lists the sdk, followed by all of its dependencies. Only the changes of dependencies matching
+// the dependency filter are collected
+func (cmd *buildSdkReleaseNotesCmd) evalReleaseNotes() (*ReleaseNotes, error) {
+	r, err := cmd.evalReleaseRange()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	fmt.Printf("* %v: [v%v -> v%v](https://github.com/openziti/sdk-golang/compare/v%v...v%v)\n",
-		newGoMod.Module.Mod.Path, cmd.CurrentVersion, cmd.NextVersion, cmd.CurrentVersion, cmd.NextVersion)
-	if err = cmd.GetChanges("sdk-golang", "v"+cmd.CurrentVersion.String(), "HEAD"); err != nil {
-		panic(err)
+	newGoMod, oldGoMod, err := cmd.getGoModChanges(r)
+	if err != nil {
+		return nil, err
 	}
 
+	notes := newReleaseNotes(r)
+
+	component := newComponent(newGoMod.Module.Mod.Path, ComponentStatusChanged, r.from, r.toTag)
+	component.Current = true
+	component.CompareUrl = cmd.Config.compareUrl(cmd.Config.ReleaseNotes.SdkProject, r.from, r.toTag)
+	component.setChanges("", r.from, r.head())
+	notes.Components = append(notes.Components, component)
+
 	oldVersions := map[string]*modfile.Require{}
 
 	for _, m := range oldGoMod.Require {
@@ -69,43 +75,56 @@ func (cmd *buildSdkReleaseNotesCmd) Execute() {
 	}
 
 	for _, m := range newGoMod.Require {
-		prev, found := oldVersions[m.Mod.Path]
-		if !found {
-			path := m.Mod.Path
-			prevVersion := cmd.getPreviousVersion(path)
-			for prevVersion != nil {
-				prev, found = oldVersions[*prevVersion]
-				if found {
-					break
-				}
-				prevVersion = cmd.getPreviousVersion(*prevVersion)
-			}
+		prev, found, err := cmd.findPreviousRequire(oldVersions, m.Mod.Path)
+		if err != nil {
+			return nil, err
 		}
 		if !found {
-			fmt.Printf("* %v: %v (new)\n", m.Mod.Path, m.Mod.Version)
+			notes.Components = append(notes.Components, newComponent(m.Mod.Path, ComponentStatusNew, "", m.Mod.Version))
 		} else if m.Mod.Version != prev.Mod.Version {
-			if strings.Contains(m.Mod.Path, "openziti") {
-				project := strings.Split(m.Mod.Path, "/")[2]
-				fmt.Printf("* %v: [%v -> %v](https://github.com/openziti/%v/compare/%v...%v)\n",
-					m.Mod.Path, prev.Mod.Version, m.Mod.Version, project, prev.Mod.Version, m.Mod.Version)
-				if err = cmd.GetChanges(project, prev.Mod.Version, m.Mod.Version); err != nil {
-					panic(err)
+			component = newComponent(m.Mod.Path, ComponentStatusChanged, prev.Mod.Version, m.Mod.Version)
+			if strings.Contains(m.Mod.Path, cmd.Config.ReleaseNotes.DependencyFilter) {
+				project, err := getModuleProject(m.Mod.Path)
+				if err != nil {
+					return nil, err
 				}
-			} else {
-				fmt.Printf("* %v: %v -> %v\n", m.Mod.Path, prev.Mod.Version, m.Mod.Version)
+				tagPrefix := getModuleTagPrefix(m.Mod.Path)
+				component.CompareUrl = cmd.Config.compareUrl(project, tagPrefix+prev.Mod.Version, tagPrefix+m.Mod.Version)
+				component.setChanges(m.Mod.Path, tagPrefix+prev.Mod.Version, tagPrefix+m.Mod.Version)
 			}
-
+			notes.Components = append(notes.Components, component)
 		} else if cmd.ShowUnchanged {
-			fmt.Printf("* %v: %v (unchanged)\n", m.Mod.Path, m.Mod.Version)
+			notes.Components = append(notes.Components, newComponent(m.Mod.Path, ComponentStatusUnchanged, m.Mod.Version, m.Mod.Version))
+		}
+	}
+
+	if cmd.GoModDiff {
+		notes.GoMod = cmd.diffGoMod(oldGoMod, newGoMod)
+	}
+	if err = cmd.annotateVulnerabilities(notes); err != nil {
+		return nil, err
+	}
+	if cmd.ApiDiff {
+		if notes.Api, err = cmd.evalApiDiff(r.from, r.to); err != nil {
+			return nil, err
 		}
 	}
+	if err = cmd.collectChanges(notes); err != nil {
+		return nil, err
+	}
+	if cmd.Contributors {
+		if notes.Contributors, err = cmd.collectContributors(notes); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
 }
 
 func newBuildSdkReleaseNotesCmd(root *RootCommand) *cobra.Command {
 	cobraCmd := &cobra.Command{
-		Use:   "build-sdk-release-notes",
-		Short: "Prints out the release notes for the latest or a given version",
-		Args:  cobra.MaximumNArgs(1),
+		Use:   "build-sdk-release-notes [from] [to]",
+		Short: "Prints out the sdk release notes for the next version, or between two tags or commits",
+		Args:  cobra.MaximumNArgs(2),
 	}
 
 	result := &buildSdkReleaseNotesCmd{
@@ -118,7 +137,16 @@ func newBuildSdkReleaseNotesCmd(root *RootCommand) *cobra.Command {
 	}
 
 	cobraCmd.Flags().BoolVarP(&result.AllCommits, "all-commits", "a", false, "Show all commits, not just closed issues")
-	cobraCmd.Flags().BoolVarP(&result.ShowUnchanged, "show-unchanged", "u", false, "Show OpenZiti upstream libraries, even if unchanged")
-
-	return Finalize(result)
+	cobraCmd.Flags().BoolVarP(&result.ShowUnchanged, "show-unchanged", "u", false, "Show upstream libraries matching release-notes.dependency-filter, even if unchanged")
+	cobraCmd.Flags().IntVarP(&result.Concurrency, "concurrency", "j", DefaultReleaseNotesConcurrency, "number of repositories whose changes are collected at the same time")
+	cobraCmd.Flags().IntVar(&result.MaxCommits, "max-commits", DefaultReleaseNotesMaxCommits, "maximum number of commits listed for a repository, or 0 for no limit")
+	cobraCmd.Flags().StringVarP(&result.Format, "format", "o", ReleaseNotesFormatMarkdown, "output format. Valid values: [markdown,json,yaml]")
+	cobraCmd.Flags().StringVar(&result.Template, "template", "", "text/template file used to render markdown release notes, instead of the built-in layout")
+	cobraCmd.Flags().BoolVarP(&result.Categorized, "categorized", "c", false, "group all commits into sections, such as Features and Fixes, by conventional commit type or issue labels")
+	cobraCmd.Flags().BoolVar(&result.GoModDiff, "go-mod-diff", false, "add a section listing every go.mod change, including indirect dependencies, replace directives and go version")
+	cobraCmd.Flags().BoolVar(&result.Contributors, "contributors", false, "add a section listing the authors of the release's commits, marking first-time contributors")
+	cobraCmd.Flags().BoolVar(&result.ApiDiff, "api-diff", false, "add a section listing the changes to the module's exported API")
+	cobraCmd.Flags().String("vuln-db", "", "OSV vulnerability database url or directory, ex: https://vuln.go.dev, used to list the advisories fixed by dependency updates")
+
+	return FinalizeErroringCmd(result)
 }
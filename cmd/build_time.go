@@ -0,0 +1,88 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BuildTimeNow    = "now"
+	BuildTimeCommit = "commit"
+
+	// SourceDateEpochEnvVar follows https://reproducible-builds.org/specs/source-date-epoch/
+	SourceDateEpochEnvVar = "SOURCE_DATE_EPOCH"
+)
+
+func (cmd *BaseCommand) validateBuildTime() error {
+	if cmd.buildTimeMode != BuildTimeNow && cmd.buildTimeMode != BuildTimeCommit {
+		return configErrorf("unsupported build time: '%v'. Valid values: [%v,%v]", cmd.buildTimeMode, BuildTimeNow, BuildTimeCommit)
+	}
+	return nil
+}
+
+// getBuildTime returns the time stamped into build metadata and archives. SOURCE_DATE_EPOCH takes precedence,
+// then the HEAD commit time if --build-time is commit. Otherwise, it's the current time. The second result
+// reports whether the time is reproducible, in which case archives also drop file times and ownership
+func (cmd *BaseCommand) getBuildTime() (time.Time, bool, error) {
+	if cmd.buildTime == nil {
+		buildTime, reproducible, err := cmd.evalBuildTime()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		cmd.buildTime = &buildTime
+		cmd.reproducibleBuildTime = reproducible
+	}
+	return *cmd.buildTime, cmd.reproducibleBuildTime, nil
+}
+
+func (cmd *BaseCommand) evalBuildTime() (time.Time, bool, error) {
+	if val, found := os.LookupEnv(SourceDateEpochEnvVar); found && val != "" {
+		epoch, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil {
+			return time.Time{}, false, configErrorf("invalid %v '%v', expected seconds since the unix epoch: %w", SourceDateEpochEnvVar, val, err)
+		}
+		return time.Unix(epoch, 0).UTC(), true, nil
+	}
+
+	if cmd.buildTimeMode == BuildTimeCommit {
+		g, err := cmd.getGit()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		commitTime, err := g.CommitTime("HEAD")
+		if err != nil {
+			return time.Time{}, false, gitErrorf("unable to get HEAD commit time: %w", err)
+		}
+		return commitTime.UTC(), true, nil
+	}
+
+	return time.Now(), false, nil
+}
+
+// getBuildDate returns the build time formatted for build info
+func (cmd *BaseCommand) getBuildDate() (string, error) {
+	buildTime, _, err := cmd.getBuildTime()
+	if err != nil {
+		return "", err
+	}
+	return buildTime.Format(time.RFC3339), nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildTime(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0")
+
+	out := &bytes.Buffer{}
+	opts := &Options{Out: out, Err: out}
+
+	cmd, err := opts.newBaseCommand("test")
+	req.NoError(err)
+	_, reproducible, err := cmd.getBuildTime()
+	req.NoError(err)
+	req.False(reproducible)
+
+	// chdirTestRepo commits once a minute from the start of 2024
+	cmd.buildTimeMode = BuildTimeCommit
+	cmd.buildTime = nil
+	buildDate, err := cmd.getBuildDate()
+	req.NoError(err)
+	req.Equal("2024-01-01T00:02:00Z", buildDate)
+	req.True(cmd.reproducibleBuildTime)
+
+	t.Setenv(SourceDateEpochEnvVar, "1700000000")
+	cmd.buildTime = nil
+	buildDate, err = cmd.getBuildDate()
+	req.NoError(err)
+	req.Equal("2023-11-14T22:13:20Z", buildDate)
+
+	t.Setenv(SourceDateEpochEnvVar, "yesterday")
+	cmd.buildTime = nil
+	_, err = cmd.getBuildDate()
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+}
+
+func TestReproducibleArchives(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	t.Setenv(SourceDateEpochEnvVar, "1700000000")
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+
+	req.NoError(os.WriteFile("a.txt", []byte("a\n"), 0644))
+	req.NoError(os.WriteFile("b.txt", []byte("b\n"), 0600))
+
+	nameMap := map[string]string{"a.txt": "a.txt", "b.txt": "b.txt", "version": "version"}
+	req.NoError(cmd.tarGz("first.tar.gz", nameMap))
+	req.NoError(cmd.zip("first.zip", nameMap))
+
+	later := time.Now().Add(time.Hour)
+	req.NoError(os.Chtimes("a.txt", later, later))
+	req.NoError(os.Chmod("b.txt", 0664))
+	req.NoError(cmd.tarGz("second.tar.gz", nameMap))
+	req.NoError(cmd.zip("second.zip", nameMap))
+
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		first, err := hashFiles("first" + ext)
+		req.NoError(err)
+		second, err := hashFiles("second" + ext)
+		req.NoError(err)
+		req.Equal(first["first"+ext], second["second"+ext], ext)
+	}
+
+	req.Equal([]string{"a.txt: sha256 1 != 2", "c.txt: only produced by the second run"},
+		compareHashes(fileHashes{"a.txt": "1", "b.txt": "3"}, fileHashes{"a.txt": "2", "b.txt": "3", "c.txt": "4"}))
+}
+
+func TestVerifyReproducibleBuild(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3")
+
+	verify := func(script string, outputs ...string) error {
+		out := &bytes.Buffer{}
+		base, err := (&Options{Out: out, Err: out}).newBaseCommand("verify-reproducible")
+		req.NoError(err)
+		base.Args = []string{"sh", "-c", script}
+		return (&verifyReproducibleBuildCmd{BaseCommand: *base, outputs: outputs}).Execute()
+	}
+
+	// nothing the build didn't create is removed
+	req.NoError(os.WriteFile("existing", []byte("keep\n"), 0644))
+	for _, output := range []string{".", "..", r.dir(), "existing"} {
+		err := verify("true", output)
+		req.Error(err, output)
+		req.Equal(ExitCodeConfig, ExitCode(err), output)
+	}
+	contents, err := os.ReadFile("existing")
+	req.NoError(err)
+	req.Equal("keep\n", string(contents))
+
+	// the second run starts without the first run's outputs, and its own are kept
+	req.NoError(verify("test ! -e dist && mkdir dist && echo widget > dist/widget", "dist"))
+	contents, err = os.ReadFile(filepath.Join("dist", "widget"))
+	req.NoError(err)
+	req.Equal("widget\n", string(contents))
+	matches, err := filepath.Glob(".ziti-ci-verify-*")
+	req.NoError(err)
+	req.Empty(matches)
+
+	req.NoError(os.RemoveAll("dist"))
+	err = verify("mkdir dist && date +%N > dist/widget", "dist")
+	req.Error(err)
+	req.Contains(err.Error(), "the build isn't reproducible")
+}
+
+func TestVerifyReproduciblePackage(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	t.Setenv(SourceDateEpochEnvVar, "")
+
+	req.NoError(os.WriteFile("a.txt", []byte("a\n"), 0644))
+	req.NoError(os.WriteFile("b.sh", []byte("#!/bin/sh\n"), 0755))
+
+	verify := func(zip bool) error {
+		out := &bytes.Buffer{}
+		base, err := (&Options{Out: out, Err: out}).newBaseCommand("verify-reproducible")
+		req.NoError(err)
+		base.Args = []string{"a.txt", "b.sh"}
+		return (&verifyReproduciblePackageCmd{BaseCommand: *base, zip: zip}).Execute()
+	}
+
+	// without a reproducible build time the tar headers carry the modification times and modes of the inputs
+	err := verify(false)
+	req.Error(err)
+	req.Contains(err.Error(), "the build isn't reproducible")
+
+	t.Setenv(SourceDateEpochEnvVar, "1700000000")
+	req.NoError(verify(false))
+	req.NoError(verify(true))
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-version"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ChangelogFormatZiti           = "ziti"
+	ChangelogFormatKeepAChangelog = "keepachangelog"
+	ChangelogFormatRegex          = "regex"
+
+	// ChangelogUnreleased is the version of the section holding changes which haven't been released yet
+	ChangelogUnreleased = "Unreleased"
+)
+
+var changelogFormats = []string{ChangelogFormatZiti, ChangelogFormatKeepAChangelog, ChangelogFormatRegex}
+
+// changelogFormat recognizes the release headings of a changelog, and writes new ones
+type changelogFormat struct {
+	name    string
+	heading *regexp.Regexp
+	// newHeading returns the heading of a new release section, or is nil if the format can't write headings
+	newHeading func(version string, date time.Time) string
+}
+
+// ChangelogSection is the part of a changelog describing one release
+type ChangelogSection struct {
+	Version string `json:"version"`
+	Date    string `json:"date,omitempty"`
+	Heading string `json:"heading"`
+	Body    string `json:"body"`
+
+	// lines of the changelog from the heading up to the next heading
+	start, end int
+}
+
+func (s *ChangelogSection) isUnreleased() bool {
+	return strings.EqualFold(s.Version, ChangelogUnreleased)
+}
+
+// getChangelogFormat returns the changelog heading format selected by the changelog configuration
+func (cmd *BaseCommand) getChangelogFormat() (*changelogFormat, error) {
+	return newChangelogFormat(cmd.Config.Changelog.HeadingFormat, cmd.Config.Changelog.HeadingRegex)
+}
+
+func newChangelogFormat(name, headingRegex string) (*changelogFormat, error) {
+	switch name {
+	case ChangelogFormatZiti:
+		return &changelogFormat{
+			name:    name,
+			heading: regexp.MustCompile(`^# Release\s+v?(?P<version>\S+)`),
+			newHeading: func(version string, date time.Time) string {
+				return "# Release " + version
+			},
+		}, nil
+	case ChangelogFormatKeepAChangelog:
+		return &changelogFormat{
+			name:    name,
+			heading: regexp.MustCompile(`^## \[v?(?P<version>[^\]]+)\](?:\s+-\s+(?P<date>\S+))?`),
+			newHeading: func(version string, date time.Time) string {
+				return fmt.Sprintf("## [%v] - %v", version, date.Format(time.DateOnly))
+			},
+		}, nil
+	case ChangelogFormatRegex:
+		if headingRegex == "" {
+			return nil, configErrorf("changelog heading format regex requires changelog.heading-regex")
+		}
+		heading, err := regexp.Compile(headingRegex)
+		if err != nil {
+			return nil, configErrorf("invalid changelog heading regex %v: %w", headingRegex, err)
+		}
+		if heading.SubexpIndex("version") < 0 {
+			return nil, configErrorf("changelog heading regex %v has no version group, ex: (?P<version>\\S+)", headingRegex)
+		}
+		return &changelogFormat{name: name, heading: heading}, nil
+	default:
+		return nil, configErrorf("invalid changelog heading format %v. Valid values: %v", name, changelogFormats)
+	}
+}
+
+// parse returns the release sections of a changelog, in the order they appear
+func (f *changelogFormat) parse(changelog string) []*ChangelogSection {
+	lines := strings.Split(changelog, "\n")
+
+	var result []*ChangelogSection
+	for i, line := range lines {
+		match := f.heading.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if len(result) > 0 {
+			result[len(result)-1].end = i
+		}
+		section := &ChangelogSection{
+			Version: strings.TrimPrefix(match[f.heading.SubexpIndex("version")], "v"),
+			Heading: line,
+			start:   i,
+			end:     len(lines),
+		}
+		if idx := f.heading.SubexpIndex("date"); idx >= 0 {
+			section.Date = match[idx]
+		}
+		result = append(result, section)
+	}
+
+	for _, section := range result {
+		section.Body = strings.Trim(strings.Join(lines[section.start+1:section.end], "\n"), "\n")
+	}
+	return result
+}
+
+// findSection returns the section for the given version, or nil if there is none
+func (f *changelogFormat) findSection(changelog, v string) *ChangelogSection {
+	v = strings.TrimPrefix(v, "v")
+	for _, section := range f.parse(changelog) {
+		if section.Version == v || (strings.EqualFold(v, ChangelogUnreleased) && section.isUnreleased()) {
+			return section
+		}
+	}
+	return nil
+}
+
+// selectChangelogSections returns the sections matching the selector, which is one of:
+//   - empty, for the latest release
+//   - unreleased, for the Unreleased section
+//   - a version, ex: 1.2.3
+//   - a version range, ex: 1.2.0..1.3.0, for the releases after the first version up to and including the second.
+//     Either end may be left out
+func selectChangelogSections(sections []*ChangelogSection, selector string) ([]*ChangelogSection, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		for _, section := range sections {
+			if !section.isUnreleased() {
+				return []*ChangelogSection{section}, nil
+			}
+		}
+		return nil, nil
+	}
+
+	if from, to, isRange := strings.Cut(selector, ".."); isRange {
+		parse := func(v string) (*version.Version, error) {
+			if v == "" {
+				return nil, nil
+			}
+			result, err := version.NewVersion(v)
+			if err != nil {
+				return nil, configErrorf("invalid version %v in range %v: %w", v, selector, err)
+			}
+			return result, nil
+		}
+		fromVersion, err := parse(from)
+		if err != nil {
+			return nil, err
+		}
+		toVersion, err := parse(to)
+		if err != nil {
+			return nil, err
+		}
+		var result []*ChangelogSection
+		for _, section := range sections {
+			v, err := version.NewVersion(section.Version)
+			if err != nil {
+				continue
+			}
+			if (fromVersion == nil || v.GreaterThan(fromVersion)) && (toVersion == nil || v.LessThanOrEqual(toVersion)) {
+				result = append(result, section)
+			}
+		}
+		return result, nil
+	}
+
+	selector = strings.TrimPrefix(selector, "v")
+	idx := slices.IndexFunc(sections, func(section *ChangelogSection) bool {
+		if strings.EqualFold(selector, ChangelogUnreleased) {
+			return section.isUnreleased()
+		}
+		return section.Version == selector
+	})
+	if idx < 0 {
+		return nil, nil
+	}
+	return sections[idx : idx+1], nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const keepAChangelog = `# Changelog
+
+## [Unreleased]
+
+- Upcoming change
+
+## [1.3.0] - 2024-05-01
+
+- Widgets
+
+## [1.2.1] - 2024-04-01
+
+- Fixed widgets
+
+## [1.2.0] - 2024-03-01
+
+- First widgets
+`
+
+func TestParseChangelog(t *testing.T) {
+	req := require.New(t)
+
+	format, err := newChangelogFormat(ChangelogFormatKeepAChangelog, "")
+	req.NoError(err)
+	sections := format.parse(keepAChangelog)
+	req.Len(sections, 4)
+	req.Equal(ChangelogUnreleased, sections[0].Version)
+	req.Equal("- Upcoming change", sections[0].Body)
+	req.Equal("1.3.0", sections[1].Version)
+	req.Equal("2024-05-01", sections[1].Date)
+	req.Equal("## [1.3.0] - 2024-05-01", sections[1].Heading)
+
+	versions := func(selector string) []string {
+		selected, err := selectChangelogSections(sections, selector)
+		req.NoError(err)
+		var result []string
+		for _, section := range selected {
+			result = append(result, section.Version)
+		}
+		return result
+	}
+	req.Equal([]string{"1.3.0"}, versions(""))
+	req.Equal([]string{ChangelogUnreleased}, versions("unreleased"))
+	req.Equal([]string{"1.2.1"}, versions("v1.2.1"))
+	req.Equal([]string{"1.3.0", "1.2.1"}, versions("1.2.0..1.3.0"))
+	req.Equal([]string{"1.2.1", "1.2.0"}, versions("..1.2.1"))
+	req.Nil(versions("2.0.0"))
+	_, err = selectChangelogSections(sections, "1.2.0..latest")
+	req.Error(err)
+
+	ziti, err := newChangelogFormat(ChangelogFormatZiti, "")
+	req.NoError(err)
+	sections = ziti.parse("# Release 0.3.1\n\n* one\n\n# Release v0.3.0\n\n* two\n")
+	req.Len(sections, 2)
+	req.Equal("0.3.0", sections[1].Version)
+	req.Equal("* two", sections[1].Body)
+
+	custom, err := newChangelogFormat(ChangelogFormatRegex, `^Version (?P<version>\S+) \((?P<date>[^)]+)\)`)
+	req.NoError(err)
+	sections = custom.parse("Version 2.0 (May 2024)\n* two\nVersion 1.0 (April 2024)\n* one\n")
+	req.Len(sections, 2)
+	req.Equal("May 2024", sections[0].Date)
+	req.Equal("* one", sections[1].Body)
+
+	_, err = newChangelogFormat(ChangelogFormatRegex, `^Version (\S+)`)
+	req.Error(err)
+	_, err = newChangelogFormat("rst", "")
+	req.Error(err)
+}
+
+func TestExtractReleaseNotes(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "1.3")
+	req.NoError(os.WriteFile(".ziti-ci.yaml", []byte("changelog:\n  heading-format: keepachangelog\n"), 0644))
+	req.NoError(os.WriteFile("CHANGELOG.md", []byte(keepAChangelog), 0644))
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("get-release-notes")
+	req.NoError(err)
+
+	req.NoError(cmd.extractReleaseNotes("CHANGELOG.md", "1.2.1", "", false, ReleaseNotesSectionFormatText))
+	req.Equal("## [1.2.1] - 2024-04-01\n\n- Fixed widgets\n\n", out.String())
+
+	outfile := filepath.Join(t.TempDir(), "notes.md")
+	req.NoError(cmd.extractReleaseNotes("CHANGELOG.md", "unreleased", outfile, false, ReleaseNotesSectionFormatText))
+	data, err := os.ReadFile(outfile)
+	req.NoError(err)
+	req.Equal("## [Unreleased]\n\n- Upcoming change\n\n", string(data))
+
+	out.Reset()
+	req.NoError(cmd.extractReleaseNotes("CHANGELOG.md", "", "", true, ReleaseNotesSectionFormatJson))
+	var sections []*ChangelogSection
+	req.NoError(json.Unmarshal(out.Bytes(), &sections))
+	req.Len(sections, 4)
+	req.Equal("- First widgets", sections[3].Body)
+
+	req.Error(cmd.extractReleaseNotes("CHANGELOG.md", "", "", false, "xml"))
+}
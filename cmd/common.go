@@ -23,19 +23,26 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/hashicorp/go-version"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
 	"io"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
+var releaseBranchRegex = regexp.MustCompile(`^release-v(\d+)\.(\d+)(?:\.x)?$`)
+
+// prereleaseChannelRegex matches dot separated semver pre-release identifiers
+var prereleaseChannelRegex = regexp.MustCompile(`^[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*$`)
+
 const (
 	DefaultGitUsername    = "ziti-ci"
 	DefaultGitEmail       = "ziti-ci@netfoundry.io"
@@ -45,29 +52,19 @@ const (
 	DefaultSshKeyFile     = "github_deploy_key"
 )
 
-type CiCmd interface {
-	GetCobraCmd() *cobra.Command
-	Init(args []string)
-	Execute()
-}
-
 type ErroringCiCmd interface {
 	GetCobraCmd() *cobra.Command
-	Init(args []string)
+	Init(args []string) error
 	Execute() error
 }
 
-func Finalize(cmd CiCmd) *cobra.Command {
-	cmd.GetCobraCmd().Run = func(_ *cobra.Command, args []string) {
-		cmd.Init(args)
-		cmd.Execute()
-	}
-	return cmd.GetCobraCmd()
-}
-
 func FinalizeErroringCmd(cmd ErroringCiCmd) *cobra.Command {
-	cmd.GetCobraCmd().RunE = func(_ *cobra.Command, args []string) error {
-		cmd.Init(args)
+	cmd.GetCobraCmd().RunE = func(cobraCmd *cobra.Command, args []string) error {
+		// the arguments were accepted, so failures from here on aren't usage errors
+		cobraCmd.SilenceUsage = true
+		if err := cmd.Init(args); err != nil {
+			return err
+		}
 		return cmd.Execute()
 	}
 	return cmd.GetCobraCmd()
@@ -82,14 +79,23 @@ type BaseCommand struct {
 	BaseVersion    *version.Version
 	CurrentVersion *version.Version
 	NextVersion    *version.Version
+	BumpReasons    []*versionBumpReason
+	VersionTrace   *versionTrace
 
 	CurrentBranch *string
 	BuildNumber   *string
-}
 
-func (cmd *BaseCommand) Failf(format string, params ...interface{}) {
-	_, _ = fmt.Fprintf(cmd.Cmd.ErrOrStderr(), format, params...)
-	os.Exit(1)
+	buildTime             *time.Time
+	reproducibleBuildTime bool
+
+	repoCache *repoCache
+
+	// Config holds the repository settings, ConfigFile the file they were read from, if any
+	Config       *RepoConfig
+	ConfigFile   string
+	ConfigValues []*configValue
+
+	git Git
 }
 
 func (cmd *BaseCommand) Infof(format string, params ...interface{}) {
@@ -98,21 +104,48 @@ func (cmd *BaseCommand) Infof(format string, params ...interface{}) {
 	}
 }
 
+// Printf writes command output, as opposed to informational messages, which are suppressed in quiet mode
+func (cmd *BaseCommand) Printf(format string, params ...interface{}) {
+	_, _ = fmt.Fprintf(cmd.Cmd.OutOrStdout(), format, params...)
+}
+
 func (cmd *BaseCommand) Errorf(format string, params ...interface{}) {
 	_, _ = fmt.Fprintf(cmd.Cmd.OutOrStderr(), format, params...)
 }
+
+// Warnf writes a warning to stderr, so it never mixes with command output
 func (cmd *BaseCommand) Warnf(format string, params ...interface{}) {
-	_, _ = fmt.Fprintf(cmd.Cmd.OutOrStdout(), "WARNING: "+format, params...)
+	_, _ = fmt.Fprintf(cmd.Cmd.ErrOrStderr(), "WARNING: "+format, params...)
 }
 
-func (cmd *BaseCommand) exitIfErrf(err error, format string, params ...interface{}) {
-	if err != nil {
-		cmd.Failf(format, params...)
+func (cmd *BaseCommand) isGoLang() bool {
+	return cmd.lang == LangGo
+}
+
+// getTagPrefix returns the prefix which version tags for the current module carry, ex: sdk/ for sdk/v1.4.2
+func (cmd *BaseCommand) getTagPrefix() string {
+	if cmd.tagPrefix != "" {
+		return cmd.tagPrefix
 	}
+	return moduleTagPrefix(cmd.moduleDir)
 }
 
-func (cmd *BaseCommand) isGoLang() bool {
-	return cmd.lang == LangGo
+// getVersionTag returns the name of the tag for the given version, including any module prefix
+func (cmd *BaseCommand) getVersionTag(v *version.Version) string {
+	if cmd.isGoLang() {
+		return fmt.Sprintf("%vv%v", cmd.getTagPrefix(), v)
+	}
+	return fmt.Sprintf("%v%v", cmd.getTagPrefix(), v)
+}
+
+// getModuleFile returns the path of the given file in the current module directory
+func (cmd *BaseCommand) getModuleFile(name string) string {
+	return filepath.Join(cmd.moduleDir, name)
+}
+
+// getModuleRepoDir returns the module directory in the form git uses for paths, or an empty string for the root module
+func (cmd *BaseCommand) getModuleRepoDir() string {
+	return cleanRepoDir(cmd.moduleDir)
 }
 
 func (cmd *BaseCommand) getPublishVersion() *version.Version {
@@ -122,103 +155,325 @@ func (cmd *BaseCommand) getPublishVersion() *version.Version {
 	return cmd.CurrentVersion
 }
 
-func (cmd *BaseCommand) setLangType() {
+func (cmd *BaseCommand) setLangType() error {
 	if cmd.langName == "" {
-		return
+		return nil
 	}
 	if strings.EqualFold("go", cmd.langName) {
 		cmd.lang = LangGo
 	} else if strings.EqualFold("java", cmd.langName) {
 		cmd.lang = LangJava
+	} else if strings.EqualFold("c", cmd.langName) {
+		cmd.lang = LangC
+	} else if strings.EqualFold("rust", cmd.langName) {
+		cmd.lang = LangRust
+	} else if strings.EqualFold("node", cmd.langName) {
+		cmd.lang = LangNode
 	} else {
-		cmd.Failf("unsupported language: '%v'\n", cmd.langName)
+		return configErrorf("unsupported language: '%v'", cmd.langName)
 	}
+	return nil
 }
 
-func (cmd *BaseCommand) Init(args []string) {
+func (cmd *BaseCommand) Init(args []string) error {
 	cmd.Args = args
-	cmd.setLangType()
-	cmd.BaseVersion = cmd.getBaseVersion()
+	cmd.VersionTrace = &versionTrace{}
+	if err := cmd.loadConfig(); err != nil {
+		return err
+	}
+	cmd.repoCache = newRepoCache()
+	if err := cmd.setLangType(); err != nil {
+		return err
+	}
+	if err := cmd.validateBumpStrategy(); err != nil {
+		return err
+	}
+	if err := cmd.validatePrereleaseChannel(); err != nil {
+		return err
+	}
+	if err := cmd.validateVersionSourceKind(); err != nil {
+		return err
+	}
+	if err := cmd.validateBuildTime(); err != nil {
+		return err
+	}
+	baseVersion, err := cmd.getBaseVersion()
+	if err != nil {
+		return err
+	}
+	cmd.BaseVersion = baseVersion
+	return nil
 }
 
 func (cmd *BaseCommand) GetCobraCmd() *cobra.Command {
 	return cmd.Cmd
 }
 
-func (cmd *BaseCommand) EvalCurrentAndNextVersion() {
-	cmd.runGitCommandAlways("fetching git tags", "fetch", "--tags", "--force")
-	versions := cmd.getVersionList("tag", "--list")
-
-	min := setPatch(cmd.BaseVersion, 0)
-	max := getNext(Minor, min)
-	if len(versions) == 0 {
-		cmd.NextVersion = min
+func (cmd *BaseCommand) EvalCurrentAndNextVersion() error {
+	if err := cmd.fetchTags(); err != nil {
+		return err
 	}
-
-	for _, v := range versions {
-		if min.LessThanOrEqual(v) && v.LessThan(max) {
-			cmd.CurrentVersion = v
-		}
+	tags, err := cmd.listTags("listing git tags", Git.ListTags)
+	if err != nil {
+		return err
 	}
+	allVersions := cmd.getAllVersionList("tags", tags)
+	versions := filterReleaseVersions(allVersions)
 
-	if cmd.CurrentVersion == nil {
-		cmd.NextVersion = min
+	if err = cmd.VersionTrace.recordSettings(cmd); err != nil {
+		return err
+	}
 
-		for _, v := range versions {
-			if (cmd.CurrentVersion == nil || cmd.CurrentVersion.LessThan(v)) && v.LessThan(max) {
-				cmd.CurrentVersion = v
-			}
-		}
+	releaseLine, err := cmd.getReleaseBranchLine()
+	if err != nil {
+		return err
+	}
+	if releaseLine != nil {
+		cmd.VersionTrace.ReleaseLine = releaseLine.String()
+		err = cmd.evalReleaseLineNextVersion(releaseLine)
+	} else if cmd.bumpStrategy == BumpStrategyConventional {
+		err = cmd.evalConventionalNextVersion(versions)
 	} else {
-		cmd.NextVersion = getNext(Patch, cmd.CurrentVersion)
+		err = cmd.evalPatchNextVersion(versions)
+	}
+	if err != nil {
+		return err
 	}
 
-	if cmd.NextVersion.LessThan(cmd.BaseVersion) {
+	// a maintenance branch only honors the base version if it belongs to the line the branch maintains
+	if cmd.NextVersion.LessThan(cmd.BaseVersion) && (releaseLine == nil || isSameMinorLine(releaseLine, cmd.BaseVersion)) {
 		cmd.NextVersion = cmd.BaseVersion
 	}
 
+	if cmd.isPrerelease() {
+		if err = cmd.evalPrereleaseVersion(allVersions); err != nil {
+			return err
+		}
+	}
+
 	if cmd.useCurrentTag {
 		tag := ""
 		if strings.EqualFold("true", os.Getenv("GITHUB_ACTIONS")) {
 			if cmd.verbose {
-				fmt.Println("running in github actions, getting tag name from environment")
+				cmd.Infof("running in github actions, getting tag name from environment\n")
 			}
 			tag = os.Getenv("GITHUB_REF_NAME")
+			cmd.VersionTrace.CurrentTagSource = "GITHUB_REF_NAME"
 			if tag == "" {
-				panic(errors.New("GITHUB_REF_NAME not set"))
+				return configErrorf("GITHUB_REF_NAME not set")
 			}
 			if cmd.verbose {
-				fmt.Printf("running in github actions, found tag: %s\n", tag)
+				cmd.Infof("running in github actions, found tag: %s\n", tag)
 			}
 		} else {
-			tags := cmd.runCommandWithOutput("get current git tag", "git", "describe", "--tags")
-			tag = tags[0]
+			g, err := cmd.getGit()
+			if err != nil {
+				return err
+			}
+			if tag, err = g.Describe(cmd.getTagPrefix()); err != nil {
+				return gitErrorf("error getting current git tag: %w", err)
+			}
+			cmd.VersionTrace.CurrentTagSource = "git describe --tags"
 			if cmd.verbose {
-				fmt.Printf("got tag name from git: %s\n", tag)
+				cmd.Infof("got tag name from git: %s\n", tag)
 			}
 		}
-		v, err := version.NewVersion(tag)
+		prefix := cmd.getTagPrefix()
+		if !strings.HasPrefix(tag, prefix) {
+			return versionErrorf("current tag %s doesn't have tag prefix %s", tag, prefix)
+		}
+		v, err := version.NewVersion(strings.TrimPrefix(tag, prefix))
 		if err != nil {
-			panic(fmt.Errorf("unable to parse tag %s", tag))
+			return versionErrorf("unable to parse tag %s: %w", tag, err)
 		}
 
 		cmd.CurrentVersion = v
+		cmd.VersionTrace.CurrentTag = tag
 	}
 
-	if !cmd.quiet {
-		fmt.Printf("current version: %v, next version: %v\n", cmd.CurrentVersion, cmd.NextVersion)
+	cmd.VersionTrace.recordResult(cmd)
+
+	cmd.Infof("current version: %v, next version: %v\n", cmd.CurrentVersion, cmd.NextVersion)
+	cmd.explainBumpReasons()
+	return nil
+}
+
+// getReleaseBranchLine returns the first version of the minor line maintained by the current branch, if it's a
+// maintenance branch such as release-v0.34, otherwise nil
+func (cmd *BaseCommand) getReleaseBranchLine() (*version.Version, error) {
+	if !cmd.releaseBranchLines {
+		return nil, nil
+	}
+	branch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	match := releaseBranchRegex.FindStringSubmatch(branch)
+	if match == nil {
+		return nil, nil
+	}
+	line, err := version.NewVersion(fmt.Sprintf("%v.%v.0", match[1], match[2]))
+	if err != nil {
+		return nil, versionErrorf("unable to parse release line from branch %v: %w", branch, err)
+	}
+	return line, nil
+}
+
+// evalReleaseLineNextVersion computes the next patch of a maintenance line, looking only at the tags reachable
+// from the current branch, so that tags on newer lines don't influence the result
+func (cmd *BaseCommand) evalReleaseLineNextVersion(min *version.Version) error {
+	max, err := getNext(Minor, min)
+	if err != nil {
+		return versionErrorf("unable to find the end of the %v line: %w", min, err)
+	}
+	cmd.VersionTrace.setWindow(min, max)
+	tags, err := cmd.listTags("listing git tags merged into HEAD", func(g Git) ([]string, error) {
+		return g.TagsMergedInto("HEAD")
+	})
+	if err != nil {
+		return err
+	}
+	versions := filterReleaseVersions(cmd.getAllVersionList("tags merged into HEAD", tags))
+
+	for _, v := range versions {
+		if min.LessThanOrEqual(v) && v.LessThan(max) {
+			cmd.CurrentVersion = v
+		}
+	}
+
+	if cmd.CurrentVersion == nil {
+		cmd.NextVersion = min
+	} else if cmd.NextVersion, err = getNext(Patch, cmd.CurrentVersion); err != nil {
+		return versionErrorf("unable to bump %v: %w", cmd.CurrentVersion, err)
+	}
+
+	branch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	cmd.BumpReasons = append(cmd.BumpReasons, &versionBumpReason{
+		Subject: fmt.Sprintf("branch %v maintains the %v.%v line, only patch releases are made from it", branch, min.Segments()[0], min.Segments()[1]),
+		Bump:    bumpName(Patch),
+	})
+	return nil
+}
+
+// evalPrereleaseVersion turns the next release version into the next pre-release of it on the requested channel.
+// The current version becomes the latest existing pre-release of the next release, if there is one, so that
+// a final release later graduates the candidate
+func (cmd *BaseCommand) evalPrereleaseVersion(allVersions []*version.Version) error {
+	next, latest, err := nextPrerelease(cmd.NextVersion, cmd.prereleaseChannel, allVersions)
+	if err != nil {
+		return versionErrorf("unable to make pre-release of %v on channel %v: %w", cmd.NextVersion, cmd.prereleaseChannel, err)
+	}
+	if latest != nil {
+		if next.LessThan(latest) {
+			cmd.Warnf("next pre-release %v sorts before existing pre-release %v\n", next, latest)
+		}
+		cmd.CurrentVersion = latest
+	}
+	cmd.BumpReasons = append(cmd.BumpReasons, &versionBumpReason{
+		Subject: fmt.Sprintf("pre-release of %v on channel %v", cmd.NextVersion, cmd.prereleaseChannel),
+		Bump:    "prerelease",
+	})
+	cmd.NextVersion = next
+	return nil
+}
+
+func (cmd *BaseCommand) isPrerelease() bool {
+	return cmd.prereleaseChannel != ""
+}
+
+func (cmd *BaseCommand) validatePrereleaseChannel() error {
+	if !cmd.isPrerelease() {
+		return nil
+	}
+	if !prereleaseChannelRegex.MatchString(cmd.prereleaseChannel) {
+		return configErrorf("invalid pre-release channel: '%v'. Only letters, digits, hyphens and dots between them are allowed", cmd.prereleaseChannel)
+	}
+	for _, channel := range cmd.prereleaseChannels {
+		if channel == cmd.prereleaseChannel {
+			return nil
+		}
+	}
+	return configErrorf("unsupported pre-release channel: '%v'. Valid values: %v", cmd.prereleaseChannel, cmd.prereleaseChannels)
+}
+
+func (cmd *BaseCommand) evalPatchNextVersion(versions []*version.Version) error {
+	min, err := setPatch(cmd.BaseVersion, 0)
+	if err != nil {
+		return versionErrorf("unable to find the start of the %v line: %w", cmd.BaseVersion, err)
+	}
+	max, err := getNext(Minor, min)
+	if err != nil {
+		return versionErrorf("unable to find the end of the %v line: %w", min, err)
+	}
+	cmd.VersionTrace.setWindow(min, max)
+	if len(versions) == 0 {
+		cmd.NextVersion = min
+	}
+
+	for _, v := range versions {
+		if min.LessThanOrEqual(v) && v.LessThan(max) {
+			cmd.CurrentVersion = v
+		}
+	}
+
+	if cmd.CurrentVersion == nil {
+		cmd.NextVersion = min
+
+		for _, v := range versions {
+			if (cmd.CurrentVersion == nil || cmd.CurrentVersion.LessThan(v)) && v.LessThan(max) {
+				cmd.CurrentVersion = v
+			}
+		}
+	} else if cmd.NextVersion, err = getNext(Patch, cmd.CurrentVersion); err != nil {
+		return versionErrorf("unable to bump %v: %w", cmd.CurrentVersion, err)
+	}
+
+	cmd.BumpReasons = append(cmd.BumpReasons, &versionBumpReason{
+		Subject: fmt.Sprintf("next patch in the %v line from base version %v", min, cmd.BaseVersion),
+		Bump:    bumpName(Patch),
+	})
+	return nil
+}
+
+// evalConventionalNextVersion bumps the most recent release according to the conventional commit markers
+// found since it was tagged. The base version acts as a floor, rather than pinning the minor line
+func (cmd *BaseCommand) evalConventionalNextVersion(versions []*version.Version) error {
+	if len(versions) == 0 {
+		next, err := setPatch(cmd.BaseVersion, 0)
+		if err != nil {
+			return versionErrorf("unable to find the start of the %v line: %w", cmd.BaseVersion, err)
+		}
+		cmd.NextVersion = next
+		return nil
+	}
+
+	// every version counts, the base version only puts a floor under the next one
+	cmd.CurrentVersion = versions[len(versions)-1]
+	currentTag := cmd.getVersionTag(cmd.CurrentVersion)
+
+	bump, reasons, err := cmd.evalConventionalBump(currentTag)
+	if err != nil {
+		return gitErrorf("unable to evaluate commits since %v: %w", currentTag, err)
 	}
+	cmd.BumpReasons = reasons
+	if cmd.NextVersion, err = getNext(bump, cmd.CurrentVersion); err != nil {
+		return versionErrorf("unable to bump %v: %w", cmd.CurrentVersion, err)
+	}
+	return nil
 }
 
-func (cmd *BaseCommand) RunGitCommand(description string, params ...string) {
-	cmd.runGitCommandOptional(description, cmd.dryRun, params...)
+func (cmd *BaseCommand) RunGitCommand(description string, params ...string) error {
+	return cmd.runGitCommandOptional(description, cmd.dryRun, params...)
 }
 
-func (cmd *BaseCommand) runGitCommandAlways(description string, params ...string) {
-	cmd.runGitCommandOptional(description, false, params...)
+func (cmd *BaseCommand) runGitCommandAlways(description string, params ...string) error {
+	return cmd.runGitCommandOptional(description, false, params...)
 }
 
-func (cmd *BaseCommand) runGitCommandOptional(description string, dryRun bool, params ...string) {
+func (cmd *BaseCommand) runGitCommandOptional(description string, dryRun bool, params ...string) error {
 	cmd.Infof("%v: git %v \n", description, strings.Join(params, " "))
 	if !dryRun {
 		gitCmd := exec.Command("git", params...)
@@ -227,46 +482,42 @@ func (cmd *BaseCommand) runGitCommandOptional(description string, dryRun bool, p
 			gitCmd.Stdout = os.Stdout
 		}
 		if err := gitCmd.Run(); err != nil {
-			cmd.Failf("error %v: %v\n", description, err)
+			return gitErrorf("error %v: %w", description, err)
 		}
 	}
+	return nil
 }
 
-func (cmd *BaseCommand) GetCmdOutputOneLine(description string, name string, params ...string) string {
-	output := cmd.runCommandWithOutput(description, name, params...)
-	if len(output) != 1 {
-		cmd.Failf("expected 1 line return from %v: %v %v, but got %v\n", description, cmd, strings.Join(params, " "), len(output))
+// runGitCommands runs the given git commands in order, stopping at the first failure. Each command is given
+// as its description followed by the git parameters
+func (cmd *BaseCommand) runGitCommands(commands ...[]string) error {
+	for _, params := range commands {
+		if err := cmd.RunGitCommand(params[0], params[1:]...); err != nil {
+			return err
+		}
 	}
-	return output[0]
+	return nil
 }
 
-func (cmd *BaseCommand) getGoEnv() map[string]string {
-	lines := cmd.runCommandWithOutput("get go environment", "go", "env", "-json")
-	result := map[string]string{}
-	err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &result)
+func (cmd *BaseCommand) GetCmdOutputOneLine(description string, name string, params ...string) (string, error) {
+	output, err := cmd.runCommandWithOutput(description, name, params...)
 	if err != nil {
-		cmd.Failf("error unmarshalling go env json: %v\n", err)
+		return "", err
 	}
-	return result
-}
-
-func (cmd *BaseCommand) runCommandWithOutput(description string, name string, params ...string) []string {
-	result, _ := cmd.runCommandWithOutputFailOptional(true, description, name, params...)
-	return result
+	if len(output) != 1 {
+		return "", fmt.Errorf("expected 1 line return from %v: %v %v, but got %v", description, name, strings.Join(params, " "), len(output))
+	}
+	return output[0], nil
 }
 
-func (cmd *BaseCommand) runCommandWithOutputFailOptional(fail bool, description string, name string, params ...string) ([]string, error) {
+func (cmd *BaseCommand) runCommandWithOutput(description string, name string, params ...string) ([]string, error) {
 	cmd.Infof("%v: %v %v\n", description, name, strings.Join(params, " "))
 	command := exec.Command(name, params...)
 	command.Stderr = nil
 	output := &bytes.Buffer{}
 	command.Stdout = output
 	if err := command.Run(); err != nil {
-		if fail {
-			cmd.Failf("error %v: %v\n", description, err)
-		} else {
-			return nil, err
-		}
+		return nil, fmt.Errorf("error %v: %w", description, err)
 	}
 
 	stringData := strings.Replace(output.String(), "\r\n", "\n", -1)
@@ -280,7 +531,7 @@ func (cmd *BaseCommand) runCommandWithOutputFailOptional(fail bool, description
 	return result, nil
 }
 
-func (cmd *BaseCommand) runCommand(description string, name string, params ...string) {
+func (cmd *BaseCommand) runCommand(description string, name string, params ...string) error {
 	cmd.Infof("%v: %v %v\n", description, name, strings.Join(params, " "))
 	command := exec.Command(name, params...)
 	command.Stderr = os.Stderr
@@ -292,41 +543,85 @@ func (cmd *BaseCommand) runCommand(description string, name string, params ...st
 
 	if name != "jfrog-cli" || !cmd.dryRun {
 		if err := command.Run(); err != nil {
-			cmd.Failf("error %v: %v\n", description, err)
+			return fmt.Errorf("error %v: %w", description, err)
 		}
 	}
+	return nil
+}
+
+// getVersionList returns the release versions, excluding pre-releases, named by the given tags
+func (cmd *BaseCommand) getVersionList(description string, tags []string) []*version.Version {
+	return filterReleaseVersions(cmd.getAllVersionList(description, tags))
 }
 
-func (cmd *BaseCommand) getVersionList(params ...string) []*version.Version {
-	lines := cmd.runCommandWithOutput("list git tags", "git", params...)
+// getAllVersionList returns the release and pre-release versions named by the given tags. The description is
+// recorded in the version trace, to identify where the tags came from
+func (cmd *BaseCommand) getAllVersionList(description string, lines []string) []*version.Version {
+	prefix := cmd.getTagPrefix()
 
 	var versions []*version.Version
+	query := cmd.VersionTrace.addTagQuery(description)
 
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
+		tag := line
+
+		if prefix != "" {
+			if !strings.HasPrefix(line, prefix) {
+				query.reject(tag, fmt.Sprintf("doesn't have tag prefix %v", prefix))
+				continue
+			}
+			line = strings.TrimPrefix(line, prefix)
+		} else if strings.Contains(line, "/") {
+			query.reject(tag, "tag for a nested module")
+			continue
+		}
 
 		v, err := version.NewVersion(line)
 		if err != nil {
 			if cmd.verbose {
 				cmd.Warnf("failure interpreting tag version on %v: %v\n", line, err)
 			}
+			query.reject(tag, fmt.Sprintf("not a version: %v", err))
 			continue
 		}
-		if v.Prerelease() == "" && v.Metadata() == "" {
+		if v.Metadata() == "" {
 			versions = append(versions, v)
+			query.accept(tag, v)
+		} else {
+			query.reject(tag, "has build metadata")
 		}
 	}
 	sort.Sort(versionList(versions))
 	return versions
 }
 
-func (cmd *BaseCommand) getModule() string {
+func (cmd *BaseCommand) getModule() (string, error) {
+	if cmd.moduleDir != "" {
+		goMod, err := cmd.getGoMod(cmd.getModuleFile("go.mod"))
+		if err != nil {
+			return "", err
+		}
+		return goMod.Module.Mod.Path, nil
+	}
 	return cmd.GetCmdOutputOneLine("get go module", "go", "list", "-m")
 }
 
-func (cmd *BaseCommand) GetCurrentBranch() string {
+func (cmd *BaseCommand) getGoMod(path string) (*modfile.File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("unable to read %v: %w", path, err)
+	}
+	goMod, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return nil, configErrorf("unable to parse %v: %w", path, err)
+	}
+	return goMod, nil
+}
+
+func (cmd *BaseCommand) GetCurrentBranch() (string, error) {
 	if cmd.CurrentBranch == nil {
 		branchName := ""
 
@@ -353,24 +648,40 @@ func (cmd *BaseCommand) GetCurrentBranch() string {
 		}
 
 		if branchName == "" {
-			branchName = cmd.GetCmdOutputOneLine("get git branch (rev-parse)", "git", "rev-parse", "--abbrev-ref", "HEAD")
-			if branchName == "HEAD" {
-				branchName = cmd.GetCmdOutputOneLine("get git branch (name-rev)", "git", "name-rev", "--name-only", "HEAD")
-				cmd.Infof("git name-rev returned %v\n", branchName)
-				if strings.HasPrefix(branchName, "/remotes/origin/") {
-					branchName = strings.TrimPrefix(branchName, "/remotes/origin/")
-				}
+			g, err := cmd.getGit()
+			if err != nil {
+				return "", err
+			}
+			if branchName, err = g.CurrentBranch(); err != nil {
+				return "", gitErrorf("error getting git branch: %w", err)
 			}
 		}
 
 		cmd.CurrentBranch = &branchName
 	}
-	return *cmd.CurrentBranch
+	return *cmd.CurrentBranch, nil
+}
+
+// tagExists returns true if the given tag exists, regardless of which line or branch it's on
+func (cmd *BaseCommand) tagExists(tag string) (bool, error) {
+	tags, err := cmd.listTags("listing git tags", Git.ListTags)
+	if err != nil {
+		return false, err
+	}
+	for _, existing := range tags {
+		if existing == tag {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-func (cmd *BaseCommand) isReleaseBranch() bool {
-	currentBranch := cmd.GetCurrentBranch()
-	return currentBranch == "main" || strings.HasPrefix(currentBranch, "release-v")
+func (cmd *BaseCommand) isReleaseBranch() (bool, error) {
+	currentBranch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return false, err
+	}
+	return currentBranch == "main" || strings.HasPrefix(currentBranch, "release-v"), nil
 }
 
 func (cmd *BaseCommand) getBuildNumber() string {
@@ -386,8 +697,16 @@ func (cmd *BaseCommand) getBuildNumber() string {
 	return *cmd.BuildNumber
 }
 
-func (cmd *BaseCommand) getCommitterEmail() string {
-	return cmd.GetCmdOutputOneLine("get committer e-mail address", "git", "log", "-1", "FETCH_HEAD", "--pretty=%cE")
+func (cmd *BaseCommand) getCommitterEmail() (string, error) {
+	g, err := cmd.getGit()
+	if err != nil {
+		return "", err
+	}
+	email, err := g.CommitterEmail("FETCH_HEAD")
+	if err != nil {
+		return "", gitErrorf("error getting committer e-mail address: %w", err)
+	}
+	return email, nil
 }
 
 func (cmd *BaseCommand) GetUsername() string {
@@ -399,41 +718,53 @@ func (cmd *BaseCommand) GetUsername() string {
 	return currUser.Name
 }
 
-func (cmd *BaseCommand) getBaseVersion() *version.Version {
+func (cmd *BaseCommand) getBaseVersion() (*version.Version, error) {
 	if cmd.baseVersionString == "" {
 		if cmd.baseVersionFile == "" {
 			cmd.baseVersionFile = DefaultVersionFile
 		}
-		contents, err := os.ReadFile(cmd.baseVersionFile)
+		if cmd.moduleDir != "" && !cmd.Cmd.Flags().Changed("base-version-file") {
+			cmd.baseVersionFile = cmd.getModuleFile(DefaultVersionFile)
+		}
+		source, err := cmd.getVersionSource()
 		if err != nil {
+			return nil, err
+		}
+		cmd.VersionTrace.BaseVersionFile = source.Path()
+		cmd.VersionTrace.BaseVersionSource = source.Kind()
+		contents, err := source.ReadVersion()
+		if err != nil && source.Kind() == VersionSourceFile {
 			currdir, _ := os.Getwd()
 			cmd.Errorf("unable to load base version information from '%v'. current dir: '%v'\n", cmd.baseVersionFile, currdir)
+			cmd.VersionTrace.BaseVersionFileError = err.Error()
 
-			contents, err = os.ReadFile("./common/version/VERSION")
-			if err != nil {
-				cmd.Failf("unable to load base version information from '%v'. current dir: '%v'\n", cmd.baseVersionFile, currdir)
-			}
+			source = &plainFileVersionSource{path: "./common/version/VERSION"}
+			cmd.VersionTrace.BaseVersionFile = source.Path()
+			contents, err = source.ReadVersion()
 		}
-		cmd.baseVersionString = string(contents)
-		cmd.baseVersionString = strings.TrimSpace(cmd.baseVersionString)
+		if err != nil {
+			currdir, _ := os.Getwd()
+			return nil, versionErrorf("unable to load base version information from %v. current dir: '%v'. err: %w", describeVersionSource(source), currdir, err)
+		}
+		cmd.VersionTrace.BaseVersionFileContents = contents
+		cmd.baseVersionString = contents
+	} else if cmd.VersionTrace.BaseVersionFile == "" {
+		cmd.VersionTrace.BaseVersionFromFlag = true
 	}
+	cmd.VersionTrace.BaseVersion = cmd.baseVersionString
 	baseVersion, err := version.NewVersion(cmd.baseVersionString)
 	if err != nil {
-		cmd.Failf("Invalid base version %v\n", cmd.baseVersionString)
+		return nil, versionErrorf("invalid base version %v: %w", cmd.baseVersionString, err)
 	}
-	return baseVersion
+	return baseVersion, nil
 }
 
 func (cmd *BaseCommand) logJson(data []byte) {
 	var prettyJSON bytes.Buffer
 	if err := json.Indent(&prettyJSON, data, "", "    "); err == nil {
-		if _, err := fmt.Printf("Result:\n%s\n", prettyJSON.String()); err != nil {
-			panic(err)
-		}
+		_, _ = fmt.Printf("Result:\n%s\n", prettyJSON.String())
 	} else {
-		if _, err := fmt.Printf("Result:\n%s\n", data); err != nil {
-			panic(err)
-		}
+		_, _ = fmt.Printf("Result:\n%s\n", data)
 	}
 }
 
@@ -443,16 +774,16 @@ func (cmd *BaseCommand) close(closer io.Closer, descripion string) {
 	}
 }
 
-func (cmd *BaseCommand) tarGzSimple(archiveFile string, filesToInclude ...string) {
+func (cmd *BaseCommand) tarGzSimple(archiveFile string, filesToInclude ...string) error {
 	nameMap := map[string]string{}
 	for _, file := range filesToInclude {
 		_, fileName := filepath.Split(file)
 		nameMap[file] = fileName
 	}
-	cmd.tarGz(archiveFile, nameMap)
+	return cmd.tarGz(archiveFile, nameMap)
 }
 
-func (cmd *BaseCommand) tarGzGhArtifacts(projectName, archiveFile string, artifacts ...*githubArtifact) {
+func (cmd *BaseCommand) tarGzGhArtifacts(projectName, archiveFile string, artifacts ...*githubArtifact) error {
 	nameMap := map[string]string{}
 	for _, artifact := range artifacts {
 		if len(artifacts) > 1 {
@@ -461,51 +792,89 @@ func (cmd *BaseCommand) tarGzGhArtifacts(projectName, archiveFile string, artifa
 			nameMap[artifact.sourcePath] = artifact.sourceName
 		}
 	}
-	cmd.tarGz(archiveFile, nameMap)
+	return cmd.tarGz(archiveFile, nameMap)
 }
 
-func (cmd *BaseCommand) tarGz(archiveFile string, nameMap map[string]string) {
+func (cmd *BaseCommand) tarGz(archiveFile string, nameMap map[string]string) error {
 	outputFile, err := os.Create(archiveFile)
 	if err != nil {
-		cmd.Failf("unexpected err trying to write to %v. err: %+v\n", archiveFile, err)
+		return fmt.Errorf("unexpected err trying to write to %v. err: %w", archiveFile, err)
 	}
+	defer cmd.close(outputFile, archiveFile)
+
 	gzw := gzip.NewWriter(outputFile)
 	defer cmd.close(gzw, "gzip writer for "+archiveFile)
 
 	tw := tar.NewWriter(gzw)
 	defer cmd.close(tw, "tar writer for "+archiveFile)
 
-	for filePath, name := range nameMap {
-		file, err := os.Open(filePath)
-		if err != nil {
-			cmd.Failf("unexpected err trying to open file %v. err: %+v\n", filePath, err)
-		}
-		fileInfo, err := file.Stat()
-		if err != nil {
-			cmd.close(file, "source file "+filePath)
-			cmd.Failf("unexpected err trying to read state file %v. err: %+v\n", filePath, err)
+	for _, filePath := range sortedArchiveEntries(nameMap) {
+		if err = cmd.addToTar(tw, filePath, nameMap[filePath]); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		header, err := tar.FileInfoHeader(fileInfo, "")
-		if err != nil {
-			cmd.close(file, "source file "+filePath)
-			cmd.Failf("unexpected err trying to create tar header for %v. err: %+v\n", filePath, err)
-		}
-		header.Name = name
-		if err = tw.WriteHeader(header); err != nil {
-			cmd.close(file, "source file "+filePath)
-			cmd.Failf("unexpected err trying to write tar header for %v. err: %+v\n", filePath, err)
-		}
+// sortedArchiveEntries returns the source paths of the given archive entries, ordered by name in the archive,
+// so that archives of the same files are identical
+func sortedArchiveEntries(nameMap map[string]string) []string {
+	var result []string
+	for filePath := range nameMap {
+		result = append(result, filePath)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return nameMap[result[i]] < nameMap[result[j]]
+	})
+	return result
+}
 
-		_, err = io.Copy(tw, file)
-		cmd.close(file, "source file "+filePath)
-		if err != nil {
-			cmd.Failf("unexpected err trying to write file %v to tar file. err: %+v\n", filePath, err)
+func (cmd *BaseCommand) addToTar(tw *tar.Writer, filePath, name string) error {
+	buildTime, reproducible, err := cmd.getBuildTime()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("unexpected err trying to open file %v. err: %w", filePath, err)
+	}
+	defer cmd.close(file, "source file "+filePath)
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("unexpected err trying to read state file %v. err: %w", filePath, err)
+	}
+
+	header, err := tar.FileInfoHeader(fileInfo, "")
+	if err != nil {
+		return fmt.Errorf("unexpected err trying to create tar header for %v. err: %w", filePath, err)
+	}
+	header.Name = name
+	if reproducible {
+		header.ModTime = buildTime
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		// permissions depend on the umask of the checkout, so only the executable bit is kept
+		if header.Mode&0111 != 0 {
+			header.Mode = 0755
+		} else {
+			header.Mode = 0644
 		}
 	}
+	if err = tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("unexpected err trying to write tar header for %v. err: %w", filePath, err)
+	}
+
+	if _, err = io.Copy(tw, file); err != nil {
+		return fmt.Errorf("unexpected err trying to write file %v to tar file. err: %w", filePath, err)
+	}
+	return nil
 }
 
-func (cmd *BaseCommand) zipGhArtifacts(projectName string, archiveFile string, artifacts ...*githubArtifact) {
+func (cmd *BaseCommand) zipGhArtifacts(projectName string, archiveFile string, artifacts ...*githubArtifact) error {
 	nameMap := map[string]string{}
 	for _, artifact := range artifacts {
 		if len(artifacts) > 1 {
@@ -515,34 +884,50 @@ func (cmd *BaseCommand) zipGhArtifacts(projectName string, archiveFile string, a
 		}
 	}
 
-	cmd.zip(archiveFile, nameMap)
+	return cmd.zip(archiveFile, nameMap)
 }
 
-func (cmd *BaseCommand) zip(archiveFile string, nameMap map[string]string) {
+func (cmd *BaseCommand) zip(archiveFile string, nameMap map[string]string) error {
 	outputFile, err := os.Create(archiveFile)
 	if err != nil {
-		cmd.Failf("unexpected err trying to write to %v. err: %+v\n", archiveFile, err)
+		return fmt.Errorf("unexpected err trying to write to %v. err: %w", archiveFile, err)
 	}
+	defer cmd.close(outputFile, archiveFile)
 
 	zw := zip.NewWriter(outputFile)
 	defer cmd.close(zw, "zip writer for "+archiveFile)
 
-	for filePath, name := range nameMap {
-		file, err := os.Open(filePath)
-		if err != nil {
-			cmd.Failf("unexpected err trying to open file %v. err: %+v\n", filePath, err)
+	for _, filePath := range sortedArchiveEntries(nameMap) {
+		if err = cmd.addToZip(zw, filePath, nameMap[filePath]); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		writer, err := zw.Create(name)
-		if err != nil {
-			cmd.close(file, "source file "+filePath)
-			cmd.Failf("unexpected err trying to write zip header for %v. err: %+v\n", filePath, err)
-		}
+func (cmd *BaseCommand) addToZip(zw *zip.Writer, filePath, name string) error {
+	buildTime, reproducible, err := cmd.getBuildTime()
+	if err != nil {
+		return err
+	}
 
-		_, err = io.Copy(writer, file)
-		cmd.close(file, "source file "+filePath)
-		if err != nil {
-			cmd.Failf("unexpected err trying to write file %v to tar file. err: %+v\n", filePath, err)
-		}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("unexpected err trying to open file %v. err: %w", filePath, err)
+	}
+	defer cmd.close(file, "source file "+filePath)
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if reproducible {
+		header.Modified = buildTime
+	}
+	writer, err := zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("unexpected err trying to write zip header for %v. err: %w", filePath, err)
+	}
+
+	if _, err = io.Copy(writer, file); err != nil {
+		return fmt.Errorf("unexpected err trying to write file %v to zip file. err: %w", filePath, err)
 	}
+	return nil
 }
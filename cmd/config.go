@@ -0,0 +1,447 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	ConfigFileName     = ".ziti-ci.yaml"
+	ConfigEnvVarPrefix = "ZITI_CI_"
+
+	configSourceDefault = "default"
+	configSourceFlag    = "flag"
+)
+
+var configFileNames = []string{ConfigFileName, ".ziti-ci.yml"}
+
+// RepoConfig holds the project specific settings. They're read from .ziti-ci.yaml at the repository root and
+// may be overridden with ZITI_CI_ environment variables, ex: ZITI_CI_RELEASE_NOTES_DEPENDENCY_FILTER. Settings
+// with a flag tag are also overridden by that flag, if the command has it
+type RepoConfig struct {
+	Github       GithubConfig       `yaml:"github"`
+	ReleaseNotes ReleaseNotesConfig `yaml:"release-notes"`
+	Git          GitConfig          `yaml:"git"`
+	Jenkins      JenkinsConfig      `yaml:"jenkins"`
+	Travis       TravisConfig       `yaml:"travis"`
+	Build        BuildConfig        `yaml:"build"`
+	Changelog    ChangelogConfig    `yaml:"changelog"`
+	// Vulnerabilities configures the vulnerability database used to annotate release notes and by audit-deps
+	Vulnerabilities VulnerabilitiesConfig `yaml:"vulnerabilities"`
+
+	// Flags provides values for command line flags which weren't given, ex: bump-strategy: conventional
+	Flags map[string]string `yaml:"flags"`
+}
+
+type GithubConfig struct {
+	Url                string `yaml:"url"`
+	ApiUrl             string `yaml:"api-url"`
+	Org                string `yaml:"org"`
+	DependencyWorkflow string `yaml:"dependency-workflow"`
+}
+
+type ReleaseNotesConfig struct {
+	// Project is the repository whose changes build-release-notes lists
+	Project string `yaml:"project"`
+	// SdkProject is the repository whose changes build-sdk-release-notes lists
+	SdkProject string `yaml:"sdk-project"`
+	// DependencyFilter selects the dependencies whose changes are included, by module path substring
+	DependencyFilter string   `yaml:"dependency-filter"`
+	IgnoredAuthors   []string `yaml:"ignored-authors"`
+	// CacheDir holds bare mirrors of the repositories of dependencies, keyed by repository path
+	CacheDir string `yaml:"cache-dir"`
+	// RemoteBase, if set, is the url under which dependency repositories are cloned, ex: file:///srv/mirrors
+	// clones github.com/openziti/edge from file:///srv/mirrors/github.com/openziti/edge
+	RemoteBase string `yaml:"remote-base"`
+	// IssueCacheDir holds the closed GitHub issues and pull requests which have been looked up. Empty disables it
+	IssueCacheDir string `yaml:"issue-cache-dir"`
+	// Repositories maps repository or module paths to the url they're cloned from, overriding RemoteBase
+	Repositories map[string]string `yaml:"repositories"`
+	// Trackers are issue trackers, other than GitHub, whose keys are linked from commit messages
+	Trackers []TrackerConfig `yaml:"trackers"`
+	// ModuleCompareUrl links the changes to a module in the go.mod diff, with {module}, {repo}, {old}, {new},
+	// {oldRev} and {newRev} replaced, ex: https://{repo}/compare/{oldRev}...{newRev}
+	ModuleCompareUrl string `yaml:"module-compare-url"`
+}
+
+// TrackerConfig links the keys of an issue tracker, ex: Jira, found in commit messages
+type TrackerConfig struct {
+	Name string `yaml:"name"`
+	// Pattern matches the tracker's keys, ex: \bZITI-\d+\b
+	Pattern string `yaml:"pattern"`
+	// Url is the link for a key, with {key} replaced by the key, ex: https://jira.example.com/browse/{key}
+	Url string `yaml:"url"`
+}
+
+type GitConfig struct {
+	Username string `yaml:"username" flag:"git-username"`
+	Email    string `yaml:"email" flag:"git-email"`
+	// AllowedOwners are the GitHub repository owners for which configure-git sets up keys and signing
+	AllowedOwners []string `yaml:"allowed-owners"`
+}
+
+type JenkinsConfig struct {
+	SmokeTestUrl string `yaml:"smoke-test-url"`
+}
+
+type TravisConfig struct {
+	ApiUrl string `yaml:"api-url"`
+}
+
+type BuildConfig struct {
+	// VersionPackage is the package, relative to the module, whose variables go-build-flags sets
+	VersionPackage   string `yaml:"version-package"`
+	SdkBuildInfoFile string `yaml:"sdk-build-info-file"`
+}
+
+type ChangelogConfig struct {
+	// HeadingFormat is how release headings are recognized in the changelog: ziti, keepachangelog or regex
+	HeadingFormat string `yaml:"heading-format" flag:"heading-format"`
+	// HeadingRegex matches release headings when HeadingFormat is regex. Its version group captures the version
+	// and its optional date group the release date
+	HeadingRegex string `yaml:"heading-regex" flag:"heading-regex"`
+}
+
+type VulnerabilitiesConfig struct {
+	// Db is an OSV vulnerability database url or directory, ex: https://vuln.go.dev. Empty leaves release notes
+	// unannotated
+	Db string `yaml:"db" flag:"vuln-db"`
+}
+
+func defaultRepoConfig() *RepoConfig {
+	return &RepoConfig{
+		Github: GithubConfig{
+			Url:                "https://github.com",
+			ApiUrl:             "https://api.github.com",
+			Org:                "openziti",
+			DependencyWorkflow: "update-dependency.yml",
+		},
+		ReleaseNotes: ReleaseNotesConfig{
+			Project:          "ziti",
+			SdkProject:       "sdk-golang",
+			DependencyFilter: "openziti",
+			CacheDir:         defaultRepoCacheDir(),
+			IssueCacheDir:    defaultIssueCacheDir(),
+			IgnoredAuthors:   []string{DefaultGitUsername, "dependabot[bot]"},
+		},
+		Git: GitConfig{
+			Username:      DefaultGitUsername,
+			Email:         DefaultGitEmail,
+			AllowedOwners: []string{"openziti", "netfoundry", "qrkourier"},
+		},
+		Jenkins: JenkinsConfig{
+			SmokeTestUrl: "https://jenkinstest.tools.netfoundry.io/job/ziti-smoke-test/buildWithParameters",
+		},
+		Travis: TravisConfig{
+			ApiUrl: "https://api.travis-ci.org",
+		},
+		Build: BuildConfig{
+			VersionPackage:   "common/version",
+			SdkBuildInfoFile: "ziti/sdkinfo/build_info.go",
+		},
+		Changelog: ChangelogConfig{
+			HeadingFormat: ChangelogFormatZiti,
+		},
+	}
+}
+
+// configValue records the resolved value of a setting and where it came from
+type configValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// isIgnoredAuthor returns true if commits by the given author should be left out of release notes
+func (c *RepoConfig) isIgnoredAuthor(name string) bool {
+	return slices.Contains(c.ReleaseNotes.IgnoredAuthors, name)
+}
+
+// isIgnoredContributor returns true if the author, by name or email, is left out of the contributors to a release
+func (c *RepoConfig) isIgnoredContributor(name, email string) bool {
+	for _, ignored := range c.ReleaseNotes.IgnoredAuthors {
+		if ignored == name || strings.EqualFold(ignored, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareUrl returns the url of the page comparing two revisions of the given project
+func (c *RepoConfig) compareUrl(project, oldRev, newRev string) string {
+	return fmt.Sprintf("%v/%v/%v/compare/%v...%v", strings.TrimSuffix(c.Github.Url, "/"), c.Github.Org, project, oldRev, newRev)
+}
+
+// commitUrl returns the url of the page showing a commit of the given project
+func (c *RepoConfig) commitUrl(project, hash string) string {
+	return fmt.Sprintf("%v/%v/%v/commit/%v", strings.TrimSuffix(c.Github.Url, "/"), c.Github.Org, project, hash)
+}
+
+// findRepoRoot returns the closest directory at or above the working directory which contains a .git entry,
+// or the working directory if there is none
+func findRepoRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := wd; ; dir = filepath.Dir(dir) {
+		if _, err = os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		if filepath.Dir(dir) == dir {
+			return wd, nil
+		}
+	}
+}
+
+// findConfigFile returns the path of the configuration file given with --config, or the one found at the
+// repository root. An empty path is returned if there is none
+func (cmd *BaseCommand) findConfigFile() (string, error) {
+	if cmd.configFile != "" {
+		return cmd.configFile, nil
+	}
+	root, err := findRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range configFileNames {
+		path := filepath.Join(root, name)
+		if _, err = os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// loadConfig resolves the repository configuration from defaults, the configuration file, environment
+// variables and flags, in increasing order of precedence. Flags listed in the configuration file's flags
+// section, or set with ZITI_CI_<FLAG> environment variables, are applied to the command if they weren't given
+func (cmd *BaseCommand) loadConfig() error {
+	cmd.Config = defaultRepoConfig()
+	cmd.ConfigValues = nil
+
+	path, err := cmd.findConfigFile()
+	if err != nil {
+		return configErrorf("unable to locate configuration file: %w", err)
+	}
+
+	fileKeys := map[string]bool{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return configErrorf("unable to read configuration file %v: %w", path, err)
+		}
+		if err = decodeConfig(data, cmd.Config, fileKeys); err != nil {
+			return configErrorf("invalid configuration file %v: %w", path, err)
+		}
+		cmd.ConfigFile = path
+	}
+
+	// flags are resolved first, so that settings tied to a flag see values from the flags section
+	flagSources := map[string]string{}
+	if cmd.Cmd != nil {
+		if flagSources, err = cmd.applyConfigFlags(cmd.Cmd.Flags(), path); err != nil {
+			return err
+		}
+	}
+
+	walkConfig(reflect.ValueOf(cmd.Config).Elem(), "", func(key string, field reflect.StructField, value reflect.Value) {
+		source := configSourceDefault
+		if fileKeys[key] {
+			source = path
+		}
+		envVar := configEnvVar(key)
+		if val, found := os.LookupEnv(envVar); found {
+			setConfigField(value, val)
+			source = "env " + envVar
+		}
+		if flagName := field.Tag.Get("flag"); flagName != "" && cmd.Cmd != nil {
+			if flag := cmd.Cmd.Flags().Lookup(flagName); flag != nil && flag.Changed {
+				setConfigField(value, flag.Value.String())
+				source = flagSources[flagName]
+			}
+		}
+		cmd.ConfigValues = append(cmd.ConfigValues, &configValue{Key: key, Value: formatConfigField(value), Source: source})
+	})
+
+	for repoPath, url := range cmd.Config.ReleaseNotes.Repositories {
+		key := "release-notes.repositories." + repoPath
+		cmd.ConfigValues = append(cmd.ConfigValues, &configValue{Key: key, Value: url, Source: path})
+	}
+
+	for _, tracker := range cmd.Config.ReleaseNotes.Trackers {
+		key := "release-notes.trackers." + tracker.Name
+		value := fmt.Sprintf("%v -> %v", tracker.Pattern, tracker.Url)
+		cmd.ConfigValues = append(cmd.ConfigValues, &configValue{Key: key, Value: value, Source: path})
+	}
+
+	sort.Slice(cmd.ConfigValues, func(i, j int) bool {
+		return cmd.ConfigValues[i].Key < cmd.ConfigValues[j].Key
+	})
+	return nil
+}
+
+// applyConfigFlags sets flags which weren't given on the command line from the environment or the configuration
+// file, and records the source of every flag value. The sources are returned by flag name
+func (cmd *BaseCommand) applyConfigFlags(flags *pflag.FlagSet, path string) (map[string]string, error) {
+	// the flags section is shared by all commands, so only flags which no command has are rejected
+	for name := range cmd.Config.Flags {
+		if !isKnownFlag(cmd.RootCobraCmd, name) {
+			return nil, configErrorf("unknown flag %v in the flags section of %v", name, path)
+		}
+	}
+
+	sources := map[string]string{}
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Name == "help" || flag.Name == "config" {
+			return
+		}
+		source := configSourceDefault
+		if flag.Changed {
+			source = configSourceFlag + " --" + flag.Name
+		} else if val, found := os.LookupEnv(configEnvVar(flag.Name)); found {
+			if err = flags.Set(flag.Name, val); err != nil {
+				err = configErrorf("invalid value %v for %v: %w", val, configEnvVar(flag.Name), err)
+				return
+			}
+			source = "env " + configEnvVar(flag.Name)
+		} else if val, found := cmd.Config.Flags[flag.Name]; found {
+			if err = flags.Set(flag.Name, val); err != nil {
+				err = configErrorf("invalid value %v for %v in %v: %w", val, flag.Name, path, err)
+				return
+			}
+			source = path
+		}
+		sources[flag.Name] = source
+		cmd.ConfigValues = append(cmd.ConfigValues, &configValue{Key: "flags." + flag.Name, Value: flag.Value.String(), Source: source})
+	})
+	return sources, err
+}
+
+func isKnownFlag(cobraCmd *cobra.Command, name string) bool {
+	if cobraCmd.Flags().Lookup(name) != nil || cobraCmd.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, child := range cobraCmd.Commands() {
+		if isKnownFlag(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeConfig overlays the settings in the given yaml onto the configuration, recording which keys were present
+func decodeConfig(data []byte, config *RepoConfig, keys map[string]bool) error {
+	node := &yaml.Node{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(node); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+
+	// decode again with known fields enforced, so typos in keys are reported rather than ignored
+	decoder = yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return err
+	}
+
+	collectConfigKeys(node, "", keys)
+	return nil
+}
+
+func collectConfigKeys(node *yaml.Node, prefix string, keys map[string]bool) {
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			collectConfigKeys(child, prefix, keys)
+		}
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := prefix + node.Content[i].Value
+		keys[key] = true
+		collectConfigKeys(node.Content[i+1], key+".", keys)
+	}
+}
+
+// walkConfig calls f for every string and string list setting in the given configuration struct. Keys are
+// the dotted yaml path of the setting, ex: release-notes.cache-dir
+func walkConfig(v reflect.Value, prefix string, f func(key string, field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value := v.Field(i)
+		switch value.Kind() {
+		case reflect.Struct:
+			walkConfig(value, prefix+name+".", f)
+		case reflect.String:
+			f(prefix+name, field, value)
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.String {
+				f(prefix+name, field, value)
+			}
+		}
+	}
+}
+
+func configEnvVar(key string) string {
+	return ConfigEnvVarPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func setConfigField(value reflect.Value, s string) {
+	if value.Kind() == reflect.String {
+		value.SetString(s)
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	value.Set(reflect.ValueOf(list))
+}
+
+func formatConfigField(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	return strings.Join(value.Interface().([]string), ",")
+}
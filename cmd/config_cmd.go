@@ -0,0 +1,93 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"text/tabwriter"
+)
+
+type configShowCmd struct {
+	BaseCommand
+	format string
+}
+
+// Init only resolves the configuration, so that it can be shown in repositories without a version file
+func (cmd *configShowCmd) Init(args []string) error {
+	cmd.Args = args
+	return cmd.loadConfig()
+}
+
+func (cmd *configShowCmd) Execute() error {
+	if cmd.format != "text" && cmd.format != "json" {
+		return configErrorf("unsupported format: '%v'. Valid values: [text,json]", cmd.format)
+	}
+
+	out := cmd.Cmd.OutOrStdout()
+	if cmd.format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(cmd.ConfigValues); err != nil {
+			return fmt.Errorf("unable to write configuration as json: %w", err)
+		}
+		return nil
+	}
+
+	if cmd.ConfigFile != "" {
+		cmd.Printf("configuration file: %v\n\n", cmd.ConfigFile)
+	} else {
+		cmd.Printf("configuration file: none\n\n")
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, value := range cmd.ConfigValues {
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\n", value.Key, value.Value, value.Source)
+	}
+	return w.Flush()
+}
+
+func newConfigCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the repository configuration",
+	}
+
+	cobraCmd.AddCommand(newConfigShowCmd(root))
+	return cobraCmd
+}
+
+func newConfigShowCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print every resolved setting and where its value came from",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	result := &configShowCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&result.format, "format", "o", "text", "output format. Valid values: [text,json]")
+
+	return FinalizeErroringCmd(result)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func configSource(cmd *BaseCommand, key string) string {
+	for _, value := range cmd.ConfigValues {
+		if value.Key == key {
+			return value.Source
+		}
+	}
+	return ""
+}
+
+func TestLoadConfig(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0")
+
+	out := &bytes.Buffer{}
+	opts := &Options{Out: out, Err: out}
+
+	cmd, err := opts.newBaseCommand("test")
+	req.NoError(err)
+	req.Empty(cmd.ConfigFile)
+	req.Equal("openziti", cmd.Config.ReleaseNotes.DependencyFilter)
+	req.Equal(configSourceDefault, configSource(cmd, "release-notes.dependency-filter"))
+	req.Equal("https://github.com/openziti/ziti/compare/v0.3.0...v0.3.1", cmd.Config.compareUrl("ziti", "v0.3.0", "v0.3.1"))
+
+	config := `
+github:
+  url: https://git.example.com/
+  org: acme
+release-notes:
+  dependency-filter: example.com/acme
+  ignored-authors: [release-bot]
+flags:
+  bump-strategy: conventional
+`
+	req.NoError(os.WriteFile(ConfigFileName, []byte(config), 0644))
+	t.Setenv("ZITI_CI_GITHUB_ORG", "widgets")
+	t.Setenv("ZITI_CI_RELEASE_NOTES_IGNORED_AUTHORS", "bot-a, bot-b")
+
+	cmd, err = opts.newBaseCommand("test")
+	req.NoError(err)
+	req.NotEmpty(cmd.ConfigFile)
+	req.Equal("example.com/acme", cmd.Config.ReleaseNotes.DependencyFilter)
+	req.Equal(cmd.ConfigFile, configSource(cmd, "release-notes.dependency-filter"))
+	req.Equal("https://git.example.com/widgets/ziti/compare/v0.3.0...v0.3.1", cmd.Config.compareUrl("ziti", "v0.3.0", "v0.3.1"))
+	req.Equal("env ZITI_CI_GITHUB_ORG", configSource(cmd, "github.org"))
+	req.True(cmd.Config.isIgnoredAuthor("bot-b"))
+	req.False(cmd.Config.isIgnoredAuthor("release-bot"))
+	req.Equal(BumpStrategyConventional, cmd.bumpStrategy)
+	req.Equal(cmd.ConfigFile, configSource(cmd, "flags.bump-strategy"))
+
+	// flags given explicitly win over the configuration file
+	opts.BumpStrategy = BumpStrategyPatch
+	cmd, err = opts.newBaseCommand("test")
+	req.NoError(err)
+	req.Equal(BumpStrategyPatch, cmd.bumpStrategy)
+	req.Equal("flag --bump-strategy", configSource(cmd, "flags.bump-strategy"))
+
+	// an empty configuration file keeps the defaults
+	req.NoError(os.WriteFile(ConfigFileName, nil, 0644))
+	cmd, err = opts.newBaseCommand("test")
+	req.NoError(err)
+	req.Equal("openziti", cmd.Config.ReleaseNotes.DependencyFilter)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	out := &bytes.Buffer{}
+	opts := &Options{Out: out, Err: out}
+
+	req.NoError(os.WriteFile(ConfigFileName, []byte("github:\n  orgs: acme\n"), 0644))
+	_, err := opts.newBaseCommand("test")
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+
+	req.NoError(os.WriteFile(ConfigFileName, []byte("flags:\n  no-such-flag: true\n"), 0644))
+	_, err = opts.newBaseCommand("test")
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+
+	// flags of other commands may be given, as the flags section is shared by all commands
+	req.NoError(os.WriteFile(ConfigFileName, []byte("flags:\n  git-username: release-bot\n"), 0644))
+	_, err = opts.newBaseCommand("test")
+	req.NoError(err)
+}
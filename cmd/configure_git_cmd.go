@@ -26,40 +26,38 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 type configureGitCmd struct {
 	BaseCommand
 
-	gitUsername string
-	gitEmail    string
-
 	sshKeyEnv  string
 	sshKeyFile string
 }
 
-func (cmd *configureGitCmd) Execute() {
-	if val, found := os.LookupEnv("GITHUB_REPOSITORY_OWNER"); found && (val != "openziti" && val != "netfoundry" && val != "qrkourier") {
-		cmd.Warnf("Running in non-openziti context. Not attempting to configure git.\n")
-		return
+func (cmd *configureGitCmd) Execute() error {
+	if val, found := os.LookupEnv("GITHUB_REPOSITORY_OWNER"); found && !slices.Contains(cmd.Config.Git.AllowedOwners, val) {
+		cmd.Warnf("Running in context of repository owner %v, which isn't one of %v. Not attempting to configure git.\n", val, cmd.Config.Git.AllowedOwners)
+		return nil
 	}
 	cmd.Infof("configuring git\n")
 	if val, found := os.LookupEnv(cmd.sshKeyEnv); found && val != "" {
 		sshKey, err := base64.StdEncoding.DecodeString(val)
 		if err != nil {
-			cmd.Failf("unable to decode ssh key. err: %v\n", err)
+			return configErrorf("unable to decode ssh key. err: %w", err)
 		}
 		if err = ioutil.WriteFile(cmd.sshKeyFile, sshKey, 0600); err != nil {
-			cmd.Failf("unable to write ssh key file %v. err: %v\n", cmd.sshKeyFile, err)
+			return fmt.Errorf("unable to write ssh key file %v. err: %w", cmd.sshKeyFile, err)
 		}
 	} else {
-		cmd.Failf("unable to read ssh key from env var %v. Found? %v\n", cmd.sshKeyEnv, found)
+		return configErrorf("unable to read ssh key from env var %v. Found? %v", cmd.sshKeyEnv, found)
 	}
 
 	kfAbs, err := filepath.Abs(cmd.sshKeyFile)
 	if err != nil {
-		cmd.Failf("unable to read path for sshKeyFile? %v\n", cmd.sshKeyFile)
+		return fmt.Errorf("unable to read path for sshKeyFile? %v", cmd.sshKeyFile)
 	}
 
 	keyDir := path.Dir(kfAbs)
@@ -84,11 +82,11 @@ func (cmd *configureGitCmd) Execute() {
 		f, err := os.OpenFile(keyDir+string(os.PathSeparator)+".gitignore",
 			os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			cmd.Failf("could not write to .gitignore (%v)\n", err)
+			return fmt.Errorf("could not write to .gitignore (%w)", err)
 		}
 		defer f.Close()
 		if _, err := f.WriteString("\n" + cmd.sshKeyFile + "\n"); err != nil {
-			cmd.Failf("error writing to .gitignore (%v)\n", err)
+			return fmt.Errorf("error writing to .gitignore (%w)", err)
 		}
 	} else {
 		cmd.Infof(".gitignore file already contains entry for %v\n", cmd.sshKeyFile)
@@ -96,28 +94,41 @@ func (cmd *configureGitCmd) Execute() {
 
 	if val, found := os.LookupEnv(DefaultGpgKeyEnvVar); found && val != "" {
 		if val, found := os.LookupEnv(DefaultGpgKeyIdEnvVar); found && val != "" {
-			cmd.RunGitCommand("set gpg key id", "config", "user.signingkey", val)
+			if err = cmd.RunGitCommand("set gpg key id", "config", "user.signingkey", val); err != nil {
+				return err
+			}
 		} else {
-			cmd.Failf("unable to read gpg key from env var %v. Found? %v\n", DefaultGpgKeyIdEnvVar, found)
+			return configErrorf("unable to read gpg key from env var %v. Found? %v", DefaultGpgKeyIdEnvVar, found)
 		}
 
 		if err = os.WriteFile("gpg.key", []byte(val), 0600); err != nil {
-			cmd.Failf("unable to write gpg key file [%v]. err: (%v)\n", cmd.sshKeyFile, err)
+			return fmt.Errorf("unable to write gpg key file [%v]. err: (%w)", cmd.sshKeyFile, err)
+		}
+		if err = cmd.runCommand("import gpg key", "gpg", "--import", "gpg.key"); err != nil {
+			return err
 		}
-		cmd.runCommand("import gpg key", "gpg", "--import", "gpg.key")
 		if err = os.Remove("gpg.key"); err != nil {
-			cmd.Failf("unable to delete gpg.key (%v)\n", err)
+			return fmt.Errorf("unable to delete gpg.key (%w)", err)
+		}
+		err = cmd.runGitCommands(
+			[]string{"require gpg signed commit", "config", "commit.gpgsign", "true"},
+			[]string{"require gpg signed tags", "config", "tag.gpgSign", "true"},
+		)
+		if err != nil {
+			return err
 		}
-		cmd.RunGitCommand("require gpg signed commit", "config", "commit.gpgsign", "true")
-		cmd.RunGitCommand("require gpg signed tags", "config", "tag.gpgSign", "true")
-
 	} else {
 		cmd.Warnf("unable to read gpg key from env var %v. Found? %v\n", DefaultGpgKeyEnvVar, found)
 	}
 
-	cmd.RunGitCommand("set git username", "config", "user.name", cmd.gitUsername)
-	cmd.RunGitCommand("set git password", "config", "user.email", cmd.gitEmail)
-	cmd.RunGitCommand("set ssh config", "config", "core.sshCommand", fmt.Sprintf("ssh -i %v", cmd.sshKeyFile))
+	err = cmd.runGitCommands(
+		[]string{"set git username", "config", "user.name", cmd.Config.Git.Username},
+		[]string{"set git password", "config", "user.email", cmd.Config.Git.Email},
+		[]string{"set ssh config", "config", "core.sshCommand", fmt.Sprintf("ssh -i %v", cmd.sshKeyFile)},
+	)
+	if err != nil {
+		return err
+	}
 
 	repo := ""
 	if travisRepoSlug, ok := os.LookupEnv("TRAVIS_REPO_SLUG"); ok {
@@ -131,8 +142,9 @@ func (cmd *configureGitCmd) Execute() {
 	// Ensure we're in ssh mode
 	if repo != "" {
 		url := fmt.Sprintf("git@github.com:%v.git", repo)
-		cmd.RunGitCommand("set remote to ssh", "remote", "set-url", "origin", url)
+		return cmd.RunGitCommand("set remote to ssh", "remote", "set-url", "origin", url)
 	}
+	return nil
 }
 
 func newConfigureGitCmd(root *RootCommand) *cobra.Command {
@@ -149,10 +161,10 @@ func newConfigureGitCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	cobraCmd.PersistentFlags().StringVar(&result.gitUsername, "git-username", DefaultGitUsername, "override the default git username")
-	cobraCmd.PersistentFlags().StringVar(&result.gitEmail, "git-email", DefaultGitEmail, "override the default git email")
+	cobraCmd.PersistentFlags().String("git-username", DefaultGitUsername, "override the git username. Overrides git.username from the repository configuration")
+	cobraCmd.PersistentFlags().String("git-email", DefaultGitEmail, "override the git email. Overrides git.email from the repository configuration")
 	cobraCmd.PersistentFlags().StringVar(&result.sshKeyEnv, "ssh-key-env-var", DefaultSshKeyEnvVar, "set ssh key environment variable name")
 	cobraCmd.PersistentFlags().StringVar(&result.sshKeyFile, "ssh-key-file", DefaultSshKeyFile, "set ssh key file name")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -0,0 +1,194 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// contributorsTemplateDefinitions render the contributors of a release, and are available to all release notes
+// templates
+const contributorsTemplateDefinitions = `
+{{- define "contributors"}}
+## Contributors
+
+{{range .}}* {{.Name}}{{if .FirstTime}} (first contribution){{end}}
+{{end}}
+{{- end}}`
+
+// ReleaseNotesContributor is an author, or co-author, of commits in the release
+type ReleaseNotesContributor struct {
+	Name    string `json:"name" yaml:"name"`
+	Email   string `json:"email" yaml:"email"`
+	Commits int    `json:"commits" yaml:"commits"`
+	// FirstTime is set if the contributor's first commit to the repository is in this release
+	FirstTime bool `json:"firstTime,omitempty" yaml:"firstTime,omitempty"`
+}
+
+// mailmapLineRegex matches the entries of a .mailmap file, see git-check-mailmap(1)
+var mailmapLineRegex = regexp.MustCompile(`^\s*([^<]*?)\s*<([^>]*)>\s*(?:([^<]*?)\s*<([^>]*)>)?\s*$`)
+
+// mailmap maps the names and emails commits were made with to the contributors' canonical ones
+type mailmap struct {
+	entries []mailmapEntry
+}
+
+type mailmapEntry struct {
+	properName, properEmail string
+	// commitName is optional, commitEmail is matched case-insensitively
+	commitName, commitEmail string
+}
+
+func parseMailmap(data string) *mailmap {
+	result := &mailmap{}
+	for _, line := range strings.Split(data, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		match := mailmapLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if match[4] == "" {
+			// Proper Name <commit@email>
+			result.entries = append(result.entries, mailmapEntry{properName: match[1], commitEmail: match[2]})
+		} else {
+			result.entries = append(result.entries, mailmapEntry{properName: match[1], properEmail: match[2], commitName: match[3], commitEmail: match[4]})
+		}
+	}
+	return result
+}
+
+// readMailmap reads the .mailmap at the root of the repository, if there is one
+func readMailmap() (*mailmap, error) {
+	root, err := findRepoRoot()
+	if err != nil {
+		return nil, gitErrorf("unable to find repository root: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, ".mailmap"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &mailmap{}, nil
+	}
+	if err != nil {
+		return nil, configErrorf("unable to read .mailmap: %w", err)
+	}
+	return parseMailmap(string(data)), nil
+}
+
+// lookup returns the canonical name and email of a commit author. Entries naming the commit's author take
+// precedence over those matching the email alone
+func (m *mailmap) lookup(name, email string) (string, string) {
+	var match *mailmapEntry
+	for i := range m.entries {
+		entry := &m.entries[i]
+		if !strings.EqualFold(entry.commitEmail, email) {
+			continue
+		}
+		if entry.commitName == "" {
+			if match == nil || match.commitName == "" {
+				match = entry
+			}
+		} else if entry.commitName == name {
+			match = entry
+		}
+	}
+	if match == nil {
+		return name, email
+	}
+	return valueOr(match.properName, name), valueOr(match.properEmail, email)
+}
+
+// collectContributors returns the authors and co-authors of the commits of the module being released, by
+// canonical email, leaving out ignored authors. Contributors without commits to the module before the release are marked
+func (cmd *baseBuildReleaseNotesCmd) collectContributors(notes *ReleaseNotes) ([]*ReleaseNotesContributor, error) {
+	var component *ReleaseNotesComponent
+	for _, c := range notes.Components {
+		if c.Current {
+			component = c
+		}
+	}
+	if component == nil || len(component.Commits) == 0 {
+		return nil, nil
+	}
+
+	m, err := readMailmap()
+	if err != nil {
+		return nil, err
+	}
+
+	contributors := map[string]*ReleaseNotesContributor{}
+	var result []*ReleaseNotesContributor
+	for _, commit := range component.Commits {
+		authors := append([]*ReleaseNotesAuthor{{Name: commit.Author, Email: commit.AuthorEmail}}, commit.CoAuthors...)
+		seen := map[string]bool{}
+		for _, author := range authors {
+			name, email := m.lookup(author.Name, author.Email)
+			key := strings.ToLower(email)
+			if seen[key] || cmd.Config.isIgnoredContributor(author.Name, author.Email) || cmd.Config.isIgnoredContributor(name, email) {
+				continue
+			}
+			seen[key] = true
+			contributor, found := contributors[key]
+			if !found {
+				contributor = &ReleaseNotesContributor{Name: name, Email: email, FirstTime: true}
+				contributors[key] = contributor
+				result = append(result, contributor)
+			}
+			contributor.Commits++
+		}
+	}
+
+	// anyone with a commit before the start of the release isn't new
+	r, err := cmd.openCurrentRepo()
+	if err != nil {
+		return nil, err
+	}
+	start, err := resolveChangeCommit(r, component.changes.oldRev)
+	if err != nil {
+		return nil, gitErrorf("unable to resolve %v: %w", component.changes.oldRev, err)
+	}
+	dir := cmd.getModuleRepoDir()
+	err = object.NewCommitPreorderIter(start, nil, nil).ForEach(func(c *object.Commit) error {
+		if touches, err := commitTouchesDir(c, dir); err != nil || !touches {
+			return err
+		}
+		authors := append([]*ReleaseNotesAuthor{{Name: c.Author.Name, Email: c.Author.Email}}, extractCoAuthors(c.Message)...)
+		for _, author := range authors {
+			_, email := m.lookup(author.Name, author.Email)
+			if contributor, found := contributors[strings.ToLower(email)]; found {
+				contributor.FirstTime = false
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, gitErrorf("unable to list commits before %v: %w", component.changes.oldRev, err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMailmap(t *testing.T) {
+	req := require.New(t)
+	m := parseMailmap(`# comment
+Jane Doe <jane@old.example.com>
+<bob@example.com> <bob@laptop.local>
+Carol Smith <carol@example.com> <CAROL@old.example.com> # trailing comment
+Dave <dave@example.com> build <ci@example.com>
+`)
+
+	lookup := func(name, email string) []string {
+		name, email = m.lookup(name, email)
+		return []string{name, email}
+	}
+	req.Equal([]string{"Jane Doe", "jane@old.example.com"}, lookup("jdoe", "jane@old.example.com"))
+	req.Equal([]string{"Bob", "bob@example.com"}, lookup("Bob", "bob@laptop.local"))
+	req.Equal([]string{"Carol Smith", "carol@example.com"}, lookup("carol", "carol@old.example.com"))
+	req.Equal([]string{"Dave", "dave@example.com"}, lookup("build", "ci@example.com"))
+	req.Equal([]string{"other", "ci@example.com"}, lookup("other", "ci@example.com"))
+	req.Equal([]string{"Eve", "eve@example.com"}, lookup("Eve", "eve@example.com"))
+}
+
+func TestCollectContributors(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0")
+	req.NoError(os.WriteFile(".mailmap", []byte("Jane Doe <jane@example.com> <jdoe@old.example.com>\n"), 0644))
+
+	repo, err := git.PlainOpen(".")
+	req.NoError(err)
+	wt, err := repo.Worktree()
+	req.NoError(err)
+	when := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	commit := func(name, email, message string) {
+		req.NoError(os.WriteFile("change.txt", []byte(message), 0644))
+		_, err = wt.Add("change.txt")
+		req.NoError(err)
+		when = when.Add(time.Minute)
+		signature := &object.Signature{Name: name, Email: email, When: when}
+		_, err = wt.Commit(message, &git.CommitOptions{Author: signature, Committer: signature})
+		req.NoError(err)
+	}
+	commit("J. Doe", "jdoe@old.example.com", "fix widgets")
+	commit("Bob", "bob@example.com", "add gadgets\n\nCo-authored-by: Carol <carol@example.com>\nCo-authored-by: dependabot[bot] <bot@example.com>")
+	commit("Bob", "bob@example.com", "tidy gadgets")
+	commit("ziti-ci", "ziti-ci@netfoundry.io", "update dependencies")
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base, Concurrency: 1}
+
+	component := newComponent("github.com/openziti/ziti", ComponentStatusChanged, "v0.3.0", "v0.3.1")
+	component.Current = true
+	component.setChanges("", "v0.3.0", "HEAD")
+	notes := &ReleaseNotes{Version: "0.3.1", Components: []*ReleaseNotesComponent{component}}
+	req.NoError(cmd.collectChanges(notes))
+	contributors, err := cmd.collectContributors(notes)
+	req.NoError(err)
+	req.Equal([]*ReleaseNotesContributor{
+		{Name: "Bob", Email: "bob@example.com", Commits: 2, FirstTime: true},
+		{Name: "Carol", Email: "carol@example.com", Commits: 1, FirstTime: true},
+		{Name: "Jane Doe", Email: "jane@example.com", Commits: 1},
+	}, contributors)
+
+	cmd.quiet = true
+	req.NoError(cmd.writeMarkdown(out, &ReleaseNotes{Version: "0.3.1", Contributors: contributors}, defaultReleaseNotesTemplate))
+	req.Equal("\n## Contributors\n\n* Bob (first contribution)\n* Carol (first contribution)\n* Jane Doe\n", out.String())
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-version"
+	"regexp"
+	"strings"
+)
+
+const (
+	BumpStrategyPatch        = "patch"
+	BumpStrategyConventional = "conventional"
+
+	noBump = -1
+)
+
+var conventionalHeaderRegex = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+var breakingChangeFooterRegex = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s*(.*)$`)
+
+type conventionalCommit struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+// parseConventionalCommit returns the conventional commit details for the given message, or nil if the
+// message doesn't follow the conventional commit format
+func parseConventionalCommit(message string) *conventionalCommit {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	match := conventionalHeaderRegex.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if match == nil {
+		return nil
+	}
+
+	return &conventionalCommit{
+		Type:     strings.ToLower(match[1]),
+		Scope:    match[2],
+		Breaking: match[3] == "!" || breakingChangeFooterRegex.MatchString(message),
+		Subject:  match[4],
+	}
+}
+
+// getBump returns which version segment the commit should bump, given the current version. Breaking changes
+// on a 0.x version bump the minor version, as major version zero makes no compatibility guarantees
+func (c *conventionalCommit) getBump(current *version.Version) int {
+	if c.Breaking {
+		if current != nil && current.Segments()[0] == 0 {
+			return Minor
+		}
+		return Major
+	}
+	switch c.Type {
+	case "feat":
+		return Minor
+	case "fix", "perf":
+		return Patch
+	}
+	return noBump
+}
+
+type versionBumpReason struct {
+	Commit  string `json:"commit"`
+	Subject string `json:"subject"`
+	Bump    string `json:"bump"`
+}
+
+func (r *versionBumpReason) String() string {
+	if r.Commit == "" {
+		return fmt.Sprintf("%v: %v", r.Bump, r.Subject)
+	}
+	return fmt.Sprintf("%v %v: %v", r.Commit, r.Bump, r.Subject)
+}
+
+func bumpName(bump int) string {
+	switch bump {
+	case Major:
+		return "major"
+	case Minor:
+		return "minor"
+	case Patch:
+		return "patch"
+	}
+	return "none"
+}
+
+// evalConventionalBump looks at the commits since the current version tag and returns the largest bump
+// requested by any of them, along with the commits which requested a bump
+func (cmd *BaseCommand) evalConventionalBump(currentTag string) (int, []*versionBumpReason, error) {
+	g, err := cmd.getGit()
+	if err != nil {
+		return noBump, nil, err
+	}
+	commits, err := g.Log(currentTag, "HEAD", cmd.getModuleRepoDir())
+	if err != nil {
+		return noBump, nil, err
+	}
+
+	bump := noBump
+	var reasons []*versionBumpReason
+
+	for _, c := range commits {
+		cc := parseConventionalCommit(c.Message)
+		if cc == nil {
+			continue
+		}
+		commitBump := cc.getBump(cmd.CurrentVersion)
+		if commitBump == noBump {
+			continue
+		}
+		reasons = append(reasons, &versionBumpReason{
+			Commit:  c.Hash[:7],
+			Subject: c.Subject(),
+			Bump:    bumpName(commitBump),
+		})
+		if bump == noBump || commitBump < bump {
+			bump = commitBump
+		}
+	}
+
+	if bump == noBump {
+		bump = Patch
+		reasons = append(reasons, &versionBumpReason{
+			Subject: fmt.Sprintf("%v commits since %v, none with conventional commit markers", len(commits), currentTag),
+			Bump:    bumpName(Patch),
+		})
+	}
+
+	return bump, reasons, nil
+}
+
+func (cmd *BaseCommand) validateBumpStrategy() error {
+	if cmd.bumpStrategy != BumpStrategyPatch && cmd.bumpStrategy != BumpStrategyConventional {
+		return configErrorf("unsupported bump strategy: '%v'. Valid values: [%v,%v]", cmd.bumpStrategy, BumpStrategyPatch, BumpStrategyConventional)
+	}
+	return nil
+}
+
+func (cmd *BaseCommand) explainBumpReasons() {
+	if !cmd.explainBump && !cmd.verbose {
+		return
+	}
+	out := cmd.Cmd.ErrOrStderr()
+	_, _ = fmt.Fprintf(out, "version bump %v -> %v (strategy: %v)\n", cmd.CurrentVersion, cmd.NextVersion, cmd.bumpStrategy)
+	for _, reason := range cmd.BumpReasons {
+		_, _ = fmt.Fprintf(out, "    %v\n", reason)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	req := require.New(t)
+
+	req.Nil(parseConventionalCommit("Update dependencies"))
+	req.Nil(parseConventionalCommit("Merge pull request #12 from openziti/feat: x"))
+
+	cc := parseConventionalCommit("feat(edge): add posture checks")
+	req.NotNil(cc)
+	req.Equal("feat", cc.Type)
+	req.Equal("edge", cc.Scope)
+	req.Equal("add posture checks", cc.Subject)
+	req.False(cc.Breaking)
+
+	cc = parseConventionalCommit("fix!: drop v1 api")
+	req.NotNil(cc)
+	req.True(cc.Breaking)
+
+	cc = parseConventionalCommit("refactor: rework router\n\nBREAKING CHANGE: config format changed")
+	req.NotNil(cc)
+	req.Equal("refactor", cc.Type)
+	req.True(cc.Breaking)
+}
+
+func TestConventionalCommitBump(t *testing.T) {
+	req := require.New(t)
+	v1 := version.Must(version.NewVersion("1.4.2"))
+	v0 := version.Must(version.NewVersion("0.34.2"))
+
+	req.Equal(Minor, parseConventionalCommit("feat: x").getBump(v1))
+	req.Equal(Patch, parseConventionalCommit("fix: x").getBump(v1))
+	req.Equal(Patch, parseConventionalCommit("perf: x").getBump(v1))
+	req.Equal(noBump, parseConventionalCommit("docs: x").getBump(v1))
+	req.Equal(Major, parseConventionalCommit("feat!: x").getBump(v1))
+	req.Equal(Minor, parseConventionalCommit("feat!: x").getBump(v0))
+}
+
+func TestGetNextResetsLowerSegments(t *testing.T) {
+	req := require.New(t)
+	v := version.Must(version.NewVersion("1.4.2"))
+	for index, expected := range map[int]string{Major: "2.0.0", Minor: "1.5.0", Patch: "1.4.3"} {
+		next, err := getNext(index, v)
+		req.NoError(err)
+		req.Equal(expected, next.String())
+	}
+
+	_, err := getNext(noBump, v)
+	req.Error(err)
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies the failures reported by ziti-ci. Each kind maps to its own process exit code
+type ErrorKind int
+
+const (
+	ErrorKindGeneral ErrorKind = iota
+	ErrorKindConfig
+	ErrorKindVersion
+	ErrorKindGit
+	ErrorKindNetwork
+	ErrorKindVulnerability
+)
+
+const (
+	ExitCodeGeneral = 1
+	ExitCodeConfig  = 2
+	ExitCodeVersion = 3
+	ExitCodeGit     = 4
+	ExitCodeNetwork = 5
+	// ExitCodeVulnerability is returned when dependencies have known vulnerabilities, as opposed to failing to check
+	ExitCodeVulnerability = 6
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindConfig:
+		return "config"
+	case ErrorKindVersion:
+		return "version"
+	case ErrorKindGit:
+		return "git"
+	case ErrorKindNetwork:
+		return "network"
+	case ErrorKindVulnerability:
+		return "vulnerability"
+	}
+	return "general"
+}
+
+// ExitCode returns the process exit code used for failures of this kind
+func (k ErrorKind) ExitCode() int {
+	switch k {
+	case ErrorKindConfig:
+		return ExitCodeConfig
+	case ErrorKindVersion:
+		return ExitCodeVersion
+	case ErrorKindGit:
+		return ExitCodeGit
+	case ErrorKindNetwork:
+		return ExitCodeNetwork
+	case ErrorKindVulnerability:
+		return ExitCodeVulnerability
+	}
+	return ExitCodeGeneral
+}
+
+// CiError is a failure of a known kind. Use errors.As or KindOf to inspect the kind of errors returned
+// from commands or from the library API
+type CiError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *CiError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CiError) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the first CiError in the given error's chain, or ErrorKindGeneral if there is none
+func KindOf(err error) ErrorKind {
+	var ciErr *CiError
+	if errors.As(err, &ciErr) {
+		return ciErr.Kind
+	}
+	return ErrorKindGeneral
+}
+
+// ExitCode returns the process exit code for the given error, 0 if it's nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return KindOf(err).ExitCode()
+}
+
+func newCiError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ciErr *CiError
+	if errors.As(err, &ciErr) {
+		return err
+	}
+	return &CiError{Kind: kind, Err: err}
+}
+
+func configErrorf(format string, params ...interface{}) error {
+	return &CiError{Kind: ErrorKindConfig, Err: fmt.Errorf(format, params...)}
+}
+
+func versionErrorf(format string, params ...interface{}) error {
+	return &CiError{Kind: ErrorKindVersion, Err: fmt.Errorf(format, params...)}
+}
+
+func gitErrorf(format string, params ...interface{}) error {
+	return &CiError{Kind: ErrorKindGit, Err: fmt.Errorf(format, params...)}
+}
+
+func networkErrorf(format string, params ...interface{}) error {
+	return &CiError{Kind: ErrorKindNetwork, Err: fmt.Errorf(format, params...)}
+}
+
+func vulnerabilityErrorf(format string, params ...interface{}) error {
+	return &CiError{Kind: ErrorKindVulnerability, Err: fmt.Errorf(format, params...)}
+}
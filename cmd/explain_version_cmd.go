@@ -0,0 +1,298 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+// ciEnvVars are the environment variables which influence version and branch evaluation
+var ciEnvVars = []string{
+	"GITHUB_ACTIONS",
+	"GITHUB_REF",
+	"GITHUB_REF_NAME",
+	"GITHUB_HEAD_REF",
+	"GITHUB_RUN_NUMBER",
+	"TRAVIS_BRANCH",
+	"TRAVIS_PULL_REQUEST_BRANCH",
+	"TRAVIS_BUILD_NUMBER",
+}
+
+// versionTrace records every input consulted while evaluating the current and next version, so that
+// surprising results can be explained after the fact
+type versionTrace struct {
+	BaseVersionSource       string               `json:"baseVersionSource,omitempty"`
+	BaseVersionFile         string               `json:"baseVersionFile,omitempty"`
+	BaseVersionFileError    string               `json:"baseVersionFileError,omitempty"`
+	BaseVersionFileContents string               `json:"baseVersionFileContents,omitempty"`
+	BaseVersionFromFlag     bool                 `json:"baseVersionFromFlag"`
+	BaseVersion             string               `json:"baseVersion"`
+	Language                string               `json:"language"`
+	BumpStrategy            string               `json:"bumpStrategy"`
+	PrereleaseChannel       string               `json:"prereleaseChannel,omitempty"`
+	TagPrefix               string               `json:"tagPrefix,omitempty"`
+	Branch                  string               `json:"branch,omitempty"`
+	ReleaseLine             string               `json:"releaseLine,omitempty"`
+	UseCurrentTag           bool                 `json:"useCurrentTag"`
+	CurrentTagSource        string               `json:"currentTagSource,omitempty"`
+	CurrentTag              string               `json:"currentTag,omitempty"`
+	Env                     map[string]string    `json:"env"`
+	TagQueries              []*tagQuery          `json:"tagQueries"`
+	WindowMin               string               `json:"windowMin,omitempty"`
+	WindowMax               string               `json:"windowMax,omitempty"`
+	BumpReasons             []*versionBumpReason `json:"bumpReasons,omitempty"`
+	CurrentVersion          string               `json:"currentVersion,omitempty"`
+	NextVersion             string               `json:"nextVersion,omitempty"`
+	// Error is the failure which stopped the evaluation, leaving the rest of the trace incomplete
+	Error string `json:"error,omitempty"`
+}
+
+type tagQuery struct {
+	Command string         `json:"command"`
+	Tags    []*tagDecision `json:"tags"`
+}
+
+type tagDecision struct {
+	Tag      string `json:"tag"`
+	Version  string `json:"version,omitempty"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (q *tagQuery) accept(tag string, v *version.Version) {
+	q.Tags = append(q.Tags, &tagDecision{Tag: tag, Version: v.String(), Accepted: true})
+}
+
+func (q *tagQuery) reject(tag string, reason string) {
+	q.Tags = append(q.Tags, &tagDecision{Tag: tag, Reason: reason})
+}
+
+func (t *versionTrace) addTagQuery(command string) *tagQuery {
+	result := &tagQuery{Command: command}
+	if t != nil {
+		t.TagQueries = append(t.TagQueries, result)
+	}
+	return result
+}
+
+func (t *versionTrace) setWindow(min, max *version.Version) {
+	if t == nil {
+		return
+	}
+	if min != nil {
+		t.WindowMin = min.String()
+	}
+	if max != nil {
+		t.WindowMax = max.String()
+	}
+}
+
+func (t *versionTrace) recordSettings(cmd *BaseCommand) error {
+	if t == nil {
+		return nil
+	}
+	t.Language = cmd.langName
+	t.BumpStrategy = cmd.bumpStrategy
+	t.PrereleaseChannel = cmd.prereleaseChannel
+	t.TagPrefix = cmd.getTagPrefix()
+	t.UseCurrentTag = cmd.useCurrentTag
+	branch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	t.Branch = branch
+	t.Env = map[string]string{}
+	for _, envVar := range ciEnvVars {
+		if val, found := os.LookupEnv(envVar); found {
+			t.Env[envVar] = val
+		}
+	}
+	return nil
+}
+
+func (t *versionTrace) recordResult(cmd *BaseCommand) {
+	if t == nil {
+		return
+	}
+	t.BumpReasons = cmd.BumpReasons
+	if cmd.CurrentVersion != nil {
+		t.CurrentVersion = cmd.CurrentVersion.String()
+	}
+	if cmd.NextVersion != nil {
+		t.NextVersion = cmd.NextVersion.String()
+	}
+}
+
+// inWindow returns a description of whether the given version falls into the window of versions considered
+// when looking for the current version
+func (t *versionTrace) inWindow(v string) string {
+	parsed, err := version.NewVersion(v)
+	if err != nil {
+		return ""
+	}
+	if t.WindowMin != "" && parsed.LessThan(version.Must(version.NewVersion(t.WindowMin))) {
+		return "below window"
+	}
+	if t.WindowMax != "" && !parsed.LessThan(version.Must(version.NewVersion(t.WindowMax))) {
+		return "above window"
+	}
+	if parsed.Prerelease() != "" && t.PrereleaseChannel == "" {
+		return "pre-release, only used with --prerelease-channel"
+	}
+	return "in window"
+}
+
+func (t *versionTrace) writeText(out io.Writer) {
+	p := func(format string, params ...interface{}) {
+		_, _ = fmt.Fprintf(out, format, params...)
+	}
+
+	p("base version: %v\n", t.BaseVersion)
+	if t.BaseVersionFromFlag {
+		p("    source: --base-version flag\n")
+	} else {
+		if t.BaseVersionFileError != "" {
+			p("    primary version file failed: %v\n", t.BaseVersionFileError)
+		}
+		p("    source: %v\n", t.BaseVersionSource)
+		p("    source file: %v\n", t.BaseVersionFile)
+		p("    file contents: %q\n", t.BaseVersionFileContents)
+	}
+	p("language: %v\n", t.Language)
+	p("bump strategy: %v\n", t.BumpStrategy)
+	if t.PrereleaseChannel != "" {
+		p("pre-release channel: %v\n", t.PrereleaseChannel)
+	}
+	if t.TagPrefix != "" {
+		p("tag prefix: %v\n", t.TagPrefix)
+	}
+	p("branch: %v\n", t.Branch)
+	if t.ReleaseLine != "" {
+		p("release branch line: %v\n", t.ReleaseLine)
+	}
+
+	p("environment:\n")
+	for _, envVar := range ciEnvVars {
+		if val, found := t.Env[envVar]; found {
+			p("    %v=%v\n", envVar, val)
+		} else {
+			p("    %v (not set)\n", envVar)
+		}
+	}
+
+	p("version window: %v <= v < %v\n", valueOr(t.WindowMin, "any"), valueOr(t.WindowMax, "any"))
+
+	for _, query := range t.TagQueries {
+		p("tags from %v:\n", query.Command)
+		for _, tag := range query.Tags {
+			if tag.Accepted {
+				p("    %v: accepted as %v (%v)\n", tag.Tag, tag.Version, t.inWindow(tag.Version))
+			} else {
+				p("    %v: rejected, %v\n", tag.Tag, tag.Reason)
+			}
+		}
+	}
+
+	if t.UseCurrentTag {
+		p("--use-current-tag: %v from %v\n", t.CurrentTag, t.CurrentTagSource)
+	}
+
+	p("bump reasons:\n")
+	for _, reason := range t.BumpReasons {
+		p("    %v\n", reason)
+	}
+
+	p("current version: %v\n", valueOr(t.CurrentVersion, "none"))
+	p("next version: %v\n", valueOr(t.NextVersion, "none"))
+	if t.Error != "" {
+		p("failed: %v\n", t.Error)
+	}
+}
+
+func valueOr(val, defaultVal string) string {
+	if val == "" {
+		return defaultVal
+	}
+	return val
+}
+
+type explainVersionCmd struct {
+	BaseCommand
+	format  string
+	initErr error
+}
+
+// Init defers any failure to Execute, so the trace gathered up to the failure is still printed
+func (cmd *explainVersionCmd) Init(args []string) error {
+	cmd.initErr = cmd.BaseCommand.Init(args)
+	return nil
+}
+
+func (cmd *explainVersionCmd) Execute() error {
+	if cmd.format != "text" && cmd.format != "json" {
+		return configErrorf("unsupported format: '%v'. Valid values: [text,json]", cmd.format)
+	}
+
+	if !cmd.RootCobraCmd.Flags().Changed("quiet") {
+		cmd.quiet = true
+	}
+
+	err := cmd.initErr
+	if err == nil {
+		err = cmd.EvalCurrentAndNextVersion()
+	}
+	if err != nil {
+		cmd.VersionTrace.Error = err.Error()
+	}
+
+	out := cmd.Cmd.OutOrStdout()
+	if cmd.format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		if encodeErr := encoder.Encode(cmd.VersionTrace); encodeErr != nil && err == nil {
+			err = fmt.Errorf("unable to write version trace as json: %w", encodeErr)
+		}
+	} else {
+		cmd.VersionTrace.writeText(out)
+	}
+	return err
+}
+
+func newExplainVersionCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "explain-version",
+		Short: "Print every input consulted when computing the current and next version",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	result := &explainVersionCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&result.format, "format", "o", "text", "output format. Valid values: [text,json]")
+
+	return FinalizeErroringCmd(result)
+}
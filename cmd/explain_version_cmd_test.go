@@ -0,0 +1,103 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// explainVersion runs explain-version with the given arguments, returning its stdout, stderr and error
+func explainVersion(args ...string) (string, string, error) {
+	root := newRootCommand()
+	root.RootCobraCmd.AddCommand(newExplainVersionCmd(root))
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	root.RootCobraCmd.SetOut(out)
+	root.RootCobraCmd.SetErr(errOut)
+	root.RootCobraCmd.SetArgs(append([]string{"explain-version"}, args...))
+	err := root.RootCobraCmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestExplainVersion(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.2.4", "v0.3.0", "v0.3.1")
+
+	out, _, err := explainVersion()
+	req.NoError(err)
+	req.Contains(out, "base version: 0.3\n")
+	req.Contains(out, "    v0.3.1: accepted as 0.3.1")
+	req.Contains(out, "current version: 0.3.1\n")
+	req.Contains(out, "next version: 0.3.2\n")
+	req.NotContains(out, "failed:")
+
+	out, _, err = explainVersion("-o", "json")
+	req.NoError(err)
+	trace := &versionTrace{}
+	req.NoError(json.Unmarshal([]byte(out), trace))
+	req.Equal("0.3", trace.BaseVersion)
+	req.Equal("0.3.1", trace.CurrentVersion)
+	req.Equal("0.3.2", trace.NextVersion)
+	req.Empty(trace.Error)
+}
+
+func TestExplainVersionConventional(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3", "v0.2.4")
+	r.commit("change.txt", "widgets\n", "feat: add widgets")
+
+	// the latest tag is current, even below the base version
+	out, _, err := explainVersion("--bump-strategy", "conventional")
+	req.NoError(err)
+	req.Contains(out, "version window: any <= v < any\n")
+	req.Contains(out, "    v0.2.4: accepted as 0.2.4 (in window)\n")
+	req.Contains(out, "current version: 0.2.4\n")
+	req.Contains(out, "next version: 0.3.0\n")
+}
+
+func TestExplainVersionFailure(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0", "v0.3.1")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REF_NAME", "")
+
+	// the tags were listed before the current tag was found missing, so they're part of the trace
+	out, _, err := explainVersion("--use-current-tag")
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+	req.Contains(out, "    v0.3.1: accepted as 0.3.1")
+	req.Contains(out, "failed: GITHUB_REF_NAME not set\n")
+
+	out, _, err = explainVersion("--use-current-tag", "-o", "json")
+	req.Error(err)
+	trace := &versionTrace{}
+	req.NoError(json.Unmarshal([]byte(out), trace))
+	req.Equal("GITHUB_REF_NAME not set", trace.Error)
+	req.Len(trace.TagQueries, 1)
+
+	// a failure during initialization still produces a trace
+	out, _, err = explainVersion("--base-version", "not-a-version", "-o", "json")
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+	trace = &versionTrace{}
+	req.NoError(json.Unmarshal([]byte(out), trace))
+	req.Contains(trace.Error, "not-a-version")
+}
@@ -26,8 +26,13 @@ type getBranchCmd struct {
 	BaseCommand
 }
 
-func (cmd *getBranchCmd) Execute() {
-	fmt.Print(cmd.GetCurrentBranch())
+func (cmd *getBranchCmd) Execute() error {
+	branch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	fmt.Print(branch)
+	return nil
 }
 
 func newGetBranchCmd(root *RootCommand) *cobra.Command {
@@ -44,5 +49,5 @@ func newGetBranchCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
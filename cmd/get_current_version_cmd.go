@@ -26,14 +26,14 @@ type getCurrentVersionCmd struct {
 	BaseCommand
 }
 
-func (cmd *getCurrentVersionCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
-
-	tagVersion := fmt.Sprintf("%v", cmd.CurrentVersion)
-	if cmd.isGoLang() {
-		tagVersion = "v" + tagVersion
+func (cmd *getCurrentVersionCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
 	}
+
+	tagVersion := cmd.getVersionTag(cmd.CurrentVersion)
 	fmt.Print(tagVersion)
+	return nil
 }
 
 func newGetCurrentVersionCmd(root *RootCommand) *cobra.Command {
@@ -50,5 +50,5 @@ func newGetCurrentVersionCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -26,14 +26,14 @@ type getNextVersionCmd struct {
 	BaseCommand
 }
 
-func (cmd *getNextVersionCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
-
-	tagVersion := fmt.Sprintf("%v", cmd.NextVersion)
-	if cmd.isGoLang() {
-		tagVersion = "v" + tagVersion
+func (cmd *getNextVersionCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
 	}
+
+	tagVersion := cmd.getVersionTag(cmd.NextVersion)
 	fmt.Print(tagVersion)
+	return nil
 }
 
 func newGetNextVersionCmd(root *RootCommand) *cobra.Command {
@@ -50,5 +50,5 @@ func newGetNextVersionCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
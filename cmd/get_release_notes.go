@@ -18,7 +18,7 @@
 package cmd
 
 import (
-	"bufio"
+	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
 	"io"
@@ -26,53 +26,74 @@ import (
 	"strings"
 )
 
+const (
+	ReleaseNotesSectionFormatText = "text"
+	ReleaseNotesSectionFormatJson = "json"
+)
+
 type getReleaseNotesCmd struct {
 	BaseCommand
+	all    bool
+	format string
 }
 
-func extractReleaseNotes(changelog string, version string, outfile string) {
-	if strings.HasPrefix(version, "v") {
-		version = version[1:]
+// extractReleaseNotes writes the changelog sections matching the selector to outfile, or stdout if it's empty.
+// See selectChangelogSections for the selectors. With all set, every section is written, regardless of selector
+func (cmd *BaseCommand) extractReleaseNotes(changelog, selector, outfile string, all bool, format string) error {
+	changelogFormat, err := cmd.getChangelogFormat()
+	if err != nil {
+		return err
+	}
+	if format != ReleaseNotesSectionFormatText && format != ReleaseNotesSectionFormatJson {
+		return configErrorf("invalid format %v. Valid values: [text,json]", format)
 	}
-	file, err := os.Open(changelog)
+
+	data, err := os.ReadFile(changelog)
 	if err != nil {
-		panic(err)
+		return configErrorf("unable to open changelog: %w", err)
 	}
-	defer func() { _ = file.Close() }()
 
-	var out io.WriteCloser
-	if outfile == "" {
-		out = os.Stdout
-	} else {
-		out, err = os.Create(outfile)
-		if err != nil {
-			panic(err)
+	sections := changelogFormat.parse(string(data))
+	if !all {
+		if sections, err = selectChangelogSections(sections, selector); err != nil {
+			return err
+		}
+		if len(sections) == 0 {
+			cmd.Warnf("no release notes for %v found in %v\n", valueOr(selector, "the latest release"), changelog)
 		}
-		defer func() { _ = out.Close() }()
 	}
 
-	scanner := bufio.NewScanner(file)
-	startFound := false
+	var out io.Writer = cmd.Cmd.OutOrStdout()
+	if outfile != "" {
+		file, err := os.Create(outfile)
+		if err != nil {
+			return err
+		}
+		defer cmd.close(file, outfile)
+		out = file
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "# Release") {
-			if startFound {
-				return
-			}
-			if version == "" || strings.HasPrefix(line, fmt.Sprintf("# Release %v", version)) {
-				startFound = true
-			}
+	if format == ReleaseNotesSectionFormatJson {
+		if sections == nil {
+			sections = []*ChangelogSection{}
 		}
-		if startFound {
-			if _, err := fmt.Fprintln(out, line); err != nil {
-				panic(err)
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(sections)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, section := range sections {
+		for _, line := range lines[section.start:section.end] {
+			if _, err = fmt.Fprintln(out, line); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
 }
 
-func (cmd *getReleaseNotesCmd) Execute() {
+func (cmd *getReleaseNotesCmd) Execute() error {
 	version := ""
 	if len(cmd.Args) > 1 {
 		version = cmd.Args[1]
@@ -80,15 +101,15 @@ func (cmd *getReleaseNotesCmd) Execute() {
 
 	outfile := ""
 	if len(cmd.Args) > 2 {
-		version = cmd.Args[2]
+		outfile = cmd.Args[2]
 	}
 
-	extractReleaseNotes(cmd.Args[0], version, outfile)
+	return cmd.extractReleaseNotes(cmd.Args[0], version, outfile, cmd.all, cmd.format)
 }
 
 func newGetReleaseNotesCmd(root *RootCommand) *cobra.Command {
 	cobraCmd := &cobra.Command{
-		Use:   "get-release-notes <changelog> <version>",
+		Use:   "get-release-notes <changelog> [version|unreleased|from..to] [outfile]",
 		Short: "Prints out the release notes for the latest or a given version",
 		Args:  cobra.RangeArgs(1, 3),
 	}
@@ -100,5 +121,10 @@ func newGetReleaseNotesCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	cobraCmd.Flags().BoolVar(&result.all, "all", false, "print all sections of the changelog")
+	cobraCmd.Flags().StringVarP(&result.format, "format", "o", ReleaseNotesSectionFormatText, "output format. Valid values: [text,json]")
+	cobraCmd.Flags().String("heading-format", ChangelogFormatZiti, fmt.Sprintf("how release headings are recognized. Valid values: %v", changelogFormats))
+	cobraCmd.Flags().String("heading-regex", "", "regex matching release headings, with a version group, for --heading-format regex")
+
+	return FinalizeErroringCmd(result)
 }
@@ -0,0 +1,436 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/pkg/errors"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GitBackendGoGit = "go-git"
+	GitBackendCli   = "cli"
+)
+
+// Git provides the read operations ziti-ci needs from the repository being built. Operations which change the
+// repository or talk to a remote, such as committing, pushing and signing, still go through the git binary
+type Git interface {
+	FetchTags() error
+	ListTags() ([]string, error)
+	TagsPointingAt(rev string) ([]string, error)
+	TagsMergedInto(rev string) ([]string, error)
+	Describe(tagPrefix string) (string, error)
+	CurrentBranch() (string, error)
+	RevParse(rev string) (string, error)
+	Show(rev string, path string) ([]byte, error)
+	CommitterEmail(rev string) (string, error)
+	CommitTime(rev string) (time.Time, error)
+	// Log returns the commits in oldRev..newRev, newest first. If dir is set, only commits touching it are returned
+	Log(oldRev, newRev, dir string) ([]*gitCommit, error)
+}
+
+type gitCommit struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	When        time.Time
+	Message     string
+	NumParents  int
+}
+
+func (c *gitCommit) Subject() string {
+	return strings.Split(strings.TrimSpace(c.Message), "\n")[0]
+}
+
+func newGitCommit(c *object.Commit) *gitCommit {
+	return &gitCommit{
+		Hash:        c.Hash.String(),
+		AuthorName:  c.Author.Name,
+		AuthorEmail: c.Author.Email,
+		When:        c.Author.When,
+		Message:     c.Message,
+		NumParents:  c.NumParents(),
+	}
+}
+
+func (cmd *BaseCommand) getGit() (Git, error) {
+	if cmd.git == nil {
+		if cmd.gitBackend == GitBackendCli {
+			cmd.git = &cliGit{cmd: cmd}
+		} else if cmd.gitBackend == GitBackendGoGit {
+			r, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+			if err != nil {
+				return nil, gitErrorf("unable to open git repository: %w", err)
+			}
+			cmd.git = &goGit{repo: r}
+		} else {
+			return nil, configErrorf("unsupported git backend: '%v'. Valid values: [%v,%v]", cmd.gitBackend, GitBackendGoGit, GitBackendCli)
+		}
+	}
+	return cmd.git, nil
+}
+
+func (cmd *BaseCommand) fetchTags() error {
+	g, err := cmd.getGit()
+	if err != nil {
+		return err
+	}
+	cmd.Infof("fetching git tags using %v\n", cmd.gitBackend)
+	err = g.FetchTags()
+	if err != nil && cmd.gitBackend == GitBackendGoGit {
+		// go-git doesn't honor git configuration such as core.sshCommand, so fall back to the binary if we have one
+		if _, lookupErr := exec.LookPath("git"); lookupErr == nil {
+			cmd.Warnf("unable to fetch tags using go-git, falling back to git binary: %v\n", err)
+			err = (&cliGit{cmd: cmd}).FetchTags()
+		}
+	}
+	if err != nil {
+		return gitErrorf("error fetching git tags: %w", err)
+	}
+	return nil
+}
+
+// listTags runs the given tag query against the repository
+func (cmd *BaseCommand) listTags(description string, f func(g Git) ([]string, error)) ([]string, error) {
+	g, err := cmd.getGit()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := f(g)
+	if err != nil {
+		return nil, gitErrorf("error %v: %w", description, err)
+	}
+	return tags, nil
+}
+
+func (cmd *BaseCommand) getRevision() (string, error) {
+	g, err := cmd.getGit()
+	if err != nil {
+		return "", err
+	}
+	revision, err := g.RevParse("HEAD")
+	if err != nil {
+		return "", gitErrorf("error getting git SHA: %w", err)
+	}
+	return revision[:12], nil
+}
+
+// goGit implements Git in-process, using go-git
+type goGit struct {
+	repo *git.Repository
+}
+
+func (g *goGit) FetchTags() error {
+	err := g.repo.Fetch(&git.FetchOptions{
+		RefSpecs: []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+		Tags:     git.AllTags,
+		Force:    true,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+// peeledTags returns the tags in the repository, mapped to the hash of the commit they point to
+func (g *goGit) peeledTags() (map[string]plumbing.Hash, error) {
+	iter, err := g.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]plumbing.Hash{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		hash, err := g.repo.ResolveRevision(plumbing.Revision(ref.Name().String()))
+		if err != nil {
+			// tags pointing to trees or blobs don't name versions
+			return nil
+		}
+		result[ref.Name().Short()] = *hash
+		return nil
+	})
+	return result, err
+}
+
+func (g *goGit) ListTags() ([]string, error) {
+	tags, err := g.peeledTags()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for tag := range tags {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (g *goGit) TagsPointingAt(rev string) ([]string, error) {
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := g.peeledTags()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for tag, tagHash := range tags {
+		if tagHash == *hash {
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (g *goGit) ancestors(rev string) (map[plumbing.Hash]bool, error) {
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	result := map[plumbing.Hash]bool{}
+	err = object.NewCommitPreorderIter(c, nil, nil).ForEach(func(c *object.Commit) error {
+		result[c.Hash] = true
+		return nil
+	})
+	return result, err
+}
+
+func (g *goGit) TagsMergedInto(rev string) ([]string, error) {
+	ancestors, err := g.ancestors(rev)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := g.peeledTags()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for tag, tagHash := range tags {
+		if ancestors[tagHash] {
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Describe mimics git describe --tags, returning the closest tag of the module with the given tag prefix reachable
+// from HEAD, followed by the number of commits since the tag and the abbreviated commit hash if HEAD isn't tagged
+func (g *goGit) Describe(tagPrefix string) (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	tags, err := g.peeledTags()
+	if err != nil {
+		return "", err
+	}
+	tagsByHash := map[plumbing.Hash][]string{}
+	for tag, hash := range tags {
+		if isModuleTag(tag, tagPrefix) {
+			tagsByHash[hash] = append(tagsByHash[hash], tag)
+		}
+	}
+
+	headCommit, err := g.repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", err
+	}
+
+	depth := map[plumbing.Hash]int{head.Hash(): 0}
+	result := ""
+	iter := object.NewCommitIterBSF(headCommit, nil, nil)
+	err = iter.ForEach(func(c *object.Commit) error {
+		if names, found := tagsByHash[c.Hash]; found {
+			sort.Strings(names)
+			if depth[c.Hash] == 0 {
+				result = names[len(names)-1]
+			} else {
+				result = fmt.Sprintf("%v-%v-g%v", names[len(names)-1], depth[c.Hash], head.Hash().String()[:7])
+			}
+			return storer.ErrStop
+		}
+		for _, parent := range c.ParentHashes {
+			if _, found := depth[parent]; !found {
+				depth[parent] = depth[c.Hash] + 1
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if result == "" {
+		return "", errors.New("no tags can describe HEAD")
+	}
+	return result, nil
+}
+
+// isModuleTag returns true if the tag has the given prefix, and isn't for a module nested below it. With no prefix,
+// only tags of the root module, without a slash, are accepted
+func isModuleTag(tag, tagPrefix string) bool {
+	return strings.HasPrefix(tag, tagPrefix) && !strings.Contains(strings.TrimPrefix(tag, tagPrefix), "/")
+}
+
+// CurrentBranch returns the checked out branch. If HEAD is detached, a branch whose tip is HEAD is returned
+// instead, or HEAD if there is none
+func (g *goGit) CurrentBranch() (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if head.Name().IsBranch() {
+		return head.Name().Short(), nil
+	}
+
+	refs, err := g.repo.References()
+	if err != nil {
+		return "", err
+	}
+	var candidates []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Hash() == head.Hash() && (ref.Name().IsBranch() || ref.Name().IsRemote()) {
+			name := ref.Name().Short()
+			if ref.Name().IsRemote() {
+				name = name[strings.Index(name, "/")+1:]
+			}
+			candidates = append(candidates, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "HEAD", nil
+	}
+	sort.Strings(candidates)
+	return candidates[0], nil
+}
+
+func (g *goGit) resolve(rev string) (*plumbing.Hash, error) {
+	if rev == "FETCH_HEAD" {
+		return g.fetchHead()
+	}
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve revision %v", rev)
+	}
+	return hash, nil
+}
+
+// fetchHead reads the first commit from .git/FETCH_HEAD, which go-git doesn't treat as a reference
+func (g *goGit) fetchHead() (*plumbing.Hash, error) {
+	storage, ok := g.repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, errors.New("FETCH_HEAD is only available in repositories stored on disk")
+	}
+	file, err := storage.Filesystem().Open("FETCH_HEAD")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read FETCH_HEAD")
+	}
+	defer func() { _ = file.Close() }()
+
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "unable to read FETCH_HEAD")
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !plumbing.IsHash(fields[0]) {
+		return nil, errors.Errorf("unexpected FETCH_HEAD contents: %v", line)
+	}
+	hash := plumbing.NewHash(fields[0])
+	return &hash, nil
+}
+
+func (g *goGit) RevParse(rev string) (string, error) {
+	hash, err := g.resolve(rev)
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+func (g *goGit) commit(rev string) (*object.Commit, error) {
+	hash, err := g.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	return g.repo.CommitObject(*hash)
+}
+
+func (g *goGit) Show(rev string, path string) ([]byte, error) {
+	c, err := g.commit(rev)
+	if err != nil {
+		return nil, err
+	}
+	file, err := c.File(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find %v at %v", path, rev)
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+func (g *goGit) CommitterEmail(rev string) (string, error) {
+	c, err := g.commit(rev)
+	if err != nil {
+		return "", err
+	}
+	return c.Committer.Email, nil
+}
+
+func (g *goGit) CommitTime(rev string) (time.Time, error) {
+	c, err := g.commit(rev)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Committer.When, nil
+}
+
+func (g *goGit) Log(oldRev, newRev, dir string) ([]*gitCommit, error) {
+	commits, err := commitsBetween(g.repo, oldRev, newRev, cleanRepoDir(dir))
+	if err != nil {
+		return nil, err
+	}
+	var result []*gitCommit
+	for _, c := range commits {
+		result = append(result, newGitCommit(c))
+	}
+	return result, nil
+}
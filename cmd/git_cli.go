@@ -0,0 +1,161 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliGit implements Git by running the git binary
+type cliGit struct {
+	cmd *BaseCommand
+}
+
+func (g *cliGit) run(description string, params ...string) ([]string, error) {
+	return g.cmd.runCommandWithOutput(description, "git", params...)
+}
+
+func (g *cliGit) runOneLine(description string, params ...string) (string, error) {
+	lines, err := g.run(description, params...)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("expected 1 line from git %v, but got %v", strings.Join(params, " "), len(lines))
+	}
+	return lines[0], nil
+}
+
+func (g *cliGit) FetchTags() error {
+	_, err := g.run("fetching git tags", "fetch", "--tags", "--force")
+	return err
+}
+
+func (g *cliGit) ListTags() ([]string, error) {
+	return g.run("list git tags", "tag", "--list")
+}
+
+func (g *cliGit) TagsPointingAt(rev string) ([]string, error) {
+	return g.run("list git tags", "tag", "--points-at", rev)
+}
+
+func (g *cliGit) TagsMergedInto(rev string) ([]string, error) {
+	return g.run("list git tags", "tag", "--merged", rev)
+}
+
+func (g *cliGit) Describe(tagPrefix string) (string, error) {
+	lines, err := g.run("get current git tag", "describe", "--tags", "--match", tagPrefix+"*", "--exclude", tagPrefix+"*/*")
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", errors.New("no tags can describe HEAD")
+	}
+	return lines[0], nil
+}
+
+func (g *cliGit) CurrentBranch() (string, error) {
+	branchName, err := g.runOneLine("get git branch (rev-parse)", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branchName == "HEAD" {
+		branchName, err = g.runOneLine("get git branch (name-rev)", "name-rev", "--name-only", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		g.cmd.Infof("git name-rev returned %v\n", branchName)
+		if strings.HasPrefix(branchName, "/remotes/origin/") {
+			branchName = strings.TrimPrefix(branchName, "/remotes/origin/")
+		}
+	}
+	return branchName, nil
+}
+
+func (g *cliGit) RevParse(rev string) (string, error) {
+	return g.runOneLine("get git SHA", "rev-parse", rev)
+}
+
+func (g *cliGit) Show(rev string, path string) ([]byte, error) {
+	command := exec.Command("git", "show", rev+":"+path)
+	output := &bytes.Buffer{}
+	command.Stdout = output
+	if err := command.Run(); err != nil {
+		return nil, errors.Wrapf(err, "unable to show %v at %v", path, rev)
+	}
+	return output.Bytes(), nil
+}
+
+func (g *cliGit) CommitterEmail(rev string) (string, error) {
+	return g.runOneLine("get committer e-mail address", "log", "-1", rev, "--pretty=%cE")
+}
+
+func (g *cliGit) CommitTime(rev string) (time.Time, error) {
+	output, err := g.runOneLine("get commit time", "log", "-1", rev, "--pretty=%ct")
+	if err != nil {
+		return time.Time{}, err
+	}
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "unexpected commit time for %v: %v", rev, output)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
+func (g *cliGit) Log(oldRev, newRev, dir string) ([]*gitCommit, error) {
+	params := []string{"log", "--format=%H%x00%an%x00%ae%x00%at%x00%P%x00%B%x1e", oldRev + ".." + newRev}
+	if dir = cleanRepoDir(dir); dir != "" {
+		params = append(params, "--full-history", "--", ":(top)"+dir)
+	}
+	command := exec.Command("git", params...)
+	output := &bytes.Buffer{}
+	command.Stdout = output
+	if err := command.Run(); err != nil {
+		return nil, errors.Wrapf(err, "unable to get git log %v..%v", oldRev, newRev)
+	}
+
+	var result []*gitCommit
+	for _, record := range strings.Split(output.String(), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 6)
+		if len(fields) != 6 {
+			return nil, errors.Errorf("unexpected git log record: %v", record)
+		}
+		timestamp, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unexpected git log timestamp: %v", fields[3])
+		}
+		result = append(result, &gitCommit{
+			Hash:        fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			When:        time.Unix(timestamp, 0),
+			NumParents:  len(strings.Fields(fields[4])),
+			Message:     fields[5],
+		})
+	}
+	return result, nil
+}
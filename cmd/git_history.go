@@ -0,0 +1,136 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/pkg/errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// commitsBetween returns the commits reachable from newRev which are not reachable from oldRev, in pre-order starting at newRev.
+// If a directory is given, only the commits touching it are returned
+func commitsBetween(r *git.Repository, oldRev, newRev, dir string) ([]*object.Commit, error) {
+	newHash, err := r.ResolveRevision(plumbing.Revision(newRev))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve revision %v", newRev)
+	}
+
+	oldHash, err := r.ResolveRevision(plumbing.Revision(oldRev))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve revision %v", oldRev)
+	}
+
+	oldCommit, err := r.CommitObject(*oldHash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load commit %v", oldHash)
+	}
+
+	excluded := map[plumbing.Hash]bool{}
+	err = object.NewCommitPreorderIter(oldCommit, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to walk history of %v", oldRev)
+	}
+
+	newCommit, err := r.CommitObject(*newHash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load commit %v", newHash)
+	}
+
+	var result []*object.Commit
+	iter := object.NewCommitPreorderIter(newCommit, excluded, nil)
+	defer iter.Close()
+
+	for {
+		c, err := iter.Next()
+		if err == io.EOF || err == storer.ErrStop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		touches, err := commitTouchesDir(c, dir)
+		if err != nil {
+			return nil, err
+		}
+		if touches {
+			result = append(result, c)
+		}
+	}
+
+	return result, nil
+}
+
+// cleanRepoDir returns the given directory, relative to the repository root, in the slash separated form git uses
+// for paths in trees. The repository root is returned as empty
+func cleanRepoDir(dir string) string {
+	dir = strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// commitTouchesDir returns true if the commit changes anything below the given directory, relative to the
+// repository root, compared to its first parent. Every commit touches the repository root
+func commitTouchesDir(c *object.Commit, dir string) (bool, error) {
+	if dir == "" {
+		return true, nil
+	}
+	dirHash, err := treeEntryHash(c, dir)
+	if err != nil {
+		return false, err
+	}
+	if c.NumParents() == 0 {
+		return !dirHash.IsZero(), nil
+	}
+	parent, err := c.Parent(0)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to load parent of %v", c.Hash)
+	}
+	parentHash, err := treeEntryHash(parent, dir)
+	if err != nil {
+		return false, err
+	}
+	return dirHash != parentHash, nil
+}
+
+// treeEntryHash returns the hash of the tree entry at the given path in the commit, or the zero hash if it's missing
+func treeEntryHash(c *object.Commit, path string) (plumbing.Hash, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrapf(err, "unable to load tree of %v", c.Hash)
+	}
+	entry, err := tree.FindEntry(path)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrapf(err, "unable to find %v in %v", path, c.Hash)
+	}
+	return entry.Hash, nil
+}
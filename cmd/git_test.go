@@ -0,0 +1,235 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testRepo struct {
+	req  *require.Assertions
+	repo *git.Repository
+	wt   *git.Worktree
+	when time.Time
+}
+
+// newTestRepo returns an in memory repository
+func newTestRepo(t *testing.T) *testRepo {
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	return wrapTestRepo(t, repo)
+}
+
+// newDiskTestRepo returns a repository in a temporary directory, for code which opens repositories by path
+func newDiskTestRepo(t *testing.T) *testRepo {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	require.NoError(t, err)
+	return wrapTestRepo(t, repo)
+}
+
+func wrapTestRepo(t *testing.T, repo *git.Repository) *testRepo {
+	req := require.New(t)
+	wt, err := repo.Worktree()
+	req.NoError(err)
+	return &testRepo{
+		req:  req,
+		repo: repo,
+		wt:   wt,
+		when: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// dir returns the directory of the worktree
+func (r *testRepo) dir() string {
+	return r.wt.Filesystem.Root()
+}
+
+// commit writes the file and commits it, adding a lightweight tag for each of the given tags
+func (r *testRepo) commit(path, contents, message string, tags ...string) plumbing.Hash {
+	f, err := r.wt.Filesystem.Create(path)
+	r.req.NoError(err)
+	_, err = f.Write([]byte(contents))
+	r.req.NoError(err)
+	r.req.NoError(f.Close())
+	return r.commitAll(message, tags...)
+}
+
+// commitAll commits every change in the worktree, adding a lightweight tag for each of the given tags
+func (r *testRepo) commitAll(message string, tags ...string) plumbing.Hash {
+	r.req.NoError(r.wt.AddWithOptions(&git.AddOptions{All: true}))
+
+	r.when = r.when.Add(time.Minute)
+	signature := &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: r.when}
+	hash, err := r.wt.Commit(message, &git.CommitOptions{Author: signature, Committer: signature})
+	r.req.NoError(err)
+	for _, tag := range tags {
+		r.tag(tag, hash, false)
+	}
+	return hash
+}
+
+func (r *testRepo) tag(name string, hash plumbing.Hash, annotated bool) {
+	var opts *git.CreateTagOptions
+	if annotated {
+		opts = &git.CreateTagOptions{
+			Message: name,
+			Tagger:  &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: r.when},
+		}
+	}
+	_, err := r.repo.CreateTag(name, hash, opts)
+	r.req.NoError(err)
+}
+
+func TestGoGit(t *testing.T) {
+	r := newTestRepo(t)
+	req := r.req
+	g := &goGit{repo: r.repo}
+
+	first := r.commit("go.mod", "module example.com/foo\n", "Initial commit")
+	r.tag("v0.1.0", first, true)
+	second := r.commit("go.mod", "module example.com/foo\n\ngo 1.20\n", "fix: set go version")
+	r.tag("v0.1.1", second, false)
+	r.commit("README.md", "foo\n", "feat: add readme\n\nMore details")
+
+	tags, err := g.ListTags()
+	req.NoError(err)
+	req.Equal([]string{"v0.1.0", "v0.1.1"}, tags)
+
+	tags, err = g.TagsPointingAt("HEAD")
+	req.NoError(err)
+	req.Empty(tags)
+
+	tags, err = g.TagsPointingAt("HEAD~1")
+	req.NoError(err)
+	req.Equal([]string{"v0.1.1"}, tags)
+
+	tags, err = g.TagsMergedInto("HEAD~1")
+	req.NoError(err)
+	req.Equal([]string{"v0.1.0", "v0.1.1"}, tags)
+
+	tags, err = g.TagsMergedInto(first.String())
+	req.NoError(err)
+	req.Equal([]string{"v0.1.0"}, tags)
+
+	head, err := g.RevParse("HEAD")
+	req.NoError(err)
+
+	desc, err := g.Describe("")
+	req.NoError(err)
+	req.Equal("v0.1.1-1-g"+head[:7], desc)
+
+	branch, err := g.CurrentBranch()
+	req.NoError(err)
+	req.Equal("master", branch)
+
+	contents, err := g.Show("v0.1.0", "go.mod")
+	req.NoError(err)
+	req.Equal("module example.com/foo\n", string(contents))
+
+	_, err = g.Show("v0.1.0", "README.md")
+	req.Error(err)
+
+	email, err := g.CommitterEmail("HEAD")
+	req.NoError(err)
+	req.Equal("jane@example.com", email)
+
+	commits, err := g.Log("v0.1.0", "HEAD", "")
+	req.NoError(err)
+	req.Len(commits, 2)
+	req.Equal(head, commits[0].Hash)
+	req.Equal("feat: add readme", commits[0].Subject())
+	req.Equal("fix: set go version", commits[1].Subject())
+	req.Equal(1, commits[1].NumParents)
+}
+
+func TestGoGitDescribeTagPrefix(t *testing.T) {
+	r := newTestRepo(t)
+	req := r.req
+	g := &goGit{repo: r.repo}
+
+	r.commit("go.mod", "module example.com/foo\n", "Initial commit", "v1.0.0", "sdk/v1.0.0", "sdk/extra/v1.0.0")
+	head := r.commit("sdk/go.mod", "module example.com/foo/sdk\n", "Add sdk", "sdk/v1.1.0")
+
+	desc, err := g.Describe("")
+	req.NoError(err)
+	req.Equal("v1.0.0-1-g"+head.String()[:7], desc)
+
+	desc, err = g.Describe("sdk/")
+	req.NoError(err)
+	req.Equal("sdk/v1.1.0", desc)
+
+	// the root module is released from the same commit
+	r.tag("v1.1.0", head, false)
+	desc, err = g.Describe("sdk/")
+	req.NoError(err)
+	req.Equal("sdk/v1.1.0", desc)
+	desc, err = g.Describe("")
+	req.NoError(err)
+	req.Equal("v1.1.0", desc)
+
+	_, err = g.Describe("other/")
+	req.Error(err)
+}
+
+func TestGoGitDescribeTaggedHead(t *testing.T) {
+	r := newTestRepo(t)
+	req := r.req
+	g := &goGit{repo: r.repo}
+
+	hash := r.commit("go.mod", "module example.com/foo\n", "Initial commit")
+	r.tag("v1.0.0", hash, true)
+
+	desc, err := g.Describe("")
+	req.NoError(err)
+	req.Equal("v1.0.0", desc)
+
+	r.commit("go.mod", "module example.com/foo\n\ngo 1.20\n", "Update go version")
+	req.NoError(r.wt.Checkout(&git.CheckoutOptions{Hash: hash}))
+
+	branch, err := g.CurrentBranch()
+	req.NoError(err)
+	req.Equal("HEAD", branch)
+}
+
+func TestFetchTagsFallbackWarning(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3", "v0.3.0")
+
+	// an origin neither go-git nor the git binary can fetch from
+	req.NoError(r.repo.DeleteRemote("origin"))
+	_, err := r.repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{filepath.Join(t.TempDir(), "missing")}})
+	req.NoError(err)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd, err := (&Options{Out: stdout, Err: stderr}).newBaseCommand("test")
+	req.NoError(err)
+	req.Error(cmd.fetchTags())
+	req.Empty(stdout.String())
+	req.Contains(stderr.String(), "WARNING: unable to fetch tags using go-git, falling back to git binary")
+}
@@ -0,0 +1,371 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultGithubBatchSize is the number of issues looked up in one GraphQL query
+	DefaultGithubBatchSize = 50
+	// maxRateLimitWait is the longest the client waits for a rate limit to reset before giving up
+	maxRateLimitWait    = 5 * time.Minute
+	maxRateLimitRetries = 3
+)
+
+var githubTokenEnvVars = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+func defaultIssueCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ziti-ci", "issues")
+}
+
+// issueKey identifies an issue or pull request. Repo is the owner/name of its repository
+type issueKey struct {
+	Repo   string
+	Number int
+}
+
+func (k issueKey) String() string {
+	return fmt.Sprintf("%v#%v", k.Repo, k.Number)
+}
+
+// githubIssue is what's looked up about an issue or pull request
+type githubIssue struct {
+	Title       string   `json:"title"`
+	Url         string   `json:"url"`
+	Labels      []string `json:"labels,omitempty"`
+	PullRequest bool     `json:"pullRequest,omitempty"`
+	Merged      bool     `json:"merged,omitempty"`
+	// Closed issues and pull requests won't change, so they're kept in the on-disk cache
+	Closed bool `json:"closed,omitempty"`
+}
+
+// githubClient looks up issues and pull requests with the GitHub API. Lookups are made with batched GraphQL
+// queries if there's a token, or else REST calls, and closed issues are cached on disk
+type githubClient struct {
+	apiUrl    string
+	token     string
+	cacheDir  string
+	batchSize int
+	http      *resty.Client
+	// sleep waits out rate limits. Tests replace it
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+func (cmd *BaseCommand) newGithubClient() *githubClient {
+	result := &githubClient{
+		apiUrl:    strings.TrimSuffix(cmd.Config.Github.ApiUrl, "/"),
+		cacheDir:  cmd.Config.ReleaseNotes.IssueCacheDir,
+		batchSize: DefaultGithubBatchSize,
+		http:      resty.New().SetTimeout(time.Minute),
+		sleep:     time.Sleep,
+		now:       time.Now,
+	}
+	for _, envVar := range githubTokenEnvVars {
+		if token := os.Getenv(envVar); token != "" {
+			result.token = token
+			break
+		}
+	}
+	return result
+}
+
+// graphqlUrl returns the GraphQL endpoint matching the REST api url, ex: https://api.github.com/graphql, or
+// https://github.example.com/api/graphql for GitHub Enterprise
+func (c *githubClient) graphqlUrl() string {
+	if base, ok := strings.CutSuffix(c.apiUrl, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return c.apiUrl + "/graphql"
+}
+
+// lookupIssues returns what could be found about the given issues, and why the others couldn't be looked up
+func (c *githubClient) lookupIssues(keys []issueKey) (map[issueKey]*githubIssue, map[issueKey]error) {
+	result := map[issueKey]*githubIssue{}
+	failures := map[issueKey]error{}
+
+	var remaining []issueKey
+	for _, key := range keys {
+		if issue := c.readCache(key); issue != nil {
+			result[key] = issue
+		} else {
+			remaining = append(remaining, key)
+		}
+	}
+
+	if c.token != "" {
+		for len(remaining) > 0 {
+			batch := remaining[:min(c.batchSize, len(remaining))]
+			remaining = remaining[len(batch):]
+			c.queryIssues(batch, result, failures)
+		}
+	} else {
+		for _, key := range remaining {
+			issue, err := c.getIssue(key)
+			if err != nil {
+				failures[key] = err
+			} else {
+				result[key] = issue
+			}
+		}
+	}
+
+	for key, issue := range result {
+		c.writeCache(key, issue)
+	}
+	return result, failures
+}
+
+// queryIssues looks up a batch of issues with a single GraphQL query
+func (c *githubClient) queryIssues(keys []issueKey, result map[issueKey]*githubIssue, failures map[issueKey]error) {
+	byRepo := map[string][]issueKey{}
+	var repos []string
+	for _, key := range keys {
+		if _, found := byRepo[key.Repo]; !found {
+			repos = append(repos, key.Repo)
+		}
+		byRepo[key.Repo] = append(byRepo[key.Repo], key)
+	}
+	sort.Strings(repos)
+
+	fields := `__typename title url state labels(first: 20) { nodes { name } }`
+	query := &strings.Builder{}
+	query.WriteString("query {\n")
+	for i, repo := range repos {
+		owner, name, _ := strings.Cut(repo, "/")
+		_, _ = fmt.Fprintf(query, "  r%v: repository(owner: %q, name: %q) {\n", i, owner, name)
+		for _, key := range byRepo[repo] {
+			_, _ = fmt.Fprintf(query, "    i%v: issueOrPullRequest(number: %v) { ... on Issue { %v } ... on PullRequest { %v merged } }\n",
+				key.Number, key.Number, fields, fields)
+		}
+		query.WriteString("  }\n")
+	}
+	query.WriteString("}\n")
+
+	type graphqlIssue struct {
+		Typename string `json:"__typename"`
+		Title    string `json:"title"`
+		Url      string `json:"url"`
+		State    string `json:"state"`
+		Merged   bool   `json:"merged"`
+		Labels   struct {
+			Nodes []struct {
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"labels"`
+	}
+	response := &struct {
+		Data   map[string]map[string]*graphqlIssue `json:"data"`
+		Errors []struct {
+			Message string        `json:"message"`
+			Path    []interface{} `json:"path"`
+		} `json:"errors"`
+	}{}
+
+	resp, err := c.do(func() (*resty.Response, error) {
+		return c.http.R().
+			SetHeader("Authorization", "bearer "+c.token).
+			SetBody(map[string]string{"query": query.String()}).
+			Post(c.graphqlUrl())
+	})
+	if err == nil {
+		if err = json.Unmarshal(resp.Body(), response); err != nil {
+			err = networkErrorf("unable to parse GraphQL response: %w", err)
+		}
+	}
+	if err != nil {
+		for _, key := range keys {
+			failures[key] = err
+		}
+		return
+	}
+
+	queryErrors := map[string]string{}
+	for _, queryError := range response.Errors {
+		var path []string
+		for _, element := range queryError.Path {
+			path = append(path, fmt.Sprint(element))
+		}
+		queryErrors[strings.Join(path, ".")] = queryError.Message
+	}
+
+	for i, repo := range repos {
+		repoAlias := fmt.Sprintf("r%v", i)
+		for _, key := range byRepo[repo] {
+			issueAlias := fmt.Sprintf("i%v", key.Number)
+			issue := response.Data[repoAlias][issueAlias]
+			if issue == nil {
+				message := queryErrors[repoAlias+"."+issueAlias]
+				if message == "" {
+					message = queryErrors[repoAlias]
+				}
+				if message == "" {
+					message = "not found"
+				}
+				failures[key] = errors.New(message)
+				continue
+			}
+			found := &githubIssue{
+				Title:       issue.Title,
+				Url:         issue.Url,
+				PullRequest: issue.Typename == "PullRequest",
+				Merged:      issue.Merged,
+				Closed:      issue.State == "CLOSED" || issue.State == "MERGED",
+			}
+			for _, label := range issue.Labels.Nodes {
+				found.Labels = append(found.Labels, label.Name)
+			}
+			result[key] = found
+		}
+	}
+}
+
+// getIssue looks up an issue with the REST api, which doesn't need a token
+func (c *githubClient) getIssue(key issueKey) (*githubIssue, error) {
+	resp, err := c.do(func() (*resty.Response, error) {
+		request := c.http.R().SetHeader("Accept", "application/vnd.github+json")
+		if c.token != "" {
+			request.SetHeader("Authorization", "bearer "+c.token)
+		}
+		return request.Get(fmt.Sprintf("%v/repos/%v/issues/%v", c.apiUrl, key.Repo, key.Number))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	restIssue := &struct {
+		Title   string `json:"title"`
+		HtmlUrl string `json:"html_url"`
+		State   string `json:"state"`
+		Labels  []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest *struct {
+			MergedAt *string `json:"merged_at"`
+		} `json:"pull_request"`
+	}{}
+	if err = json.Unmarshal(resp.Body(), restIssue); err != nil {
+		return nil, networkErrorf("unable to parse issue %v: %w", key, err)
+	}
+	result := &githubIssue{
+		Title:  restIssue.Title,
+		Url:    restIssue.HtmlUrl,
+		Closed: restIssue.State == "closed",
+	}
+	for _, label := range restIssue.Labels {
+		result.Labels = append(result.Labels, label.Name)
+	}
+	if restIssue.PullRequest != nil {
+		result.PullRequest = true
+		result.Merged = restIssue.PullRequest.MergedAt != nil
+	}
+	return result, nil
+}
+
+// do sends a request, waiting out and retrying it if it's rate limited
+func (c *githubClient) do(send func() (*resty.Response, error)) (*resty.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := send()
+		if err != nil {
+			return nil, networkErrorf("GitHub request failed: %w", err)
+		}
+		if resp.StatusCode() == http.StatusOK {
+			return resp, nil
+		}
+
+		wait, limited := c.rateLimitWait(resp)
+		if !limited {
+			return nil, networkErrorf("GitHub request to %v returned %v", resp.Request.URL, resp.Status())
+		}
+		if attempt >= maxRateLimitRetries || wait > maxRateLimitWait {
+			return nil, networkErrorf("GitHub rate limit exceeded, resets in %v", wait.Round(time.Second))
+		}
+		c.sleep(wait)
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying a rate limited request, and false if the request
+// wasn't rate limited
+func (c *githubClient) rateLimitWait(resp *resty.Response) (time.Duration, bool) {
+	if resp.StatusCode() != http.StatusForbidden && resp.StatusCode() != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if retryAfter := resp.Header().Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if resp.Header().Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header().Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(c.now()), time.Second), true
+		}
+		return time.Minute, true
+	}
+	return 0, resp.StatusCode() == http.StatusTooManyRequests
+}
+
+func (c *githubClient) cacheFile(key issueKey) string {
+	return filepath.Join(c.cacheDir, filepath.FromSlash(key.Repo), strconv.Itoa(key.Number)+".json")
+}
+
+func (c *githubClient) readCache(key issueKey) *githubIssue {
+	if c.cacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cacheFile(key))
+	if err != nil {
+		return nil
+	}
+	result := &githubIssue{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// writeCache keeps closed issues, which won't change. The cache is best effort, so failures are ignored
+func (c *githubClient) writeCache(key issueKey, issue *githubIssue) {
+	if c.cacheDir == "" || !issue.Closed {
+		return
+	}
+	data, err := json.Marshal(issue)
+	if err != nil {
+		return
+	}
+	file := c.cacheFile(key)
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(file, data, 0644)
+}
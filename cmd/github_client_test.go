@@ -0,0 +1,225 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGithub serves issues from a map keyed by owner/name#number over the GraphQL and REST apis
+type fakeGithub struct {
+	issues   map[string]*githubIssue
+	requests []string
+	// rateLimited is the number of requests to answer with a rate limit error before serving
+	rateLimited int
+}
+
+func (f *fakeGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(30*time.Second).Unix()))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Path == "/graphql" {
+		body := &struct {
+			Query string `json:"query"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(body)
+		data := map[string]map[string]interface{}{}
+		var errs []interface{}
+		repoRegex := regexp.MustCompile(`(r\d+): repository\(owner: "([^"]+)", name: "([^"]+)"\)`)
+		issueRegex := regexp.MustCompile(`(i\d+): issueOrPullRequest\(number: (\d+)\)`)
+		var repoAlias, repo string
+		for _, line := range strings.Split(body.Query, "\n") {
+			if match := repoRegex.FindStringSubmatch(line); match != nil {
+				repoAlias, repo = match[1], match[2]+"/"+match[3]
+				data[repoAlias] = map[string]interface{}{}
+			} else if match := issueRegex.FindStringSubmatch(line); match != nil {
+				issue := f.issues[repo+"#"+match[2]]
+				if issue == nil {
+					data[repoAlias][match[1]] = nil
+					errs = append(errs, map[string]interface{}{"message": "Could not resolve to an issue", "path": []string{repoAlias, match[1]}})
+					continue
+				}
+				var labels []interface{}
+				for _, label := range issue.Labels {
+					labels = append(labels, map[string]string{"name": label})
+				}
+				typename, state := "Issue", "OPEN"
+				if issue.PullRequest {
+					typename = "PullRequest"
+				}
+				if issue.Closed {
+					state = "CLOSED"
+				}
+				data[repoAlias][match[1]] = map[string]interface{}{
+					"__typename": typename, "title": issue.Title, "url": issue.Url, "state": state,
+					"merged": issue.Merged, "labels": map[string]interface{}{"nodes": labels},
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "errors": errs})
+		return
+	}
+
+	match := regexp.MustCompile(`^/repos/([^/]+/[^/]+)/issues/(\d+)$`).FindStringSubmatch(r.URL.Path)
+	if match == nil || f.issues[match[1]+"#"+match[2]] == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	issue := f.issues[match[1]+"#"+match[2]]
+	result := map[string]interface{}{"title": issue.Title, "html_url": issue.Url, "state": "open"}
+	if issue.Closed {
+		result["state"] = "closed"
+	}
+	if issue.PullRequest {
+		pr := map[string]interface{}{"merged_at": nil}
+		if issue.Merged {
+			pr["merged_at"] = "2024-01-01T00:00:00Z"
+		}
+		result["pull_request"] = pr
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func newTestGithubClient(t *testing.T, server *httptest.Server, token string) (*githubClient, *[]time.Duration) {
+	t.Setenv("GITHUB_TOKEN", token)
+	t.Setenv("GH_TOKEN", "")
+	cmd := &BaseCommand{Config: defaultRepoConfig()}
+	cmd.Config.Github.ApiUrl = server.URL
+	cmd.Config.ReleaseNotes.IssueCacheDir = t.TempDir()
+	client := cmd.newGithubClient()
+	var waits []time.Duration
+	client.sleep = func(d time.Duration) {
+		waits = append(waits, d)
+	}
+	return client, &waits
+}
+
+func TestGithubClient(t *testing.T) {
+	req := require.New(t)
+
+	fake := &fakeGithub{issues: map[string]*githubIssue{
+		"openziti/ziti#1":   {Title: "Widgets", Url: "https://github.com/openziti/ziti/issues/1", Closed: true, Labels: []string{"bug"}},
+		"openziti/ziti#2":   {Title: "Add widgets", Url: "https://github.com/openziti/ziti/pull/2", PullRequest: true, Merged: true, Closed: true},
+		"openziti/edge#3":   {Title: "Open issue", Url: "https://github.com/openziti/edge/issues/3"},
+		"openziti/fabric#4": {Title: "Fabric", Url: "https://github.com/openziti/fabric/issues/4", Closed: true},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	keys := []issueKey{{"openziti/ziti", 1}, {"openziti/ziti", 2}, {"openziti/edge", 3}, {"openziti/fabric", 4}, {"openziti/edge", 99}}
+
+	// with a token, issues are looked up in batches with GraphQL
+	client, _ := newTestGithubClient(t, server, "secret")
+	client.batchSize = 3
+	issues, failures := client.lookupIssues(keys)
+	req.Equal([]string{"POST /graphql", "POST /graphql"}, fake.requests)
+	req.Len(issues, 4)
+	req.Equal("Widgets", issues[keys[0]].Title)
+	req.Equal([]string{"bug"}, issues[keys[0]].Labels)
+	req.True(issues[keys[1]].PullRequest)
+	req.True(issues[keys[1]].Merged)
+	req.Len(failures, 1)
+	req.Contains(failures[keys[4]].Error(), "Could not resolve")
+
+	// closed issues come from the cache, open ones are looked up again
+	fake.requests = nil
+	issues, _ = client.lookupIssues(keys[:4])
+	req.Len(issues, 4)
+	req.Equal([]string{"POST /graphql"}, fake.requests)
+
+	// without a token, the REST api is used, waiting out rate limits
+	client, waits := newTestGithubClient(t, server, "")
+	fake.requests = nil
+	fake.rateLimited = 1
+	issues, failures = client.lookupIssues(keys[1:3])
+	req.Empty(failures)
+	req.True(issues[keys[1]].Merged)
+	req.Equal("Open issue", issues[keys[2]].Title)
+	req.Equal([]string{"GET /repos/openziti/ziti/issues/2", "GET /repos/openziti/ziti/issues/2", "GET /repos/openziti/edge/issues/3"}, fake.requests)
+	req.Len(*waits, 1)
+	req.InDelta(30*time.Second, (*waits)[0], float64(5*time.Second))
+
+	// rate limits which don't reset are reported as failures
+	fake.rateLimited = 10
+	_, failures = client.lookupIssues(keys[2:3])
+	req.Len(failures, 1)
+	req.Equal(ExitCodeNetwork, ExitCode(failures[keys[2]]))
+	fake.rateLimited = 0
+}
+
+func TestResolveIssues(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	fake := &fakeGithub{issues: map[string]*githubIssue{
+		"openziti/ziti#12":      {Title: "Leak", Url: "https://github.com/openziti/ziti/issues/12"},
+		"openziti/ziti#7":       {Title: "Open PR", Url: "https://github.com/openziti/ziti/pull/7", PullRequest: true},
+		"openziti/ziti#8":       {Title: "Merged PR", Url: "https://github.com/openziti/ziti/pull/8", PullRequest: true, Merged: true, Labels: []string{"enhancement"}},
+		"openziti/sdk-golang#5": {Title: "Sdk issue", Url: "https://github.com/openziti/sdk-golang/issues/5"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: errOut}).newBaseCommand("test")
+	req.NoError(err)
+	cmd.Config.Github.ApiUrl = server.URL
+	cmd.Config.ReleaseNotes.IssueCacheDir = ""
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd}
+
+	commit := &ReleaseNotesCommit{Hash: "1"}
+	commit.parseMessage("Plug leak\n\nFixes #12, see #7, #8, #404 and fixes openziti/sdk-golang#5")
+	component := newComponent("github.com/openziti/ziti/v2", ComponentStatusChanged, "v0.3.0", "v0.3.1")
+	component.Current = true
+	component.Commits = []*ReleaseNotesCommit{commit}
+	commit.References, err = notesCmd.extractReferences(commit.message, component.ownRepo(), 0)
+	req.NoError(err)
+
+	notes := &ReleaseNotes{Version: "0.3.1", Components: []*ReleaseNotesComponent{component}}
+	req.NoError(notesCmd.resolveIssues(notes))
+	req.Equal([]string{"POST /graphql"}, fake.requests)
+
+	var names []string
+	for _, ref := range commit.References {
+		names = append(names, ref.Name()+" "+ref.Title)
+	}
+	// the open pull request and the missing #404 are plain mentions, so they're dropped
+	req.Equal([]string{"Issue #12 Leak", "openziti/sdk-golang#5 Sdk issue", "PR #8 Merged PR"}, names)
+	req.Equal(ReleaseNotesCategoryFeatures, commit.Category)
+	req.Equal([]string{"openziti/ziti#404"}, notes.LookupFailures)
+	// lookup failures must not end up in the notes written to stdout
+	req.Empty(out.String())
+	req.Contains(errOut.String(), "unable to look up openziti/ziti#404")
+}
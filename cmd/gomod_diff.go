@@ -0,0 +1,279 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"sort"
+	"strings"
+)
+
+const (
+	ModuleChangeAdded   = "added"
+	ModuleChangeRemoved = "removed"
+	ModuleChangeChanged = "changed"
+)
+
+// goModDiffTemplateDefinitions render a GoModDiff, and are available to all release notes templates
+const goModDiffTemplateDefinitions = `
+{{- define "moduleChange" -}}
+{{- if eq .Status "added"}}* {{.Path}}{{with .NewVersion}}: {{.}}{{end}} (added)
+{{else if eq .Status "removed"}}* {{.Path}}{{with .OldVersion}}: {{.}}{{end}} (removed)
+{{else if .CompareUrl}}* {{.Path}}: [{{.OldVersion}} -> {{.NewVersion}}]({{.CompareUrl}})
+{{else}}* {{.Path}}: {{.OldVersion}} -> {{.NewVersion}}
+{{end}}
+{{- template "vulns" .}}
+{{- end}}
+
+{{- define "goModDiff" -}}
+{{- if not .Empty}}
+## Go Module Changes
+{{if or .Go .Toolchain}}
+{{with .Go}}* go: {{or .Old "none"}} -> {{or .New "none"}}
+{{end}}{{with .Toolchain}}* toolchain: {{or .Old "none"}} -> {{or .New "none"}}
+{{end}}{{end}}
+{{- with .Direct}}
+### Direct Dependencies
+
+{{range .}}{{template "moduleChange" .}}{{end}}{{end}}
+{{- with .Indirect}}
+### Indirect Dependencies
+
+{{range .}}{{template "moduleChange" .}}{{end}}{{end}}
+{{- with .Replaced}}
+### Replaced
+
+{{range .}}{{template "moduleChange" .}}{{end}}{{end}}
+{{- with .Excluded}}
+### Excluded
+
+{{range .}}{{template "moduleChange" .}}{{end}}{{end}}
+{{- with .Retracted}}
+### Retracted
+
+{{range .}}{{template "moduleChange" .}}{{end}}{{end}}
+{{- end}}
+{{- end}}`
+
+// GoModDiff lists every difference between the go.mod files of two versions
+type GoModDiff struct {
+	Go        *DirectiveChange `json:"go,omitempty" yaml:"go,omitempty"`
+	Toolchain *DirectiveChange `json:"toolchain,omitempty" yaml:"toolchain,omitempty"`
+	Direct    []*ModuleChange  `json:"direct,omitempty" yaml:"direct,omitempty"`
+	Indirect  []*ModuleChange  `json:"indirect,omitempty" yaml:"indirect,omitempty"`
+	// Replaced lists replace directives by the module they replace. The versions are the replacements
+	Replaced []*ModuleChange `json:"replaced,omitempty" yaml:"replaced,omitempty"`
+	// Excluded lists exclude directives by module, with the excluded version
+	Excluded []*ModuleChange `json:"excluded,omitempty" yaml:"excluded,omitempty"`
+	// Retracted lists retract directives by version interval, ex: [v1.0.0, v1.0.2], with their rationale
+	Retracted []*ModuleChange `json:"retracted,omitempty" yaml:"retracted,omitempty"`
+}
+
+// DirectiveChange is the old and new value of a go.mod directive, either of which may be empty
+type DirectiveChange struct {
+	Old string `json:"old,omitempty" yaml:"old,omitempty"`
+	New string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// ModuleChange is a module, or directive, which was added, removed or changed
+type ModuleChange struct {
+	Path       string `json:"path" yaml:"path"`
+	Status     string `json:"status" yaml:"status"`
+	OldVersion string `json:"oldVersion,omitempty" yaml:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty" yaml:"newVersion,omitempty"`
+	CompareUrl string `json:"compareUrl,omitempty" yaml:"compareUrl,omitempty"`
+	// FixedVulns and OpenVulns are the advisories fixed by the change, and still affecting the new version
+	FixedVulns []*Advisory `json:"fixedVulns,omitempty" yaml:"fixedVulns,omitempty"`
+	OpenVulns  []*Advisory `json:"openVulns,omitempty" yaml:"openVulns,omitempty"`
+}
+
+// Empty returns true if the go.mod files are the same, apart from formatting and comments
+func (d *GoModDiff) Empty() bool {
+	return d.Go == nil && d.Toolchain == nil && len(d.Direct) == 0 && len(d.Indirect) == 0 &&
+		len(d.Replaced) == 0 && len(d.Excluded) == 0 && len(d.Retracted) == 0
+}
+
+// diffGoMod compares two go.mod files
+func (cmd *baseBuildReleaseNotesCmd) diffGoMod(oldGoMod, newGoMod *modfile.File) *GoModDiff {
+	result := &GoModDiff{}
+
+	var oldGo, newGo, oldToolchain, newToolchain string
+	if oldGoMod.Go != nil {
+		oldGo = oldGoMod.Go.Version
+	}
+	if newGoMod.Go != nil {
+		newGo = newGoMod.Go.Version
+	}
+	if oldGoMod.Toolchain != nil {
+		oldToolchain = oldGoMod.Toolchain.Name
+	}
+	if newGoMod.Toolchain != nil {
+		newToolchain = newGoMod.Toolchain.Name
+	}
+	if oldGo != newGo {
+		result.Go = &DirectiveChange{Old: oldGo, New: newGo}
+	}
+	if oldToolchain != newToolchain {
+		result.Toolchain = &DirectiveChange{Old: oldToolchain, New: newToolchain}
+	}
+
+	oldRequires := map[string]*modfile.Require{}
+	for _, r := range oldGoMod.Require {
+		oldRequires[r.Mod.Path] = r
+	}
+	newRequires := map[string]*modfile.Require{}
+	for _, r := range newGoMod.Require {
+		newRequires[r.Mod.Path] = r
+	}
+	addRequire := func(indirect bool, change *ModuleChange) {
+		if indirect {
+			result.Indirect = append(result.Indirect, change)
+		} else {
+			result.Direct = append(result.Direct, change)
+		}
+	}
+	for path, r := range newRequires {
+		if prev, found := oldRequires[path]; !found {
+			addRequire(r.Indirect, &ModuleChange{Path: path, Status: ModuleChangeAdded, NewVersion: r.Mod.Version})
+		} else if prev.Mod.Version != r.Mod.Version {
+			addRequire(r.Indirect, &ModuleChange{
+				Path:       path,
+				Status:     ModuleChangeChanged,
+				OldVersion: prev.Mod.Version,
+				NewVersion: r.Mod.Version,
+				CompareUrl: cmd.Config.moduleCompareUrl(path, prev.Mod.Version, r.Mod.Version),
+			})
+		}
+	}
+	for path, r := range oldRequires {
+		if _, found := newRequires[path]; !found {
+			addRequire(r.Indirect, &ModuleChange{Path: path, Status: ModuleChangeRemoved, OldVersion: r.Mod.Version})
+		}
+	}
+
+	oldReplaces := map[string]string{}
+	for _, r := range oldGoMod.Replace {
+		oldReplaces[formatModuleVersion(r.Old)] = formatModuleVersion(r.New)
+	}
+	newReplaces := map[string]string{}
+	for _, r := range newGoMod.Replace {
+		newReplaces[formatModuleVersion(r.Old)] = formatModuleVersion(r.New)
+	}
+	result.Replaced = diffDirectives(oldReplaces, newReplaces)
+
+	oldExcludes := map[string]string{}
+	for _, e := range oldGoMod.Exclude {
+		oldExcludes[formatModuleVersion(e.Mod)] = e.Mod.Version
+	}
+	newExcludes := map[string]string{}
+	for _, e := range newGoMod.Exclude {
+		newExcludes[formatModuleVersion(e.Mod)] = e.Mod.Version
+	}
+	result.Excluded = diffDirectives(oldExcludes, newExcludes)
+	for _, change := range result.Excluded {
+		change.Path = strings.Fields(change.Path)[0]
+	}
+
+	oldRetracts := map[string]string{}
+	for _, r := range oldGoMod.Retract {
+		oldRetracts[formatVersionInterval(r.VersionInterval)] = r.Rationale
+	}
+	newRetracts := map[string]string{}
+	for _, r := range newGoMod.Retract {
+		newRetracts[formatVersionInterval(r.VersionInterval)] = r.Rationale
+	}
+	result.Retracted = diffDirectives(oldRetracts, newRetracts)
+
+	for _, changes := range [][]*ModuleChange{result.Direct, result.Indirect} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Path < changes[j].Path
+		})
+	}
+	return result
+}
+
+// diffDirectives compares directives keyed by what they apply to, ex: the module a replace directive replaces, with
+// their values
+func diffDirectives(oldValues, newValues map[string]string) []*ModuleChange {
+	var result []*ModuleChange
+	for key, value := range newValues {
+		if prev, found := oldValues[key]; !found {
+			result = append(result, &ModuleChange{Path: key, Status: ModuleChangeAdded, NewVersion: value})
+		} else if prev != value {
+			result = append(result, &ModuleChange{Path: key, Status: ModuleChangeChanged, OldVersion: prev, NewVersion: value})
+		}
+	}
+	for key, value := range oldValues {
+		if _, found := newValues[key]; !found {
+			result = append(result, &ModuleChange{Path: key, Status: ModuleChangeRemoved, OldVersion: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+func formatModuleVersion(m module.Version) string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + " " + m.Version
+}
+
+func formatVersionInterval(interval modfile.VersionInterval) string {
+	if interval.Low == interval.High {
+		return interval.Low
+	}
+	return fmt.Sprintf("[%v, %v]", interval.Low, interval.High)
+}
+
+// moduleCompareUrl returns a link to the changes to a module between two versions. release-notes.module-compare-url
+// is used if set, otherwise modules hosted on github.com link to GitHub's compare page, and others to pkg.go.dev
+func (c *RepoConfig) moduleCompareUrl(modulePath, oldVersion, newVersion string) string {
+	tagPrefix := getModuleTagPrefix(modulePath)
+	url := c.ReleaseNotes.ModuleCompareUrl
+	if url == "" {
+		if strings.HasPrefix(modulePath, "github.com/") {
+			url = "https://{repo}/compare/{oldRev}...{newRev}"
+		} else {
+			url = "https://pkg.go.dev/{module}@{new}"
+		}
+	}
+	return strings.NewReplacer(
+		"{module}", modulePath,
+		"{repo}", getRepoPath(modulePath),
+		"{old}", oldVersion,
+		"{new}", newVersion,
+		"{oldRev}", moduleVersionRev(tagPrefix, oldVersion),
+		"{newRev}", moduleVersionRev(tagPrefix, newVersion),
+	).Replace(url)
+}
+
+// moduleVersionRev returns the revision of a module version in its repository: the commit hash of a
+// pseudo-version, or else the version's tag
+func moduleVersionRev(tagPrefix, version string) string {
+	if module.IsPseudoVersion(version) {
+		if rev, err := module.PseudoVersionRev(version); err == nil {
+			return rev
+		}
+	}
+	return tagPrefix + strings.TrimSuffix(version, "+incompatible")
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+	"testing"
+)
+
+func TestDiffGoMod(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	oldGoMod, err := modfile.Parse("old/go.mod", []byte(`module github.com/openziti/ziti
+
+go 1.21
+
+require (
+	github.com/openziti/edge v0.1.0
+	github.com/spf13/cobra v1.7.0
+	golang.org/x/sys v0.10.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace github.com/openziti/edge => ../edge
+
+exclude golang.org/x/net v0.1.0
+`), nil)
+	req.NoError(err)
+	newGoMod, err := modfile.Parse("new/go.mod", []byte(`module github.com/openziti/ziti
+
+go 1.22.0
+
+toolchain go1.22.3
+
+require (
+	github.com/openziti/edge/v2 v2.0.0
+	github.com/spf13/cobra v1.8.0
+	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/text v0.0.0-20240101000000-0123456789ab // indirect
+)
+
+replace github.com/openziti/edge => ../edge-fork
+
+retract [v0.1.0, v0.1.2] // published by mistake
+`), nil)
+	req.NoError(err)
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base}
+	diff := cmd.diffGoMod(oldGoMod, newGoMod)
+	req.False(diff.Empty())
+	req.True(cmd.diffGoMod(oldGoMod, oldGoMod).Empty())
+
+	notes := &ReleaseNotes{Version: "0.4.0", PreviousVersion: "0.3.0", GoMod: diff}
+	cmd.quiet = true
+	req.NoError(cmd.writeMarkdown(out, notes, defaultReleaseNotesTemplate))
+	req.Equal("\n## Go Module Changes\n\n"+
+		"* go: 1.21 -> 1.22.0\n"+
+		"* toolchain: none -> go1.22.3\n\n"+
+		"### Direct Dependencies\n\n"+
+		"* github.com/openziti/edge: v0.1.0 (removed)\n"+
+		"* github.com/openziti/edge/v2: v2.0.0 (added)\n"+
+		"* github.com/spf13/cobra: [v1.7.0 -> v1.8.0](https://github.com/spf13/cobra/compare/v1.7.0...v1.8.0)\n\n"+
+		"### Indirect Dependencies\n\n"+
+		"* golang.org/x/sys: [v0.10.0 -> v0.12.0](https://pkg.go.dev/golang.org/x/sys@v0.12.0)\n"+
+		"* golang.org/x/text: v0.0.0-20240101000000-0123456789ab (added)\n"+
+		"* gopkg.in/yaml.v2: v2.4.0 (removed)\n\n"+
+		"### Replaced\n\n"+
+		"* github.com/openziti/edge: ../edge -> ../edge-fork\n\n"+
+		"### Excluded\n\n"+
+		"* golang.org/x/net: v0.1.0 (removed)\n\n"+
+		"### Retracted\n\n"+
+		"* [v0.1.0, v0.1.2]: published by mistake (added)\n", out.String())
+}
+
+func TestModuleCompareUrl(t *testing.T) {
+	req := require.New(t)
+	config := defaultRepoConfig()
+
+	req.Equal("https://github.com/openziti/edge/compare/sdk/v0.1.0...sdk/v0.2.0",
+		config.moduleCompareUrl("github.com/openziti/edge/sdk", "v0.1.0", "v0.2.0"))
+	req.Equal("https://github.com/openziti/edge/compare/0123456789ab...v2.1.0",
+		config.moduleCompareUrl("github.com/openziti/edge/v2", "v2.0.1-0.20240101000000-0123456789ab", "v2.1.0"))
+	req.Equal("https://pkg.go.dev/golang.org/x/sys@v0.12.0", config.moduleCompareUrl("golang.org/x/sys", "v0.10.0", "v0.12.0"))
+
+	config.ReleaseNotes.ModuleCompareUrl = "https://sourcegraph.example.com/{repo}/-/compare/{oldRev}...{newRev}?module={module}&old={old}"
+	req.Equal("https://sourcegraph.example.com/golang.org/x/sys/-/compare/v0.10.0...v0.12.0?module=golang.org/x/sys&old=v0.10.0",
+		config.moduleCompareUrl("golang.org/x/sys", "v0.10.0", "v0.12.0"))
+}
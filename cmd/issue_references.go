@@ -0,0 +1,169 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReferenceKindIssue       = "issue"
+	ReferenceKindPullRequest = "pull-request"
+	ReferenceKindTracker     = "tracker"
+)
+
+var closingReferenceRegex = regexp.MustCompile(`(?i)\b(?:fix(?:e[sd])?|close[sd]?|resolve[sd]?):?\s*([\w.-]+/[\w.-]+)?#(\d+)\b`)
+var repoReferenceRegex = regexp.MustCompile(`(?:^|[^\w/.-])([\w.-]+/[\w.-]+)#(\d+)\b`)
+var plainReferenceRegex = regexp.MustCompile(`(?:^|[^\w/&#])#(\d+)\b`)
+var coAuthorRegex = regexp.MustCompile(`(?mi)^co-authored-by:\s*(.*?)\s*<([^>]+)>\s*$`)
+
+// ReleaseNotesReference is an issue, pull request or issue tracker entry referenced by a commit
+type ReleaseNotesReference struct {
+	Kind string `json:"kind" yaml:"kind"`
+	// Repo is the owner/name of the GitHub repository of the issue or pull request, and is empty for the
+	// repository of the component
+	Repo   string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Number int    `json:"number,omitempty" yaml:"number,omitempty"`
+	// Tracker and Key identify an entry of an issue tracker configured in release-notes.trackers, ex: ZITI-1234
+	Tracker string `json:"tracker,omitempty" yaml:"tracker,omitempty"`
+	Key     string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Closes is set if the commit fixes, closes or resolves the issue
+	Closes bool   `json:"closes,omitempty" yaml:"closes,omitempty"`
+	Title  string `json:"title,omitempty" yaml:"title,omitempty"`
+	Url    string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// mention is set for plain #N references, which are only kept if they're merged pull requests
+	mention bool
+}
+
+// Name returns the link text of the reference, ex: Issue #12, PR #3 or openziti/sdk-golang#123
+func (r *ReleaseNotesReference) Name() string {
+	switch {
+	case r.Kind == ReferenceKindTracker:
+		return r.Key
+	case r.Repo != "":
+		return fmt.Sprintf("%v#%v", r.Repo, r.Number)
+	case r.Kind == ReferenceKindPullRequest:
+		return fmt.Sprintf("PR #%v", r.Number)
+	}
+	return fmt.Sprintf("Issue #%v", r.Number)
+}
+
+// ReleaseNotesAuthor is a co-author of a commit, from its Co-authored-by trailers
+type ReleaseNotesAuthor struct {
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email" yaml:"email"`
+}
+
+// extractReferences returns the issues, pull requests and tracker entries the commit message refers to, in order
+// of appearance. ownRepo is the owner/name of the commit's repository, references to which get an empty Repo
+func (cmd *baseBuildReleaseNotesCmd) extractReferences(message, ownRepo string, pullRequest int) ([]*ReleaseNotesReference, error) {
+	var result []*ReleaseNotesReference
+	add := func(ref *ReleaseNotesReference) {
+		if strings.EqualFold(ref.Repo, ownRepo) {
+			ref.Repo = ""
+		}
+		if ref.Kind != ReferenceKindTracker && ref.Repo == "" && ref.Number == pullRequest {
+			return
+		}
+		for _, existing := range result {
+			if existing.Kind == ReferenceKindTracker || ref.Kind == ReferenceKindTracker {
+				if existing.Key != "" && existing.Key == ref.Key && existing.Tracker == ref.Tracker {
+					return
+				}
+				continue
+			}
+			if strings.EqualFold(existing.Repo, ref.Repo) && existing.Number == ref.Number {
+				existing.Closes = existing.Closes || ref.Closes
+				existing.mention = existing.mention && ref.mention
+				if existing.Url == "" {
+					existing.Kind, existing.Url = ref.Kind, ref.Url
+				}
+				return
+			}
+		}
+		result = append(result, ref)
+	}
+
+	for _, match := range closingReferenceRegex.FindAllStringSubmatch(message, -1) {
+		number, _ := strconv.Atoi(match[2])
+		add(&ReleaseNotesReference{Kind: ReferenceKindIssue, Repo: match[1], Number: number, Closes: true})
+	}
+
+	if host, err := url.Parse(cmd.Config.Github.Url); err == nil && host.Host != "" {
+		urlRegex := regexp.MustCompile(`https?://` + regexp.QuoteMeta(host.Host) + `/([\w.-]+/[\w.-]+)/(issues|pull)/(\d+)\b`)
+		for _, match := range urlRegex.FindAllStringSubmatch(message, -1) {
+			number, _ := strconv.Atoi(match[3])
+			kind := ReferenceKindIssue
+			if match[2] == "pull" {
+				kind = ReferenceKindPullRequest
+			}
+			add(&ReleaseNotesReference{Kind: kind, Repo: match[1], Number: number, Url: match[0]})
+		}
+	}
+
+	for _, match := range repoReferenceRegex.FindAllStringSubmatch(message, -1) {
+		number, _ := strconv.Atoi(match[2])
+		add(&ReleaseNotesReference{Kind: ReferenceKindIssue, Repo: match[1], Number: number})
+	}
+
+	for _, match := range plainReferenceRegex.FindAllStringSubmatch(message, -1) {
+		number, _ := strconv.Atoi(match[1])
+		add(&ReleaseNotesReference{Kind: ReferenceKindIssue, Number: number, mention: true})
+	}
+
+	for _, tracker := range cmd.Config.ReleaseNotes.Trackers {
+		pattern, err := regexp.Compile(tracker.Pattern)
+		if err != nil {
+			return nil, configErrorf("invalid pattern %v for issue tracker %v: %w", tracker.Pattern, tracker.Name, err)
+		}
+		for _, key := range pattern.FindAllString(message, -1) {
+			add(&ReleaseNotesReference{
+				Kind:    ReferenceKindTracker,
+				Tracker: tracker.Name,
+				Key:     key,
+				Url:     strings.ReplaceAll(tracker.Url, "{key}", key),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// extractCoAuthors returns the authors named in the Co-authored-by trailers of the commit message
+func extractCoAuthors(message string) []*ReleaseNotesAuthor {
+	var result []*ReleaseNotesAuthor
+	for _, match := range coAuthorRegex.FindAllStringSubmatch(message, -1) {
+		result = append(result, &ReleaseNotesAuthor{Name: match[1], Email: match[2]})
+	}
+	return result
+}
+
+// getOwnerRepo returns the owner/name of the GitHub repository at the given repository path, ex: openziti/edge
+// for github.com/openziti/edge
+func getOwnerRepo(repoPath string) string {
+	parts := strings.Split(repoPath, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1] + "/" + parts[2]
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestExtractReferences(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	cmd.Config.ReleaseNotes.Trackers = []TrackerConfig{
+		{Name: "jira", Pattern: `\bZITI-\d+\b`, Url: "https://jira.example.com/browse/{key}"},
+	}
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd}
+
+	message := "Speed up widgets (#41)\n\n" +
+		"Fixes #12 and closes openziti/sdk-golang#123. See #7, openziti/edge#5,\n" +
+		"https://github.com/openziti/fabric/pull/9 and ZITI-1234.\n" +
+		"Also fixes openziti/edge#5 and ZITI-1234.\n\n" +
+		"Co-authored-by: Jane Doe <jane@example.com>\n" +
+		"co-authored-by: John Roe <john@example.com>\n"
+
+	refs, err := notesCmd.extractReferences(message, "openziti/edge", 41)
+	req.NoError(err)
+
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name())
+	}
+	req.Equal([]string{"Issue #12", "openziti/sdk-golang#123", "Issue #5", "openziti/fabric#9", "Issue #7", "ZITI-1234"}, names)
+
+	req.True(refs[0].Closes)
+	req.True(refs[1].Closes)
+	// references to the component's own repository lose the repo
+	req.Equal("", refs[2].Repo)
+	req.True(refs[2].Closes)
+	req.Equal(ReferenceKindPullRequest, refs[3].Kind)
+	req.Equal("https://github.com/openziti/fabric/pull/9", refs[3].Url)
+	req.True(refs[4].mention)
+	req.Equal(ReferenceKindTracker, refs[5].Kind)
+	req.Equal("https://jira.example.com/browse/ZITI-1234", refs[5].Url)
+
+	req.Equal([]*ReleaseNotesAuthor{{Name: "Jane Doe", Email: "jane@example.com"}, {Name: "John Roe", Email: "john@example.com"}},
+		extractCoAuthors(message))
+
+	notesCmd.Config.ReleaseNotes.Trackers = []TrackerConfig{{Name: "broken", Pattern: "("}}
+	_, err = notesCmd.extractReferences(message, "", 0)
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+}
+
+func TestExtractClosingReferences(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd}
+
+	// closing returns the numbers of the issues in the commit's own repository which the message closes
+	closing := func(message string) []string {
+		refs, err := notesCmd.extractReferences(message, "openziti/edge", 0)
+		req.NoError(err)
+		var result []string
+		for _, ref := range refs {
+			if ref.Closes && ref.Repo == "" {
+				result = append(result, strconv.Itoa(ref.Number))
+			}
+		}
+		return result
+	}
+	a := func(s ...string) []string {
+		return s
+	}
+	req.Equal(a("10"), closing("Fixes #10"))
+	req.Equal(a("12"), closing("This commit fixed #12"))
+	req.Equal(a("13", "521"), closing("This commit fix #13 and FiXed #521"))
+	req.Equal(a("20", "10", "5"), closing("This commit fixes #20, closes #10 and resolves #5"))
+	req.Equal(a("20", "10", "5"), closing("This commit fix #20, close #10 and resolve #5"))
+	req.Equal(a("20", "10", "5"), closing("This commit fixed #20, closed #10 and resolved #5"))
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type goModuleInfo struct {
+	Dir       string
+	Path      string
+	TagPrefix string
+}
+
+// findModules returns the go modules found under the given root directory. Vendor, testdata and hidden
+// directories are skipped, as the go tool ignores them as well
+func findModules(root string) ([]*goModuleInfo, error) {
+	var result []*goModuleInfo
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		modulePath := modfile.ModulePath(data)
+		if modulePath == "" {
+			return fmt.Errorf("no module path found in %v", path)
+		}
+
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		dir = filepath.ToSlash(dir)
+
+		result = append(result, &goModuleInfo{
+			Dir:       dir,
+			Path:      modulePath,
+			TagPrefix: moduleTagPrefix(dir),
+		})
+		return nil
+	})
+	return result, err
+}
+
+type listModulesCmd struct {
+	BaseCommand
+}
+
+func (cmd *listModulesCmd) Execute() error {
+	root := "."
+	if len(cmd.Args) > 0 {
+		root = cmd.Args[0]
+	}
+
+	modules, err := findModules(root)
+	if err != nil {
+		return fmt.Errorf("unable to find go modules under %v: %w", root, err)
+	}
+
+	for _, module := range modules {
+		fmt.Printf("%v\t%v\t%v\n", module.Dir, module.Path, module.TagPrefix)
+	}
+	return nil
+}
+
+func newListModulesCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "list-modules [dir]",
+		Short: "Lists the go modules in the repository, with the directory and tag prefix to use with --module-dir",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	result := &listModulesCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	return FinalizeErroringCmd(result)
+}
@@ -25,8 +25,8 @@ type packageCmd struct {
 	BaseCommand
 }
 
-func (cmd *packageCmd) Execute() {
-	cmd.tarGzSimple(cmd.Args[0], cmd.Args[1:]...)
+func (cmd *packageCmd) Execute() error {
+	return cmd.tarGzSimple(cmd.Args[0], cmd.Args[1:]...)
 }
 
 func newPackageCmd(root *RootCommand) *cobra.Command {
@@ -43,5 +43,5 @@ func newPackageCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -40,7 +40,7 @@ type githubArtifact struct {
 	os         string
 }
 
-func (cmd *publishToGithubCmd) Execute() {
+func (cmd *publishToGithubCmd) Execute() error {
 	cmd.name = "ziti"
 	if len(cmd.Args) > 0 {
 		cmd.name = cmd.Args[0]
@@ -50,13 +50,19 @@ func (cmd *publishToGithubCmd) Execute() {
 		cmd.archiveBase = cmd.name
 	}
 
-	cmd.EvalCurrentAndNextVersion()
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	releaseDir, err := filepath.Abs("./release")
-	cmd.exitIfErrf(err, "could not get absolute path for releases directory")
+	if err != nil {
+		return fmt.Errorf("could not get absolute path for releases directory: %w", err)
+	}
 
 	archDirs, err := os.ReadDir(releaseDir)
-	cmd.exitIfErrf(err, "failed to read releases dir: %v\n", err)
+	if err != nil {
+		return fmt.Errorf("failed to read releases dir: %w", err)
+	}
 	var artifacts []*githubArtifact
 	for _, archDir := range archDirs {
 		arch := archDir.Name()
@@ -65,7 +71,9 @@ func (cmd *publishToGithubCmd) Execute() {
 
 		if archDir.IsDir() {
 			osDirs, err := os.ReadDir(archDirPath)
-			cmd.exitIfErrf(err, "failed to read arch dir %v: %v\n", archDirPath, err)
+			if err != nil {
+				return fmt.Errorf("failed to read arch dir %v: %w", archDirPath, err)
+			}
 
 			for _, osDir := range osDirs {
 				osName := osDir.Name()
@@ -73,7 +81,9 @@ func (cmd *publishToGithubCmd) Execute() {
 
 				osDirPath := filepath.Join(archDirPath, osDir.Name())
 				releasableFiles, err := os.ReadDir(osDirPath)
-				cmd.exitIfErrf(err, "failed to read os dir %v: %v\n", osDirPath, err)
+				if err != nil {
+					return fmt.Errorf("failed to read os dir %v: %w", osDirPath, err)
+				}
 
 				for _, releasableFile := range releasableFiles {
 					if !releasableFile.IsDir() && !strings.HasSuffix(releasableFile.Name(), ".gz") {
@@ -112,26 +122,29 @@ func (cmd *publishToGithubCmd) Execute() {
 		if strings.Contains(k, "windows") {
 			file := fmt.Sprintf("release/%v-%v-%v.zip", cmd.name, k, version)
 			cmd.Infof("Creating release archive %v\n", file)
-			cmd.zipGhArtifacts(cmd.name, file, v...)
+			if err = cmd.zipGhArtifacts(cmd.name, file, v...); err != nil {
+				return err
+			}
 			releaseArtifacts = append(releaseArtifacts, file)
 		} else {
 			file := fmt.Sprintf("release/%v-%v-%v.tar.gz", cmd.name, k, version)
 			cmd.Infof("Creating release archive %v\n", file)
-			cmd.tarGzGhArtifacts(cmd.name, file, v...)
+			if err = cmd.tarGzGhArtifacts(cmd.name, file, v...); err != nil {
+				return err
+			}
 			releaseArtifacts = append(releaseArtifacts, file)
 		}
 	}
 
 	releaseNotesFile := fmt.Sprintf("changelog-%v.md", version)
-	extractReleaseNotes("CHANGELOG.md", version, releaseNotesFile)
-
-	tagName := version
-	if cmd.isGoLang() {
-		tagName = "v" + version
+	if err = cmd.extractReleaseNotes("CHANGELOG.md", version, releaseNotesFile, false, ReleaseNotesSectionFormatText); err != nil {
+		return err
 	}
+
+	tagName := cmd.getVersionTag(cmd.getPublishVersion())
 	releaseParams := []string{"release", "create", tagName, "-F", releaseNotesFile, "--title", tagName}
 
-	if cmd.preRelease {
+	if cmd.preRelease || cmd.getPublishVersion().Prerelease() != "" {
 		releaseParams = append(releaseParams, "--prerelease")
 	}
 
@@ -141,8 +154,11 @@ func (cmd *publishToGithubCmd) Execute() {
 	}
 
 	if !cmd.dryRun {
-		cmd.runCommand("Create GH Release and publish release artifacts", "gh", releaseParams...)
+		if err = cmd.runCommand("Create GH Release and publish release artifacts", "gh", releaseParams...); err != nil {
+			return newCiError(ErrorKindNetwork, err)
+		}
 	}
+	return nil
 }
 
 func newPublishToGithubCmd(root *RootCommand) *cobra.Command {
@@ -161,5 +177,6 @@ func newPublishToGithubCmd(root *RootCommand) *cobra.Command {
 
 	cobraCmd.Flags().StringVar(&result.archiveBase, "archive-base", "", "Directory to store release files in archives defaults to project name if not specified. May be set to blank.")
 	cobraCmd.Flags().BoolVarP(&result.preRelease, "prerelease", "p", false, "Publish as pre-release")
-	return Finalize(result)
+	_ = cobraCmd.Flags().MarkDeprecated("prerelease", "versions with a pre-release part, ex: v1.2.0-rc.1, are now published as pre-releases automatically")
+	return FinalizeErroringCmd(result)
 }
@@ -0,0 +1,354 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"sync"
+)
+
+const (
+	ReleaseNotesFormatMarkdown = "markdown"
+	ReleaseNotesFormatJson     = "json"
+	ReleaseNotesFormatYaml     = "yaml"
+
+	ComponentStatusNew       = "new"
+	ComponentStatusChanged   = "changed"
+	ComponentStatusUnchanged = "unchanged"
+)
+
+// ReleaseNotes holds the content of the release notes for a version, independent of how they're presented
+type ReleaseNotes struct {
+	Version         string                   `json:"version" yaml:"version"`
+	PreviousVersion string                   `json:"previousVersion,omitempty" yaml:"previousVersion,omitempty"`
+	Components      []*ReleaseNotesComponent `json:"components" yaml:"components"`
+	// GoMod is every change to the go.mod of the module being released, if requested
+	GoMod *GoModDiff `json:"goMod,omitempty" yaml:"goMod,omitempty"`
+	// Api is the changes to the exported API of the module being released, if requested
+	Api *ApiDiff `json:"api,omitempty" yaml:"api,omitempty"`
+	// Contributors are the authors of the changes to the module being released, if requested
+	Contributors []*ReleaseNotesContributor `json:"contributors,omitempty" yaml:"contributors,omitempty"`
+	// LookupFailures are the issue references which couldn't be looked up on GitHub
+	LookupFailures []string `json:"lookupFailures,omitempty" yaml:"lookupFailures,omitempty"`
+}
+
+// ReleaseNotesComponent is the module being released or one of its dependencies
+type ReleaseNotesComponent struct {
+	Module string `json:"module" yaml:"module"`
+	// Current is set for the module being released
+	Current    bool                  `json:"current,omitempty" yaml:"current,omitempty"`
+	Status     string                `json:"status" yaml:"status"`
+	OldVersion string                `json:"oldVersion,omitempty" yaml:"oldVersion,omitempty"`
+	NewVersion string                `json:"newVersion" yaml:"newVersion"`
+	CompareUrl string                `json:"compareUrl,omitempty" yaml:"compareUrl,omitempty"`
+	Commits    []*ReleaseNotesCommit `json:"commits,omitempty" yaml:"commits,omitempty"`
+	// Truncated is set if there were more than --max-commits commits, and only the newest of them are listed
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+	// Issues are the issues and pull requests referenced by the commits, as looked up on GitHub
+	Issues []*ReleaseNotesIssue `json:"issues,omitempty" yaml:"issues,omitempty"`
+	// FixedVulns are the advisories affecting the old version but not the new one
+	FixedVulns []*Advisory `json:"fixedVulns,omitempty" yaml:"fixedVulns,omitempty"`
+	// OpenVulns are the advisories still affecting the new version
+	OpenVulns []*Advisory `json:"openVulns,omitempty" yaml:"openVulns,omitempty"`
+
+	changes *changeRange
+}
+
+// changeRange identifies the commits to collect for a component
+type changeRange struct {
+	// repoPath identifies the repository of a dependency, and is empty for the repository being built
+	repoPath   string
+	modulePath string
+	oldRev     string
+	newRev     string
+}
+
+type ReleaseNotesCommit struct {
+	Hash        string `json:"hash" yaml:"hash"`
+	Subject     string `json:"subject" yaml:"subject"`
+	Author      string `json:"author" yaml:"author"`
+	AuthorEmail string `json:"authorEmail" yaml:"authorEmail"`
+	// References are the issues, pull requests and issue tracker entries the commit message refers to
+	References []*ReleaseNotesReference `json:"references,omitempty" yaml:"references,omitempty"`
+	CoAuthors  []*ReleaseNotesAuthor    `json:"coAuthors,omitempty" yaml:"coAuthors,omitempty"`
+	// Type, Scope and Breaking come from the conventional commit header, or the pull request title
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Scope    string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Breaking bool   `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	// BreakingChange is the text of the commit's BREAKING CHANGE footer
+	BreakingChange string `json:"breakingChange,omitempty" yaml:"breakingChange,omitempty"`
+	// PullRequest is the number of the pull request the commit was squash-merged from, ex: Add widgets (#12)
+	PullRequest int `json:"pullRequest,omitempty" yaml:"pullRequest,omitempty"`
+	// Title is the entry text: the subject, or pull request title, without conventional commit type and pull
+	// request number
+	Title    string `json:"title" yaml:"title"`
+	Category string `json:"category" yaml:"category"`
+
+	message string
+}
+
+// ReleaseNotesIssue is an issue or pull request referenced by a commit. Title and Url are empty if it couldn't be
+// looked up
+type ReleaseNotesIssue struct {
+	// Repo is the owner/name of the repository, and is empty for the repository of the component
+	Repo        string   `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Number      int      `json:"number" yaml:"number"`
+	PullRequest bool     `json:"pullRequest,omitempty" yaml:"pullRequest,omitempty"`
+	Merged      bool     `json:"merged,omitempty" yaml:"merged,omitempty"`
+	Title       string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Url         string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Labels      []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+func newReleaseNotes(r *releaseRange) *ReleaseNotes {
+	return &ReleaseNotes{Version: r.version, PreviousVersion: r.previousVersion}
+}
+
+func newComponent(module, status, oldVersion, newVersion string) *ReleaseNotesComponent {
+	return &ReleaseNotesComponent{Module: module, Status: status, OldVersion: oldVersion, NewVersion: newVersion}
+}
+
+// setChanges marks the component for collection of the commits between the given revisions of the repository
+// holding modulePath. An empty modulePath selects the repository being built
+func (c *ReleaseNotesComponent) setChanges(modulePath, oldRev, newRev string) {
+	c.changes = &changeRange{modulePath: modulePath, oldRev: oldRev, newRev: newRev}
+	if modulePath != "" {
+		c.changes.repoPath = getRepoPath(modulePath)
+	}
+}
+
+// getIssue returns the issue or pull request with the given number in the component's repository
+func (c *ReleaseNotesComponent) getIssue(number int) *ReleaseNotesIssue {
+	return c.getRepoIssue("", number)
+}
+
+func (c *ReleaseNotesComponent) getRepoIssue(repo string, number int) *ReleaseNotesIssue {
+	for _, issue := range c.Issues {
+		if strings.EqualFold(issue.Repo, repo) && issue.Number == number {
+			return issue
+		}
+	}
+	return nil
+}
+
+// ownRepo returns the owner/name of the component's GitHub repository, ex: openziti/edge
+func (c *ReleaseNotesComponent) ownRepo() string {
+	return getOwnerRepo(getRepoPath(c.Module))
+}
+
+func (cmd *baseBuildReleaseNotesCmd) validateFormat() error {
+	switch cmd.Format {
+	case ReleaseNotesFormatMarkdown, ReleaseNotesFormatJson, ReleaseNotesFormatYaml:
+		return nil
+	}
+	return configErrorf("unsupported format: '%v'. Valid values: [%v,%v,%v]", cmd.Format,
+		ReleaseNotesFormatMarkdown, ReleaseNotesFormatJson, ReleaseNotesFormatYaml)
+}
+
+// collectChanges fills in the commits and issues of the components, up to Concurrency at a time. Errors are
+// reported for the first failing component, in order, so results don't depend on scheduling
+func (cmd *baseBuildReleaseNotesCmd) collectChanges(notes *ReleaseNotes) error {
+	errs := make([]error, len(notes.Components))
+	queue := make(chan int)
+	wg := sync.WaitGroup{}
+
+	workers := cmd.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				errs[idx] = cmd.collectComponentChanges(notes.Components[idx])
+			}
+		}()
+	}
+	for idx, component := range notes.Components {
+		if component.changes != nil {
+			queue <- idx
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return cmd.resolveIssues(notes)
+}
+
+func (cmd *baseBuildReleaseNotesCmd) collectComponentChanges(component *ReleaseNotesComponent) error {
+	changes := component.changes
+	var r *git.Repository
+	var err error
+	if changes.modulePath == "" {
+		r, err = cmd.openCurrentRepo()
+	} else {
+		r, err = cmd.openDependencyRepo(changes.modulePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	commits, truncated, err := cmd.getChanges(r, changes.repoPath, changes.oldRev, changes.newRev)
+	if err != nil {
+		return err
+	}
+	component.Commits = commits
+	component.Truncated = truncated
+
+	for _, commit := range commits {
+		if commit.References, err = cmd.extractReferences(commit.message, component.ownRepo(), commit.PullRequest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveIssues looks up the GitHub issues and pull requests referenced by the commits of all components at once,
+// links the references and categorizes the commits. Lookups which fail are reported, and the references are
+// left without a link
+func (cmd *baseBuildReleaseNotesCmd) resolveIssues(notes *ReleaseNotes) error {
+	var keys []issueKey
+	seen := map[issueKey]bool{}
+	addKey := func(key issueKey) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	keyOf := func(component *ReleaseNotesComponent, repo string, number int) issueKey {
+		if repo == "" {
+			repo = component.ownRepo()
+		}
+		return issueKey{Repo: repo, Number: number}
+	}
+
+	for _, component := range notes.Components {
+		for _, commit := range component.Commits {
+			if cmd.Categorized && commit.PullRequest != 0 {
+				addKey(keyOf(component, "", commit.PullRequest))
+			}
+			for _, ref := range commit.References {
+				if ref.Kind != ReferenceKindTracker {
+					addKey(keyOf(component, ref.Repo, ref.Number))
+				}
+			}
+		}
+	}
+
+	var issues map[issueKey]*githubIssue
+	if len(keys) > 0 {
+		var failures map[issueKey]error
+		issues, failures = cmd.newGithubClient().lookupIssues(keys)
+		for _, key := range keys {
+			if err, failed := failures[key]; failed {
+				cmd.Warnf("unable to look up %v: %v\n", key, err)
+				notes.LookupFailures = append(notes.LookupFailures, key.String())
+			}
+		}
+		if len(failures) > 0 {
+			cmd.Warnf("%v of %v issue lookups failed\n", len(failures), len(keys))
+		}
+	}
+
+	// getIssue returns the component's record of the issue, adding it if it's new
+	getIssue := func(component *ReleaseNotesComponent, repo string, number int) *ReleaseNotesIssue {
+		if issue := component.getRepoIssue(repo, number); issue != nil {
+			return issue
+		}
+		result := &ReleaseNotesIssue{Repo: repo, Number: number}
+		if found := issues[keyOf(component, repo, number)]; found != nil {
+			result.Title = found.Title
+			result.Url = found.Url
+			result.Labels = found.Labels
+			result.PullRequest = found.PullRequest
+			result.Merged = found.Merged
+		}
+		component.Issues = append(component.Issues, result)
+		return result
+	}
+
+	for _, component := range notes.Components {
+		for _, commit := range component.Commits {
+			var labels []string
+			if cmd.Categorized && commit.PullRequest != 0 {
+				pr := getIssue(component, "", commit.PullRequest)
+				commit.applyPullRequest(pr)
+				labels = append(labels, pr.Labels...)
+			}
+
+			var references []*ReleaseNotesReference
+			for _, ref := range commit.References {
+				if ref.Kind == ReferenceKindTracker {
+					references = append(references, ref)
+					continue
+				}
+				issue := getIssue(component, ref.Repo, ref.Number)
+				// plain #N references are only links if they're to merged pull requests
+				if ref.mention && !issue.Merged {
+					continue
+				}
+				if issue.PullRequest {
+					ref.Kind = ReferenceKindPullRequest
+				}
+				ref.Title = issue.Title
+				if issue.Url != "" {
+					ref.Url = issue.Url
+				}
+				references = append(references, ref)
+				labels = append(labels, issue.Labels...)
+			}
+			commit.References = references
+			commit.Category = categorizeCommit(commit, labels)
+		}
+	}
+	return nil
+}
+
+// outputReleaseNotes writes the release notes in the selected format. Markdown is rendered with the template
+// given with --template, or else the given built-in template
+func (cmd *baseBuildReleaseNotesCmd) outputReleaseNotes(notes *ReleaseNotes, builtInTemplate string) error {
+	out := cmd.Cmd.OutOrStdout()
+	switch cmd.Format {
+	case ReleaseNotesFormatJson:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(notes); err != nil {
+			return fmt.Errorf("unable to write release notes as json: %w", err)
+		}
+	case ReleaseNotesFormatYaml:
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(notes); err != nil {
+			return fmt.Errorf("unable to write release notes as yaml: %w", err)
+		}
+		return encoder.Close()
+	default:
+		return cmd.writeMarkdown(out, notes, builtInTemplate)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReleaseNotesCategoryFeatures     = "Features"
+	ReleaseNotesCategoryFixes        = "Fixes"
+	ReleaseNotesCategoryPerformance  = "Performance"
+	ReleaseNotesCategoryDependencies = "Dependencies"
+	ReleaseNotesCategoryOther        = "Other"
+)
+
+// releaseNotesCategories lists the categories in the order they're presented
+var releaseNotesCategories = []string{
+	ReleaseNotesCategoryFeatures, ReleaseNotesCategoryFixes, ReleaseNotesCategoryPerformance,
+	ReleaseNotesCategoryDependencies, ReleaseNotesCategoryOther,
+}
+
+// categoryTypes maps conventional commit types to categories
+var categoryTypes = map[string]string{
+	"feat": ReleaseNotesCategoryFeatures,
+	"fix":  ReleaseNotesCategoryFixes,
+	"perf": ReleaseNotesCategoryPerformance,
+	"deps": ReleaseNotesCategoryDependencies,
+}
+
+// categoryLabels maps GitHub issue and pull request labels to categories, for commits without a conventional type
+var categoryLabels = map[string]string{
+	"enhancement":  ReleaseNotesCategoryFeatures,
+	"feature":      ReleaseNotesCategoryFeatures,
+	"bug":          ReleaseNotesCategoryFixes,
+	"performance":  ReleaseNotesCategoryPerformance,
+	"dependencies": ReleaseNotesCategoryDependencies,
+}
+
+var breakingLabels = []string{"breaking", "breaking-change", "breaking change"}
+
+// squashMergeRegex matches the pull request number GitHub appends to the subject of squash-merged commits
+var squashMergeRegex = regexp.MustCompile(`\s*\(#(\d+)\)$`)
+
+// parseMessage sets the conventional commit details, pull request number, title and co-authors from the commit
+// message
+func (c *ReleaseNotesCommit) parseMessage(message string) {
+	c.message = message
+	c.CoAuthors = extractCoAuthors(message)
+	subject := strings.TrimSpace(strings.Split(message, "\n")[0])
+	if match := squashMergeRegex.FindStringSubmatch(subject); match != nil {
+		c.PullRequest, _ = strconv.Atoi(match[1])
+		subject = strings.TrimSpace(strings.TrimSuffix(subject, match[0]))
+	}
+	c.Title = subject
+	c.applyConventionalHeader(subject)
+	if match := breakingChangeFooterRegex.FindStringSubmatch(message); match != nil {
+		c.Breaking = true
+		c.BreakingChange = strings.TrimSpace(match[1])
+	}
+}
+
+func (c *ReleaseNotesCommit) applyConventionalHeader(header string) {
+	if cc := parseConventionalCommit(header); cc != nil {
+		c.Type = cc.Type
+		c.Scope = cc.Scope
+		c.Breaking = c.Breaking || cc.Breaking
+		c.Title = cc.Subject
+	}
+}
+
+// applyPullRequest uses the title of the pull request the commit was squash-merged from as the entry text, along
+// with its conventional commit type if the commit doesn't have one
+func (c *ReleaseNotesCommit) applyPullRequest(pr *ReleaseNotesIssue) {
+	if pr.Title == "" {
+		return
+	}
+	commitType, scope := c.Type, c.Scope
+	c.Title = strings.TrimSpace(squashMergeRegex.ReplaceAllString(pr.Title, ""))
+	c.applyConventionalHeader(c.Title)
+	if commitType != "" {
+		c.Type, c.Scope = commitType, scope
+	}
+	for _, label := range pr.Labels {
+		for _, breaking := range breakingLabels {
+			if strings.EqualFold(label, breaking) {
+				c.Breaking = true
+			}
+		}
+	}
+}
+
+// categorizeCommit returns the category of a commit, from its conventional commit type, or else from the labels
+// of its pull request and issues
+func categorizeCommit(c *ReleaseNotesCommit, labels []string) string {
+	if category, ok := categoryTypes[c.Type]; ok {
+		return category
+	}
+	if c.Scope == "deps" || c.Scope == "dependencies" {
+		return ReleaseNotesCategoryDependencies
+	}
+	for _, label := range labels {
+		if category, ok := categoryLabels[strings.ToLower(label)]; ok {
+			return category
+		}
+	}
+	return ReleaseNotesCategoryOther
+}
+
+// releaseNotesEntry is a commit of one of the components, as listed in categorized release notes
+type releaseNotesEntry struct {
+	Component *ReleaseNotesComponent
+	Commit    *ReleaseNotesCommit
+}
+
+type releaseNotesCategory struct {
+	Name    string
+	Entries []*releaseNotesEntry
+}
+
+// categorize returns the categories which have commits, in presentation order
+func categorize(notes *ReleaseNotes) []*releaseNotesCategory {
+	var result []*releaseNotesCategory
+	for _, name := range releaseNotesCategories {
+		category := &releaseNotesCategory{Name: name}
+		for _, component := range notes.Components {
+			for _, commit := range component.Commits {
+				if commit.Category == name || (commit.Category == "" && name == ReleaseNotesCategoryOther) {
+					category.Entries = append(category.Entries, &releaseNotesEntry{Component: component, Commit: commit})
+				}
+			}
+		}
+		if len(category.Entries) > 0 {
+			result = append(result, category)
+		}
+	}
+	return result
+}
+
+// breakingChanges returns the commits marked as breaking changes
+func breakingChanges(notes *ReleaseNotes) []*releaseNotesEntry {
+	var result []*releaseNotesEntry
+	for _, component := range notes.Components {
+		for _, commit := range component.Commits {
+			if commit.Breaking {
+				result = append(result, &releaseNotesEntry{Component: component, Commit: commit})
+			}
+		}
+	}
+	return result
+}
+
+const categorizedReleaseNotesTemplateDefinitions = `
+{{- define "entry" -}}
+* {{if not .Component.Current}}{{repoName .Component.Module}}: {{end}}{{.Commit.Title}}
+{{- with .Commit.PullRequest}}{{with issue $.Component .}}{{if .Url}} ([#{{.Number}}]({{.Url}})){{else}} (#{{.Number}}){{end}}{{else}} (#{{.}}){{end}}{{end}}
+{{- range .Commit.References}}{{if .Url}} ([{{.Name}}]({{.Url}})){{end}}{{end}}
+{{end}}
+
+{{- define "categories" -}}
+{{- with breakingChanges .ReleaseNotes}}## Breaking Changes
+
+{{range .}}* {{if not .Component.Current}}{{repoName .Component.Module}}: {{end}}{{or .Commit.BreakingChange .Commit.Title}}
+{{end}}
+{{end}}
+{{- range categories .ReleaseNotes}}## {{.Name}}
+
+{{range .Entries}}{{template "entry" .}}{{end}}
+{{end}}
+{{- $versions := false}}{{range .Components}}{{if not .Current}}{{$versions = true}}{{end}}{{end}}
+{{- if $versions}}## Component Versions
+
+{{range .Components}}{{if not .Current}}{{template "component" .}}{{end}}{{end}}
+{{- end}}
+{{- end}}`
+
+const categorizedReleaseNotesTemplate = `{{if not .Quiet}}Release notes {{.PreviousVersion}} -> {{.Version}}
+
+{{end}}{{template "categories" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
+
+const categorizedSdkReleaseNotesTemplate = `# Release notes {{.Version}}
+
+{{template "categories" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Api}}{{template "apiDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
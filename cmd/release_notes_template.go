@@ -0,0 +1,248 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// releaseNotesTemplateDefinitions are available to the built-in templates and to those given with --template
+const releaseNotesTemplateDefinitions = `
+{{- define "component" -}}
+{{- if eq .Status "new"}}* {{.Module}}: {{.NewVersion}} (new)
+{{else if eq .Status "unchanged"}}* {{.Module}}: {{.NewVersion}} (unchanged)
+{{else if .CompareUrl}}* {{.Module}}: [{{.OldVersion}} -> {{.NewVersion}}]({{.CompareUrl}})
+{{else}}* {{.Module}}: {{.OldVersion}} -> {{.NewVersion}}
+{{end}}
+{{- template "vulns" .}}
+{{- end}}
+
+{{- define "components" -}}
+{{- range .Components}}
+{{- template "component" .}}
+{{- $shown := false}}
+{{- range .Commits}}
+{{- range .References}}{{if .Url}}    * [{{.Name}}]({{.Url}}){{with .Title}} - {{.}}{{end}}
+{{$shown = true}}{{end}}{{end}}
+{{- if and (not .References) $.AllCommits}}    * {{shortHash .Hash}}: {{.Subject}} ({{.AuthorEmail}})
+{{$shown = true}}{{end}}
+{{- end}}
+{{- if $shown}}
+{{end}}
+{{- end}}
+{{- end}}`
+
+const defaultReleaseNotesTemplate = `{{if not .Quiet}}Release notes {{.PreviousVersion}} -> {{.Version}}
+{{end}}{{template "components" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
+
+const sdkReleaseNotesTemplate = `# Release notes {{.Version}}
+
+## Issues Fixed and Dependency Updates
+
+{{template "components" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Api}}{{template "apiDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
+
+// releaseNotesTemplateData is what release notes templates are executed against
+type releaseNotesTemplateData struct {
+	*ReleaseNotes
+	AllCommits bool
+	Quiet      bool
+}
+
+// templateGroup is a group of items with the same key, as returned by the groupBy template functions
+type templateGroup struct {
+	Key   string
+	Items []interface{}
+}
+
+// parseReleaseNotesTemplate returns the template given with --template, or the given built-in template
+func (cmd *baseBuildReleaseNotesCmd) parseReleaseNotesTemplate(builtIn string) (*template.Template, error) {
+	tmpl, err := template.New("release-notes").Funcs(cmd.releaseNotesTemplateFuncs()).Parse(releaseNotesTemplateDefinitions)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl, err = tmpl.Parse(categorizedReleaseNotesTemplateDefinitions); err != nil {
+		return nil, err
+	}
+	if tmpl, err = tmpl.Parse(goModDiffTemplateDefinitions); err != nil {
+		return nil, err
+	}
+	if tmpl, err = tmpl.Parse(vulnTemplateDefinitions); err != nil {
+		return nil, err
+	}
+	if tmpl, err = tmpl.Parse(apiDiffTemplateDefinitions); err != nil {
+		return nil, err
+	}
+	if tmpl, err = tmpl.Parse(contributorsTemplateDefinitions); err != nil {
+		return nil, err
+	}
+
+	text := builtIn
+	if cmd.Template != "" {
+		data, err := os.ReadFile(cmd.Template)
+		if err != nil {
+			return nil, configErrorf("unable to read release notes template: %w", err)
+		}
+		text = string(data)
+	}
+	if tmpl, err = tmpl.Parse(text); err != nil {
+		return nil, configErrorf("invalid release notes template %v: %w", valueOr(cmd.Template, "(built-in)"), err)
+	}
+	return tmpl, nil
+}
+
+func (cmd *baseBuildReleaseNotesCmd) writeMarkdown(out io.Writer, notes *ReleaseNotes, builtIn string) error {
+	tmpl, err := cmd.parseReleaseNotesTemplate(builtIn)
+	if err != nil {
+		return err
+	}
+	data := &releaseNotesTemplateData{
+		ReleaseNotes: notes,
+		AllCommits:   cmd.AllCommits,
+		Quiet:        cmd.quiet,
+	}
+	if err = tmpl.Execute(out, data); err != nil {
+		return configErrorf("unable to render release notes: %w", err)
+	}
+	return nil
+}
+
+func (cmd *baseBuildReleaseNotesCmd) releaseNotesTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"issue": func(component *ReleaseNotesComponent, number int) *ReleaseNotesIssue {
+			return component.getIssue(number)
+		},
+		"issueLink": func(issue *ReleaseNotesIssue) string {
+			return fmt.Sprintf("[Issue #%v](%v)", issue.Number, issue.Url)
+		},
+		"categories":      categorize,
+		"breakingChanges": breakingChanges,
+		"shortHash": func(hash string) string {
+			if len(hash) > 7 {
+				return hash[:7]
+			}
+			return hash
+		},
+		"repoName": func(module string) string {
+			return path.Base(getRepoPath(module))
+		},
+		"compareUrl": func(module, oldRev, newRev string) string {
+			return cmd.Config.compareUrl(path.Base(getRepoPath(module)), oldRev, newRev)
+		},
+		"commitUrl": func(module, hash string) string {
+			return cmd.Config.commitUrl(path.Base(getRepoPath(module)), hash)
+		},
+		"commitType": commitType,
+		"groupByType": func(commits []*ReleaseNotesCommit) []*templateGroup {
+			var result []*templateGroup
+			for _, commit := range commits {
+				result = addToGroup(result, commitType(commit), commit)
+			}
+			return result
+		},
+		"groupBy": groupBy,
+		"sortBy":  sortBy,
+		"join":    strings.Join,
+		"lower":   strings.ToLower,
+		"upper":   strings.ToUpper,
+	}
+}
+
+// commitType returns the conventional commit type of the commit, ex: feat, or other if it doesn't have one
+func commitType(commit *ReleaseNotesCommit) string {
+	if parsed := parseConventionalCommit(commit.Subject); parsed != nil {
+		return parsed.Type
+	}
+	return "other"
+}
+
+func addToGroup(groups []*templateGroup, key string, item interface{}) []*templateGroup {
+	for _, group := range groups {
+		if group.Key == key {
+			group.Items = append(group.Items, item)
+			return groups
+		}
+	}
+	return append(groups, &templateGroup{Key: key, Items: []interface{}{item}})
+}
+
+// groupBy groups the items of a list by the value of the named field, ex: groupBy "Status" .Components. Groups
+// are in order of first appearance
+func groupBy(field string, items interface{}) ([]*templateGroup, error) {
+	list := reflect.ValueOf(items)
+	if list.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("groupBy expects a list, got %T", items)
+	}
+	var result []*templateGroup
+	for i := 0; i < list.Len(); i++ {
+		key, err := fieldValue(list.Index(i), field)
+		if err != nil {
+			return nil, err
+		}
+		result = addToGroup(result, key, list.Index(i).Interface())
+	}
+	return result, nil
+}
+
+// sortBy returns a copy of a list sorted by the value of the named field, ex: sortBy "Module" .Components
+func sortBy(field string, items interface{}) (interface{}, error) {
+	list := reflect.ValueOf(items)
+	if list.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("sortBy expects a list, got %T", items)
+	}
+	result := reflect.MakeSlice(list.Type(), list.Len(), list.Len())
+	reflect.Copy(result, list)
+
+	keys := make([]string, list.Len())
+	indexes := make([]int, list.Len())
+	for i := range indexes {
+		key, err := fieldValue(list.Index(i), field)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return keys[indexes[i]] < keys[indexes[j]]
+	})
+	for i, idx := range indexes {
+		result.Index(i).Set(list.Index(idx))
+	}
+	return result.Interface(), nil
+}
+
+func fieldValue(item reflect.Value, field string) (string, error) {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		item = item.Elem()
+	}
+	if item.Kind() != reflect.Struct {
+		return "", fmt.Errorf("unable to get field %v of %v", field, item.Type())
+	}
+	value := item.FieldByName(field)
+	if !value.IsValid() {
+		return "", fmt.Errorf("%v has no field %v", item.Type(), field)
+	}
+	return fmt.Sprint(value.Interface()), nil
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"github.com/go-git/go-git/v5"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+func defaultRepoCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ziti-ci", "repos")
+}
+
+// getRepoPath returns the path of the repository holding the given module, ex: github.com/openziti/foo for
+// github.com/openziti/foo/sdk/v2
+func getRepoPath(modulePath string) string {
+	parts := strings.Split(modulePath, "/")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, "/")
+}
+
+// getRepoRemoteUrl returns the url the repository holding the given module is cloned from. A mapping in
+// release-notes.repositories, for the module or its repository, takes precedence over release-notes.remote-base
+func (c *RepoConfig) getRepoRemoteUrl(modulePath string) string {
+	repoPath := getRepoPath(modulePath)
+	for _, key := range []string{modulePath, repoPath} {
+		if url, found := c.ReleaseNotes.Repositories[key]; found {
+			return url
+		}
+	}
+	if c.ReleaseNotes.RemoteBase != "" {
+		return strings.TrimSuffix(c.ReleaseNotes.RemoteBase, "/") + "/" + repoPath
+	}
+	return "https://" + repoPath
+}
+
+// repoCache tracks the mirrors updated during this run, so each is cloned or fetched once, even when several
+// dependencies share a repository or are collected concurrently
+type repoCache struct {
+	lock  sync.Mutex
+	repos map[string]*cachedRepo
+}
+
+type cachedRepo struct {
+	once sync.Once
+	err  error
+}
+
+func newRepoCache() *repoCache {
+	return &repoCache{repos: map[string]*cachedRepo{}}
+}
+
+func (c *repoCache) get(repoPath string) *cachedRepo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result, found := c.repos[repoPath]
+	if !found {
+		result = &cachedRepo{}
+		c.repos[repoPath] = result
+	}
+	return result
+}
+
+// openDependencyRepo returns the cached mirror of the repository holding the given module. The mirror is cloned
+// if it's not in the cache yet, and otherwise fetched, once per run. Each call returns its own handle on the
+// mirror, so callers may use them concurrently
+func (cmd *BaseCommand) openDependencyRepo(modulePath string) (*git.Repository, error) {
+	repoPath := getRepoPath(modulePath)
+	dir := filepath.Join(cmd.Config.ReleaseNotes.CacheDir, filepath.FromSlash(repoPath)+".git")
+
+	cached := cmd.repoCache.get(repoPath)
+	cached.once.Do(func() {
+		cached.err = cmd.updateMirror(repoPath, cmd.Config.getRepoRemoteUrl(modulePath), dir)
+	})
+	if cached.err != nil {
+		return nil, cached.err
+	}
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, gitErrorf("unable to open cached repository %v: %w", dir, err)
+	}
+	return r, nil
+}
+
+func (cmd *BaseCommand) updateMirror(repoPath, url, dir string) error {
+	r, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		cmd.Infof("cloning %v from %v into %v\n", repoPath, url, dir)
+		_, err = git.PlainClone(dir, true, &git.CloneOptions{URL: url, Mirror: true, Tags: git.AllTags})
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return gitErrorf("unable to clone %v from %v: %w", repoPath, url, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return gitErrorf("unable to open cached repository %v: %w", dir, err)
+	}
+
+	cmd.Infof("fetching %v from %v\n", repoPath, url)
+	err = r.Fetch(&git.FetchOptions{RemoteURL: url, Tags: git.AllTags, Force: true})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return gitErrorf("unable to fetch %v from %v: %w", repoPath, url, err)
+	}
+	return nil
+}
+
+// openCurrentRepo returns the repository being built, for listing its own changes
+func (cmd *BaseCommand) openCurrentRepo() (*git.Repository, error) {
+	r, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, gitErrorf("unable to open git repository: %w", err)
+	}
+	return r, nil
+}
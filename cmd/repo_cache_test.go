@@ -0,0 +1,146 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetRepoRemoteUrl(t *testing.T) {
+	req := require.New(t)
+	config := defaultRepoConfig()
+
+	req.Equal("github.com/openziti/foo", getRepoPath("github.com/openziti/foo/sdk/v2"))
+	req.Equal("https://github.com/openziti/edge", config.getRepoRemoteUrl("github.com/openziti/edge/v2"))
+
+	config.ReleaseNotes.RemoteBase = "file:///srv/mirrors/"
+	req.Equal("file:///srv/mirrors/github.com/openziti/edge", config.getRepoRemoteUrl("github.com/openziti/edge/v2"))
+
+	config.ReleaseNotes.Repositories = map[string]string{"github.com/openziti/edge": "git@example.com:edge.git"}
+	req.Equal("git@example.com:edge.git", config.getRepoRemoteUrl("github.com/openziti/edge/v2"))
+}
+
+func TestDependencyRepoCache(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	wd, err := os.Getwd()
+	req.NoError(err)
+
+	origin := newDiskTestRepo(t)
+	origin.commit("change.txt", "initial\n", "initial commit", "v0.1.0")
+	origin.commit("change.txt", "add\n", "add widgets")
+	origin.commit("change.txt", "fix\n", "fix widgets", "v0.1.1")
+
+	out := &bytes.Buffer{}
+	newCmd := func() *baseBuildReleaseNotesCmd {
+		cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+		req.NoError(err)
+		cmd.Config.ReleaseNotes.CacheDir = filepath.Join(t.TempDir(), "cache")
+		cmd.Config.ReleaseNotes.Repositories = map[string]string{"example.com/acme/dep": "file://" + origin.dir()}
+		return &baseBuildReleaseNotesCmd{BaseCommand: *cmd, AllCommits: true}
+	}
+
+	cmd := newCmd()
+	r, err := cmd.openDependencyRepo("example.com/acme/dep/v2")
+	req.NoError(err)
+	commits, err := cmd.GetChanges(r, "example.com/acme/dep", "v0.1.0", "v0.1.1")
+	req.NoError(err)
+	req.Len(commits, 2)
+	req.Equal("fix widgets", commits[0].Subject)
+	req.Equal("add widgets", commits[1].Subject)
+
+	_, err = os.Stat(filepath.Join(cmd.Config.ReleaseNotes.CacheDir, "example.com", "acme", "dep.git"))
+	req.NoError(err)
+
+	// the working directory is left alone
+	cwd, err := os.Getwd()
+	req.NoError(err)
+	req.Equal(wd, cwd)
+
+	// later runs fetch new tags into the existing mirror
+	origin.commit("change.txt", "document\n", "document widgets", "v0.1.2")
+	cacheDir := cmd.Config.ReleaseNotes.CacheDir
+	cmd = newCmd()
+	cmd.Config.ReleaseNotes.CacheDir = cacheDir
+	r, err = cmd.openDependencyRepo("example.com/acme/dep")
+	req.NoError(err)
+	commits, err = cmd.GetChanges(r, "example.com/acme/dep", "v0.1.1", "v0.1.2")
+	req.NoError(err)
+	req.Len(commits, 1)
+	req.Equal("document widgets", commits[0].Subject)
+}
+
+func TestCollectChanges(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3", "v0.3.0")
+
+	edge := newDiskTestRepo(t)
+	edge.commit("change.txt", "initial\n", "edge initial commit", "v0.1.0")
+	edge.commit("change.txt", "change\n", "edge change", "v0.1.1")
+	edge.commit("change.txt", "sdk change\n", "edge sdk change", "sdk/v0.2.0")
+
+	fabric := newDiskTestRepo(t)
+	fabric.commit("change.txt", "initial\n", "fabric initial commit", "v1.0.0")
+	fabric.commit("change.txt", "change\n", "fabric change", "v1.0.1")
+
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	cmd.Config.ReleaseNotes.CacheDir = t.TempDir()
+	cmd.Config.ReleaseNotes.Repositories = map[string]string{
+		"example.com/acme/edge":   edge.dir(),
+		"example.com/acme/fabric": fabric.dir(),
+	}
+	notesCmd := &baseBuildReleaseNotesCmd{BaseCommand: *cmd, AllCommits: true, Concurrency: 3}
+
+	notes := &ReleaseNotes{Version: "0.3.1"}
+	addComponent := func(modulePath, oldVersion, newVersion string) {
+		component := newComponent(modulePath, ComponentStatusChanged, oldVersion, newVersion)
+		component.setChanges(modulePath, oldVersion, newVersion)
+		notes.Components = append(notes.Components, component)
+	}
+	addComponent("example.com/acme/fabric", "v1.0.0", "v1.0.1")
+	notes.Components = append(notes.Components, newComponent("example.com/acme/other", ComponentStatusNew, "", "v1.2.3"))
+	// modules sharing a repository share its mirror
+	addComponent("example.com/acme/edge", "v0.1.0", "v0.1.1")
+	addComponent("example.com/acme/edge/sdk", "v0.1.1", "sdk/v0.2.0")
+
+	req.NoError(notesCmd.collectChanges(notes))
+	var subjects []string
+	for _, component := range notes.Components {
+		for _, commit := range component.Commits {
+			subjects = append(subjects, commit.Subject)
+		}
+	}
+	req.Equal([]string{"fabric change", "edge change", "edge sdk change"}, subjects)
+
+	req.NoError(notesCmd.writeMarkdown(out, notes, defaultReleaseNotesTemplate))
+	hash := notes.Components[0].Commits[0].Hash[:7]
+	req.True(strings.HasPrefix(out.String(), "* example.com/acme/fabric: v1.0.0 -> v1.0.1\n"+
+		"    * "+hash+": fabric change (jane@example.com)\n\n"+
+		"* example.com/acme/other: v1.2.3 (new)\n"), out.String())
+
+	// errors are reported in order as well
+	addComponent("example.com/acme/fabric", "v1.0.1", "v9.9.9")
+	req.Error(notesCmd.collectChanges(notes))
+}
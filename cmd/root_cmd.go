@@ -28,6 +28,9 @@ type langType int
 const (
 	LangGo   langType = 1
 	LangJava langType = 2
+	LangC    langType = 3
+	LangRust langType = 4
+	LangNode langType = 5
 )
 
 var RootCmd = newRootCommand()
@@ -45,6 +48,24 @@ type RootCommand struct {
 
 	baseVersionString string
 	baseVersionFile   string
+	versionSourceKind string
+
+	bumpStrategy string
+	explainBump  bool
+
+	prereleaseChannel  string
+	prereleaseChannels []string
+
+	moduleDir string
+	tagPrefix string
+
+	releaseBranchLines bool
+
+	gitBackend string
+
+	configFile string
+
+	buildTimeMode string
 }
 
 func newRootCommand() *RootCommand {
@@ -57,14 +78,31 @@ func newRootCommand() *RootCommand {
 		RootCobraCmd: cobraCmd,
 	}
 
+	// errors are reported, with their exit code, by Execute
+	cobraCmd.SilenceErrors = true
+	cobraCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return newCiError(ErrorKindConfig, err)
+	})
+
 	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.verbose, "verbose", "v", false, "enable verbose output")
 	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.useCurrentTag, "use-current-tag", "t", false, "inspect all tags, including -beta, -pre, -alpha, etc")
 	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.quiet, "quiet", "q", false, "disable informational output")
 	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.dryRun, "dry-run", "d", false, "do a dry run")
-	cobraCmd.PersistentFlags().StringVarP(&rootCmd.langName, "language", "l", "go", "enable language specific settings. Valid values: [go,java]")
+	cobraCmd.PersistentFlags().StringVarP(&rootCmd.langName, "language", "l", "go", "enable language specific settings. Valid values: [go,java,c,rust,node]")
 
 	cobraCmd.PersistentFlags().StringVarP(&rootCmd.baseVersionString, "base-version", "b", "", "set base version")
 	cobraCmd.PersistentFlags().StringVarP(&rootCmd.baseVersionFile, "base-version-file", "f", DefaultVersionFile, "set base version file location")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.gitBackend, "git-backend", GitBackendGoGit, "how git repositories are read. Valid values: [go-git,cli]. Commits, tags and pushes always use the git binary")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.versionSourceKind, "version-source", VersionSourceAuto, "where the base version is read from. Valid values: [auto,file,maven,gradle,npm,cargo,cmake,helm]")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.bumpStrategy, "bump-strategy", BumpStrategyPatch, "how the next version is chosen. Valid values: [patch,conventional]")
+	cobraCmd.PersistentFlags().BoolVar(&rootCmd.explainBump, "explain-bump", false, "print the commits which caused the version bump to stderr")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.prereleaseChannel, "prerelease-channel", "", "compute pre-release versions on the given channel, ex: rc produces v1.2.0-rc.1, v1.2.0-rc.2, ...")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.moduleDir, "module-dir", "", "directory of a nested go module, relative to the repository root. Tags for the module are prefixed with the directory, ex: sdk/v1.4.2")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.tagPrefix, "tag-prefix", "", "prefix for version tags. Defaults to the module directory followed by a '/'")
+	cobraCmd.PersistentFlags().BoolVar(&rootCmd.releaseBranchLines, "release-branch-lines", false, "on release-vX.Y branches, compute the next patch of the X.Y line from the tags reachable from the branch")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.buildTimeMode, "build-time", BuildTimeNow, "time stamped into build info and archives. Valid values: [now,commit]. commit uses the HEAD commit time. SOURCE_DATE_EPOCH, if set, takes precedence")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.configFile, "config", "", "repository configuration file. Defaults to .ziti-ci.yaml at the repository root")
+	cobraCmd.PersistentFlags().StringSliceVar(&rootCmd.prereleaseChannels, "allowed-prerelease-channels", []string{"alpha", "beta", "rc"}, "pre-release channels which may be used with --prerelease-channel")
 
 	rootCobraCmd := rootCmd.RootCobraCmd
 
@@ -89,6 +127,14 @@ func newRootCommand() *RootCommand {
 	rootCobraCmd.AddCommand(newGetReleaseNotesCmd(rootCmd))
 	rootCobraCmd.AddCommand(newBuildReleaseNotesCmd(rootCmd))
 	rootCobraCmd.AddCommand(newBuildSdkReleaseNotesCmd(rootCmd))
+	rootCobraCmd.AddCommand(newListModulesCmd(rootCmd))
+	rootCobraCmd.AddCommand(newExplainVersionCmd(rootCmd))
+	rootCobraCmd.AddCommand(newUpdateBaseVersionCmd(rootCmd))
+	rootCobraCmd.AddCommand(newConfigCmd(rootCmd))
+	rootCobraCmd.AddCommand(newVerifyReproducibleCmd(rootCmd))
+	rootCobraCmd.AddCommand(newUpdateChangelogCmd(rootCmd))
+	rootCobraCmd.AddCommand(newAuditDepsCmd(rootCmd))
+	rootCobraCmd.AddCommand(newApiDiffCmd(rootCmd))
 
 	var versionCmd = &cobra.Command{
 		Use:   "version",
@@ -104,9 +150,11 @@ func newRootCommand() *RootCommand {
 	return rootCmd
 }
 
+// Execute runs the command selected by the command line arguments and exits with the exit code for the
+// kind of failure, if it fails
 func (r *RootCommand) Execute() {
 	if err := r.RootCobraCmd.Execute(); err != nil {
-		fmt.Printf("error: %s\n", err)
-		os.Exit(1)
+		_, _ = fmt.Fprintf(r.RootCobraCmd.ErrOrStderr(), "error: %s\n", err)
+		os.Exit(ExitCode(err))
 	}
 }
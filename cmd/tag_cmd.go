@@ -19,8 +19,8 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/hashicorp/go-version"
 	"github.com/spf13/cobra"
-	"os"
 	"strings"
 )
 
@@ -31,40 +31,77 @@ const (
 type tagCmd struct {
 	BaseCommand
 	onlyForBranch string
+	checkApi      bool
 }
 
-func (cmd *tagCmd) Execute() {
-	if cmd.onlyForBranch != "" && cmd.onlyForBranch != cmd.GetCurrentBranch() {
-		cmd.Infof("current branch %v doesn't match requested branch %v, so skipping\n", cmd.GetCurrentBranch(), cmd.onlyForBranch)
-		os.Exit(0)
+func (cmd *tagCmd) Execute() error {
+	if cmd.onlyForBranch != "" {
+		branch, err := cmd.GetCurrentBranch()
+		if err != nil {
+			return err
+		}
+		if cmd.onlyForBranch != branch {
+			cmd.Infof("current branch %v doesn't match requested branch %v, so skipping\n", branch, cmd.onlyForBranch)
+			return nil
+		}
+	}
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
 	}
-	cmd.EvalCurrentAndNextVersion()
 
-	headTags := cmd.getVersionList("tag", "--points-at", "HEAD")
+	tags, err := cmd.listTags("listing git tags pointing at HEAD", func(g Git) ([]string, error) {
+		return g.TagsPointingAt("HEAD")
+	})
+	if err != nil {
+		return err
+	}
+
+	var headTags []*version.Version
+	if cmd.isPrerelease() {
+		headTags = cmd.getAllVersionList("tags pointing at HEAD", tags)
+	} else {
+		// a final release may graduate a release candidate which was tagged on the same commit
+		headTags = cmd.getVersionList("tags pointing at HEAD", tags)
+	}
 	if len(headTags) > 0 {
 		cmd.Errorf("head already tagged with %+v:\n", headTags)
-		os.Exit(0)
+		return nil
 	}
 
 	cmd.Infof("previous version: %v, next version: %v\n", cmd.CurrentVersion, cmd.NextVersion)
 
+	if cmd.checkApi && cmd.isGoLang() && cmd.CurrentVersion != nil {
+		if err = cmd.verifyApiCompatibility(); err != nil {
+			return err
+		}
+	}
+
 	if cmd.isGoLang() {
 		nextMajorVersion := cmd.NextVersion.Segments()[0]
 		if nextMajorVersion > 1 {
-			moduleName := cmd.getModule()
+			moduleName, err := cmd.getModule()
+			if err != nil {
+				return err
+			}
 			if !strings.HasSuffix(moduleName, fmt.Sprintf("/v%v", nextMajorVersion)) {
-				cmd.Failf("error: module version doesn't match next version: %v\n", nextMajorVersion)
+				return versionErrorf("module version doesn't match next version: %v", nextMajorVersion)
 			}
 		}
 	}
 
-	tagVersion := fmt.Sprintf("%v", cmd.NextVersion)
-	if cmd.isGoLang() {
-		tagVersion = "v" + tagVersion
+	tagVersion := cmd.getVersionTag(cmd.NextVersion)
+	exists, err := cmd.tagExists(tagVersion)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return versionErrorf("tag %v already exists on another line", tagVersion)
 	}
-	tagParms := []string{"tag", "-a", tagVersion, "-m", fmt.Sprintf("Release %v", tagVersion)}
-	cmd.RunGitCommand("create tag", tagParms...)
-	cmd.RunGitCommand("push tag to repo", "push", "origin", tagVersion)
+
+	return cmd.runGitCommands(
+		[]string{"create tag", "tag", "-a", tagVersion, "-m", fmt.Sprintf("Release %v", tagVersion)},
+		[]string{"push tag to repo", "push", "origin", tagVersion},
+	)
 }
 
 func newTagCmd(root *RootCommand) *cobra.Command {
@@ -82,6 +119,7 @@ func newTagCmd(root *RootCommand) *cobra.Command {
 	}
 
 	cobraCmd.PersistentFlags().StringVar(&result.onlyForBranch, "only-for-branch", "", "Only do if branch matches")
+	cobraCmd.Flags().BoolVar(&result.checkApi, "check-api", false, "refuse a minor or patch release if the exported API has incompatible changes since the current version")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
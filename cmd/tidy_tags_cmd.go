@@ -262,8 +262,12 @@ type TidyTagsCmd struct {
 	fix bool
 }
 
-func (cmd *TidyTagsCmd) Execute() {
-	versions := cmd.getVersionList("tag", "--list")
+func (cmd *TidyTagsCmd) Execute() error {
+	tags, err := cmd.listTags("listing git tags", Git.ListTags)
+	if err != nil {
+		return err
+	}
+	versions := cmd.getVersionList("tags", tags)
 	versionMap := map[string]struct{}{}
 	for _, version := range knownZitiVersions {
 		versionMap[version] = struct{}{}
@@ -277,11 +281,17 @@ func (cmd *TidyTagsCmd) Execute() {
 		//if parts[0] == 0 && parts[1] < 30 {
 		fmt.Printf("tag v%s\n", version.String())
 		if cmd.fix {
-			cmd.RunGitCommand("delete remote tag", "push", "origin", "--delete", fmt.Sprintf("v%s", version.String()))
-			cmd.RunGitCommand("delete local tag", "tag", "--delete", fmt.Sprintf("v%s", version.String()))
+			err = cmd.runGitCommands(
+				[]string{"delete remote tag", "push", "origin", "--delete", fmt.Sprintf("v%s", version.String())},
+				[]string{"delete local tag", "tag", "--delete", fmt.Sprintf("v%s", version.String())},
+			)
+			if err != nil {
+				return err
+			}
 		}
 		//}
 	}
+	return nil
 }
 
 func newTidyTagsCmd(root *RootCommand) *cobra.Command {
@@ -300,5 +310,5 @@ func newTidyTagsCmd(root *RootCommand) *cobra.Command {
 
 	cobraCmd.Flags().BoolVar(&result.fix, "fix", false, "fix merged tags")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
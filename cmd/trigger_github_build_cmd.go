@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/cobra"
 	"net/http"
 	"os"
+	"strings"
 )
 
 type triggerGithubBuidlCmd struct {
@@ -30,14 +31,16 @@ type triggerGithubBuidlCmd struct {
 	githubToken string
 }
 
-func (cmd *triggerGithubBuidlCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *triggerGithubBuidlCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	if cmd.githubToken == "" {
 		found := false
 		cmd.githubToken, found = os.LookupEnv("GITHUB_TOKEN")
 		if !found {
-			cmd.Failf("no github token provided. Unable to trigger builds\n")
+			return configErrorf("no github token provided. Unable to trigger builds")
 		}
 	}
 
@@ -50,12 +53,17 @@ func (cmd *triggerGithubBuidlCmd) Execute() {
 		}`
 
 	branch := cmd.Args[1]
-	module := fmt.Sprintf("%v@v%v", cmd.getModule(), cmd.CurrentVersion.String())
+	moduleName, err := cmd.getModule()
+	if err != nil {
+		return err
+	}
+	module := fmt.Sprintf("%v@v%v", moduleName, cmd.CurrentVersion.String())
 	body := fmt.Sprintf(bodyTemplate, branch, module)
 
 	client := resty.New()
 
-	targetUrl := fmt.Sprintf("https://api.github.com/repos/%v/actions/workflows/update-dependency.yml/dispatches", cmd.Args[0])
+	targetUrl := fmt.Sprintf("%v/repos/%v/actions/workflows/%v/dispatches",
+		strings.TrimSuffix(cmd.Config.Github.ApiUrl, "/"), cmd.Args[0], cmd.Config.Github.DependencyWorkflow)
 	resp, err := client.R().
 		EnableTrace().
 		SetHeader("Accept", "application/vnd.github.v3+json").
@@ -64,16 +72,16 @@ func (cmd *triggerGithubBuidlCmd) Execute() {
 		Post(targetUrl)
 
 	if err != nil {
-		cmd.Failf("Error triggering build s\n")
-		panic(err)
+		return networkErrorf("error triggering build: %w", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusAccepted && resp.StatusCode() != http.StatusNoContent {
 		cmd.logJson(resp.Body())
-		cmd.Failf("Error triggering build. REST call returned %v", resp.StatusCode())
+		return networkErrorf("error triggering build. REST call returned %v", resp.StatusCode())
 	}
 
 	cmd.Infof("successfully triggered build of %v to update to %v\n", cmd.Args[0], module)
+	return nil
 }
 
 func newTriggerGithubBuildCmd(root *RootCommand) *cobra.Command {
@@ -92,5 +100,5 @@ func newTriggerGithubBuildCmd(root *RootCommand) *cobra.Command {
 
 	cobraCmd.PersistentFlags().StringVar(&result.githubToken, "token", "", "Github token to use to trigger the build")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -32,14 +32,16 @@ type triggerJenkinsSmokeBuildCmd struct {
 	jenkinsJobToken  string
 }
 
-func (cmd *triggerJenkinsSmokeBuildCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *triggerJenkinsSmokeBuildCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	if cmd.jenkinsUser == "" {
 		found := false
 		cmd.jenkinsUser, found = os.LookupEnv("jenkins_user")
 		if !found {
-			cmd.Failf("no jenkins user provided. Unable to trigger builds\n")
+			return configErrorf("no jenkins user provided. Unable to trigger builds")
 		}
 	}
 
@@ -47,7 +49,7 @@ func (cmd *triggerJenkinsSmokeBuildCmd) Execute() {
 		found := false
 		cmd.jenkinsUserToken, found = os.LookupEnv("jenkins_user_token")
 		if !found {
-			cmd.Failf("no jenkins user token provided. Unable to trigger builds\n")
+			return configErrorf("no jenkins user token provided. Unable to trigger builds")
 		}
 	}
 
@@ -55,38 +57,52 @@ func (cmd *triggerJenkinsSmokeBuildCmd) Execute() {
 		found := false
 		cmd.jenkinsJobToken, found = os.LookupEnv("jenkins_job_token")
 		if !found {
-			cmd.Failf("no jenkins job token provided. Unable to trigger builds\n")
+			return configErrorf("no jenkins job token provided. Unable to trigger builds")
 		}
 	}
 
 	client := resty.New()
 
 	version := cmd.getPublishVersion().String()
-	if !cmd.isReleaseBranch() {
+	isReleaseBranch, err := cmd.isReleaseBranch()
+	if err != nil {
+		return err
+	}
+	if !isReleaseBranch {
 		version = fmt.Sprintf("%v-%v", version, cmd.getBuildNumber())
 	}
 
+	branch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	committer, err := cmd.getCommitterEmail()
+	if err != nil {
+		return err
+	}
+
 	resp, err := client.R().
 		EnableTrace().
 		SetQueryParam("token", cmd.jenkinsJobToken).
-		SetQueryParam("branch", cmd.GetCurrentBranch()).
+		SetQueryParam("branch", branch).
 		SetQueryParam("version", version).
-		SetQueryParam("committer", cmd.getCommitterEmail()).
+		SetQueryParam("committer", committer).
 		SetQueryParam("cause", fmt.Sprintf("triggered by ziti-ci build #%v", cmd.getBuildNumber())).
 		SetBasicAuth(cmd.jenkinsUser, cmd.jenkinsUserToken).
-		Post("https://jenkinstest.tools.netfoundry.io/job/ziti-smoke-test/buildWithParameters")
+		Post(cmd.Config.Jenkins.SmokeTestUrl)
 
 	if err != nil {
-		cmd.Failf("Error triggering build s\n")
-		panic(err)
+		return networkErrorf("error triggering build: %w", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusCreated && resp.StatusCode() != http.StatusAccepted {
 		cmd.logJson(resp.Body())
-		cmd.Failf("Error triggering build. REST call returned %v", resp.StatusCode())
+		return networkErrorf("error triggering build. REST call returned %v", resp.StatusCode())
 	}
 
-	cmd.Infof("successfully triggered build of ziti-smoke-test for branch: %v, version: %v\n", cmd.GetCurrentBranch(), version)
+	cmd.Infof("successfully triggered build of ziti-smoke-test for branch: %v, version: %v\n", branch, version)
+	return nil
 }
 
 func newTriggerJenkinsBuildCmd(root *RootCommand) *cobra.Command {
@@ -107,5 +123,5 @@ func newTriggerJenkinsBuildCmd(root *RootCommand) *cobra.Command {
 	cobraCmd.PersistentFlags().StringVar(&result.jenkinsUserToken, "user-token", "", "Jenkins user API token to use to trigger the build")
 	cobraCmd.PersistentFlags().StringVar(&result.jenkinsJobToken, "job-token", "", "Jenkins job token to use to trigger the build")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
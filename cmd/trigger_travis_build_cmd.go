@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 )
 
 type triggerTravisBuidlCmd struct {
@@ -31,14 +32,16 @@ type triggerTravisBuidlCmd struct {
 	travisToken string
 }
 
-func (cmd *triggerTravisBuidlCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *triggerTravisBuidlCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	if cmd.travisToken == "" {
 		found := false
 		cmd.travisToken, found = os.LookupEnv("travis_token")
 		if !found {
-			cmd.Failf("no travis token provided. Unable to trigger builds\n")
+			return configErrorf("no travis token provided. Unable to trigger builds")
 		}
 	}
 
@@ -57,13 +60,17 @@ func (cmd *triggerTravisBuidlCmd) Execute() {
 		}`
 
 	branch := cmd.Args[1]
-	module := fmt.Sprintf("%v@v%v", cmd.getModule(), cmd.CurrentVersion.String())
+	moduleName, err := cmd.getModule()
+	if err != nil {
+		return err
+	}
+	module := fmt.Sprintf("%v@v%v", moduleName, cmd.CurrentVersion.String())
 	body := fmt.Sprintf(bodyTemplate, branch, module, module)
 
 	client := resty.New()
 
 	targetRepo := url.QueryEscape(cmd.Args[0])
-	targetUrl := fmt.Sprintf("https://api.travis-ci.org/repo/%v/requests", targetRepo)
+	targetUrl := fmt.Sprintf("%v/repo/%v/requests", strings.TrimSuffix(cmd.Config.Travis.ApiUrl, "/"), targetRepo)
 
 	resp, err := client.R().
 		EnableTrace().
@@ -75,16 +82,16 @@ func (cmd *triggerTravisBuidlCmd) Execute() {
 		Post(targetUrl)
 
 	if err != nil {
-		cmd.Failf("Error triggering build s\n")
-		panic(err)
+		return networkErrorf("error triggering build: %w", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusAccepted {
 		cmd.logJson(resp.Body())
-		cmd.Failf("Error triggering build. REST call returned %v", resp.StatusCode())
+		return networkErrorf("error triggering build. REST call returned %v", resp.StatusCode())
 	}
 
 	cmd.Infof("successfully triggered build of %v to update to %v\n", cmd.Args[0], module)
+	return nil
 }
 
 func newTriggerTravisBuildCmd(root *RootCommand) *cobra.Command {
@@ -103,5 +110,5 @@ func newTriggerTravisBuildCmd(root *RootCommand) *cobra.Command {
 
 	cobraCmd.PersistentFlags().StringVar(&result.travisToken, "token", "", "Travis token to use to trigger the build")
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -28,36 +28,58 @@ type updateGoDepCmd struct {
 	BaseCommand
 }
 
-func (cmd *updateGoDepCmd) Execute() {
-	cmd.RunGitCommand("Allow fetching other branches", "config", "--replace-all", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
-	//seems to have broken update deps... cmd.RunGitCommand("Ensure " + cmd.GetCurrentBranch() + " is up to date", "fetch", "origin", cmd.GetCurrentBranch())
-	cmd.RunGitCommand("Ensure origin/main is up to date", "fetch", "origin", "main")
-	cmd.RunGitCommand("Ensure go.mod/go.sum are untouched", "checkout", "--", "go.mod", "go.sum")
+func (cmd *updateGoDepCmd) Execute() error {
+	err := cmd.runGitCommands(
+		[]string{"Allow fetching other branches", "config", "--replace-all", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"},
+		//seems to have broken update deps... cmd.RunGitCommand("Ensure " + cmd.GetCurrentBranch() + " is up to date", "fetch", "origin", cmd.GetCurrentBranch())
+		[]string{"Ensure origin/main is up to date", "fetch", "origin", "main"},
+		[]string{"Ensure go.mod/go.sum are untouched", "checkout", "--", "go.mod", "go.sum"},
+	)
+	if err != nil {
+		return err
+	}
 
 	if !isManualCompleteProject() {
-		cmd.RunGitCommand("Sync with main", "merge", "--ff-only", "origin/main")
+		if err = cmd.RunGitCommand("Sync with main", "merge", "--ff-only", "origin/main"); err != nil {
+			return err
+		}
 
-		output := cmd.runCommandWithOutput("Ensure we are synced", "git", "diff", "origin/main")
+		output, err := cmd.runCommandWithOutput("Ensure we are synced", "git", "diff", "origin/main")
+		if err != nil {
+			return newCiError(ErrorKindGit, err)
+		}
 		if len(output) != 0 {
-			cmd.Failf("update branch has diverged from main. automated merges won't work until this is fixed. Diff: %+v", strings.Join(output, "\n"))
+			return gitErrorf("update branch has diverged from main. automated merges won't work until this is fixed. Diff: %+v", strings.Join(output, "\n"))
 		}
 	}
 
-	dep := cmd.getUpdatedDep()
-	cmd.runCommand("Update dependency", "go", "get", dep)
-	diffOutput := cmd.runCommandWithOutput("check if there's a change", "git", "diff", "--name-only", "go.mod")
+	dep, err := cmd.getUpdatedDep()
+	if err != nil {
+		return err
+	}
+	if err = cmd.runCommand("Update dependency", "go", "get", dep); err != nil {
+		return newCiError(ErrorKindNetwork, err)
+	}
+	diffOutput, err := cmd.runCommandWithOutput("check if there's a change", "git", "diff", "--name-only", "go.mod")
+	if err != nil {
+		return newCiError(ErrorKindGit, err)
+	}
 	if len(diffOutput) != 1 || diffOutput[0] != "go.mod" {
 		_, _ = fmt.Fprintf(cmd.Cmd.ErrOrStderr(), "requested dependency did not result in change\n")
-		os.Exit(0)
+		return nil
 	}
 	_, _ = fmt.Fprintf(cmd.Cmd.OutOrStdout(), "attempting to update to %v\n", dep)
 
-	cmd.runCommand("Tidy go.sum", "go", "mod", "tidy")
-	cmd.RunGitCommand("Add go mod changes", "add", "go.mod", "go.sum")
-	cmd.RunGitCommand("Commit go.mod changes", "commit", "-m", fmt.Sprintf("Updating dependency %v", dep))
+	if err = cmd.runCommand("Tidy go.sum", "go", "mod", "tidy"); err != nil {
+		return err
+	}
+	return cmd.runGitCommands(
+		[]string{"Add go mod changes", "add", "go.mod", "go.sum"},
+		[]string{"Commit go.mod changes", "commit", "-m", fmt.Sprintf("Updating dependency %v", dep)},
+	)
 }
 
-func (cmd *updateGoDepCmd) getUpdatedDep() string {
+func (cmd *updateGoDepCmd) getUpdatedDep() (string, error) {
 	newDep := ""
 	if len(cmd.Args) > 0 {
 		newDep = cmd.Args[0]
@@ -67,10 +89,10 @@ func (cmd *updateGoDepCmd) getUpdatedDep() string {
 	}
 
 	if newDep == "" {
-		cmd.Failf("no updated dependency provided\n")
+		return "", configErrorf("no updated dependency provided")
 	}
 
-	return newDep
+	return newDep, nil
 }
 
 func newUpdateGoDepCmd(root *RootCommand) *cobra.Command {
@@ -87,27 +109,40 @@ func newUpdateGoDepCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
 
 type completeUpdateGoDepCmd struct {
 	BaseCommand
 }
 
-func (cmd *completeUpdateGoDepCmd) Execute() {
-	updateBranch := cmd.GetCurrentBranch()
+func (cmd *completeUpdateGoDepCmd) Execute() error {
+	updateBranch, err := cmd.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
 
 	// go get gox or go get jfrog can mess with go.mod since we committed
-	cmd.RunGitCommand("Ensure go.mod/go.sum are untouched", "checkout", "--", "go.mod", "go.sum")
-	currentCommit := cmd.GetCmdOutputOneLine("get git SHA", "git", "rev-parse", "--short=12", "HEAD")
+	if err = cmd.RunGitCommand("Ensure go.mod/go.sum are untouched", "checkout", "--", "go.mod", "go.sum"); err != nil {
+		return err
+	}
+	currentCommit, err := cmd.getRevision()
+	if err != nil {
+		return err
+	}
 	if !isManualCompleteProject() {
-		cmd.RunGitCommand("Checkout main", "checkout", "main")
+		err = cmd.RunGitCommand("Checkout main", "checkout", "main")
 	} else {
-		cmd.RunGitCommand("Checkout actual branch", "checkout", cmd.GetCurrentBranch())
+		err = cmd.RunGitCommand("Checkout actual branch", "checkout", updateBranch)
+	}
+	if err != nil {
+		return err
 	}
-	cmd.RunGitCommand("Merge in changes", "merge", "--ff-only", currentCommit)
-	cmd.RunGitCommand("Push to remote", "push")
-	cmd.RunGitCommand("Push update branch ", "push", "origin", updateBranch)
+	return cmd.runGitCommands(
+		[]string{"Merge in changes", "merge", "--ff-only", currentCommit},
+		[]string{"Push to remote", "push"},
+		[]string{"Push update branch ", "push", "origin", updateBranch},
+	)
 }
 
 func newCompleteUpdateGoDepCmd(root *RootCommand) *cobra.Command {
@@ -124,7 +159,7 @@ func newCompleteUpdateGoDepCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
 
 func isManualCompleteProject() bool {
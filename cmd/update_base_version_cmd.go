@@ -0,0 +1,86 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+type updateBaseVersionCmd struct {
+	BaseCommand
+	commit bool
+}
+
+func (cmd *updateBaseVersionCmd) Execute() error {
+	newVersion := ""
+	if len(cmd.Args) > 0 {
+		newVersion = strings.TrimPrefix(cmd.Args[0], "v")
+	} else {
+		if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+			return err
+		}
+		newVersion = cmd.NextVersion.String()
+	}
+
+	source, err := cmd.getVersionSource()
+	if err != nil {
+		return err
+	}
+	if current, err := source.ReadVersion(); err == nil && current == newVersion {
+		cmd.Infof("base version in %v is already %v\n", describeVersionSource(source), newVersion)
+		return nil
+	}
+
+	cmd.Infof("setting base version in %v to %v\n", describeVersionSource(source), newVersion)
+	if cmd.dryRun {
+		return nil
+	}
+
+	if err = source.WriteVersion(newVersion); err != nil {
+		return versionErrorf("unable to write version to %v: %w", describeVersionSource(source), err)
+	}
+
+	if cmd.commit {
+		return cmd.runGitCommands(
+			[]string{"add version file to git", "add", source.Path()},
+			[]string{"commit version file", "commit", "-m", fmt.Sprintf("Update version to %v", newVersion)},
+		)
+	}
+	return nil
+}
+
+func newUpdateBaseVersionCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "update-base-version [version]",
+		Short: "Write the given version, or the next version, to the project's base version source",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	result := &updateBaseVersionCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().BoolVar(&result.commit, "commit", false, "add and commit the updated version file")
+
+	return FinalizeErroringCmd(result)
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChangelogMarker separates the hand-written prose of a changelog section, above it, from the generated release
+// notes below it, which update-changelog replaces
+const ChangelogMarker = "<!-- generated release notes -->"
+
+const changelogReleaseNotesTemplate = `{{template "components" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Api}}{{template "apiDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
+
+const categorizedChangelogReleaseNotesTemplate = `{{template "categories" .}}{{with .GoMod}}{{template "goModDiff" .}}{{end}}{{with .Api}}{{template "apiDiff" .}}{{end}}{{with .Contributors}}{{template "contributors" .}}{{end}}`
+
+type updateChangelogCmd struct {
+	baseBuildReleaseNotesCmd
+	sdk    bool
+	commit bool
+	check  bool
+}
+
+func (cmd *updateChangelogCmd) Execute() error {
+	changelog := "CHANGELOG.md"
+	if len(cmd.Args) > 0 {
+		changelog = cmd.Args[0]
+	}
+
+	changelogFormat, err := cmd.getChangelogFormat()
+	if err != nil {
+		return err
+	}
+	if cmd.check {
+		return cmd.checkChangelog(changelogFormat, changelog)
+	}
+
+	cmd.Format = ReleaseNotesFormatMarkdown
+	var notes *ReleaseNotes
+	if cmd.sdk {
+		notesCmd := &buildSdkReleaseNotesCmd{baseBuildReleaseNotesCmd: cmd.baseBuildReleaseNotesCmd}
+		notes, err = notesCmd.evalReleaseNotes()
+		cmd.NextVersion = notesCmd.NextVersion
+	} else {
+		notesCmd := &buildReleaseNotesCmd{baseBuildReleaseNotesCmd: cmd.baseBuildReleaseNotesCmd}
+		notes, err = notesCmd.evalReleaseNotes()
+		cmd.NextVersion = notesCmd.NextVersion
+	}
+	if err != nil {
+		return err
+	}
+
+	generated := &bytes.Buffer{}
+	builtInTemplate := changelogReleaseNotesTemplate
+	if cmd.Categorized {
+		builtInTemplate = categorizedChangelogReleaseNotesTemplate
+	}
+	if err = cmd.writeMarkdown(generated, notes, builtInTemplate); err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(changelog)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return configErrorf("unable to read changelog: %w", err)
+	}
+	buildTime, _, err := cmd.getBuildTime()
+	if err != nil {
+		return err
+	}
+	updated, err := changelogFormat.updateChangelogSection(string(current), notes.Version, generated.String(), buildTime)
+	if err != nil {
+		return err
+	}
+	if updated == string(current) {
+		cmd.Infof("release notes for %v in %v are up to date\n", notes.Version, changelog)
+		return nil
+	}
+
+	cmd.Infof("updating release notes for %v in %v\n", notes.Version, changelog)
+	if cmd.dryRun {
+		cmd.Printf("%v", updated)
+		return nil
+	}
+	if err = os.WriteFile(changelog, []byte(updated), 0644); err != nil {
+		return configErrorf("unable to write changelog: %w", err)
+	}
+
+	if !cmd.commit {
+		return nil
+	}
+	return cmd.runGitCommands(
+		[]string{"set git username", "config", "user.name", cmd.Config.Git.Username},
+		[]string{"set git password", "config", "user.email", cmd.Config.Git.Email},
+		[]string{"add changelog to git", "add", changelog},
+		[]string{"commit changelog", "commit", "-m", fmt.Sprintf("Update changelog for %v", cmd.getVersionTag(cmd.NextVersion))},
+	)
+}
+
+// checkChangelog fails if the changelog committed at HEAD has no section for the next version
+func (cmd *updateChangelogCmd) checkChangelog(changelogFormat *changelogFormat, changelog string) error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
+
+	root, err := findRepoRoot()
+	if err != nil {
+		return gitErrorf("unable to find repository root: %w", err)
+	}
+	abs, err := filepath.Abs(changelog)
+	if err != nil {
+		return configErrorf("invalid changelog path %v: %w", changelog, err)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return configErrorf("changelog %v is not in the repository: %w", changelog, err)
+	}
+
+	g, err := cmd.getGit()
+	if err != nil {
+		return err
+	}
+	data, err := g.Show("HEAD", filepath.ToSlash(rel))
+	if err != nil {
+		return gitErrorf("unable to read committed changelog: %w", err)
+	}
+
+	version := cmd.NextVersion.String()
+	if changelogFormat.findSection(string(data), version) == nil {
+		return versionErrorf("changelog check failed: %v has no section for %v", changelog, version)
+	}
+	cmd.Infof("%v has a section for %v\n", changelog, version)
+	return nil
+}
+
+// updateChangelogSection returns the changelog with the generated release notes placed in the section for the
+// given version. An existing section keeps its heading and the prose above ChangelogMarker, or all of its text if
+// it has no marker. A new section is inserted before the first release section, below any Unreleased section
+func (f *changelogFormat) updateChangelogSection(changelog, version, generated string, date time.Time) (string, error) {
+	lines := strings.Split(changelog, "\n")
+	sections := f.parse(changelog)
+
+	var heading string
+	var prose []string
+	start, end := len(lines), len(lines)
+	if section := f.findSection(changelog, version); section != nil {
+		heading = section.Heading
+		start, end = section.start, section.end
+		prose = lines[start+1 : end]
+		for i, line := range prose {
+			if strings.TrimSpace(line) == ChangelogMarker {
+				prose = prose[:i]
+				break
+			}
+		}
+	} else {
+		if f.newHeading == nil {
+			return "", configErrorf("unable to add a section for %v: changelog heading format %v can't write headings", version, f.name)
+		}
+		heading = f.newHeading(strings.TrimPrefix(version, "v"), date)
+		for _, section := range sections {
+			if !section.isUnreleased() {
+				start, end = section.start, section.start
+				break
+			}
+		}
+	}
+
+	section := heading + "\n\n"
+	if text := strings.TrimSpace(strings.Join(prose, "\n")); text != "" {
+		section += text + "\n\n"
+	}
+	section += ChangelogMarker + "\n"
+	if text := strings.TrimSpace(generated); text != "" {
+		section += text + "\n"
+	}
+
+	before := strings.TrimRight(strings.Join(lines[:start], "\n"), "\n")
+	after := strings.TrimRight(strings.Join(lines[end:], "\n"), "\n")
+
+	result := section
+	if before != "" {
+		result = before + "\n\n" + result
+	}
+	if after != "" {
+		result += "\n" + after + "\n"
+	}
+	return result, nil
+}
+
+func newUpdateChangelogCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "update-changelog [changelog]",
+		Short: "Write the release notes of the next version into the changelog, CHANGELOG.md by default",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	result := &updateChangelogCmd{
+		baseBuildReleaseNotesCmd: baseBuildReleaseNotesCmd{
+			BaseCommand: BaseCommand{
+				RootCommand: root,
+				Cmd:         cobraCmd,
+			},
+		},
+	}
+
+	cobraCmd.Flags().BoolVarP(&result.AllCommits, "all-commits", "a", false, "Show all commits, not just closed issues")
+	cobraCmd.Flags().BoolVarP(&result.ShowUnchanged, "show-unchanged", "u", false, "Show upstream libraries matching release-notes.dependency-filter, even if unchanged")
+	cobraCmd.Flags().IntVarP(&result.Concurrency, "concurrency", "j", DefaultReleaseNotesConcurrency, "number of repositories whose changes are collected at the same time")
+	cobraCmd.Flags().IntVar(&result.MaxCommits, "max-commits", DefaultReleaseNotesMaxCommits, "maximum number of commits listed for a repository, or 0 for no limit")
+	cobraCmd.Flags().StringVar(&result.Template, "template", "", "text/template file used to render the release notes, instead of the built-in layout")
+	cobraCmd.Flags().BoolVarP(&result.Categorized, "categorized", "c", false, "group all commits into sections, such as Features and Fixes, by conventional commit type or issue labels")
+	cobraCmd.Flags().BoolVar(&result.GoModDiff, "go-mod-diff", false, "add a section listing every go.mod change, including indirect dependencies, replace directives and go version")
+	cobraCmd.Flags().BoolVar(&result.Contributors, "contributors", false, "add a section listing the authors of the release's commits, marking first-time contributors")
+	cobraCmd.Flags().String("vuln-db", "", "OSV vulnerability database url or directory, ex: https://vuln.go.dev, used to list the advisories fixed by dependency updates")
+	cobraCmd.Flags().BoolVar(&result.sdk, "sdk", false, "list the changes of all dependencies, as build-sdk-release-notes does")
+	cobraCmd.Flags().BoolVar(&result.ApiDiff, "api-diff", false, "with --sdk, add a section listing the changes to the module's exported API")
+	cobraCmd.Flags().BoolVar(&result.commit, "commit", false, "add and commit the updated changelog")
+	cobraCmd.Flags().String("heading-format", ChangelogFormatZiti, fmt.Sprintf("how release headings are recognized. Valid values: %v", changelogFormats))
+	cobraCmd.Flags().String("heading-regex", "", "regex matching release headings, with a version group, for --heading-format regex")
+	cobraCmd.Flags().BoolVar(&result.check, "check", false, "fail if the committed changelog has no section for the next version, instead of updating it")
+
+	return FinalizeErroringCmd(result)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUpdateChangelogSection(t *testing.T) {
+	req := require.New(t)
+
+	ziti, err := newChangelogFormat(ChangelogFormatZiti, "")
+	req.NoError(err)
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	update := func(format *changelogFormat, changelog, version, generated string) string {
+		result, err := format.updateChangelogSection(changelog, version, generated, date)
+		req.NoError(err)
+		return result
+	}
+
+	generated := "* github.com/openziti/edge: v0.1.0 -> v0.1.1\n    * [Issue #12](url) - Widgets\n\n"
+
+	// new changelog
+	req.Equal("# Release 0.3.1\n\n"+ChangelogMarker+"\n"+
+		"* github.com/openziti/edge: v0.1.0 -> v0.1.1\n    * [Issue #12](url) - Widgets\n",
+		update(ziti, "", "v0.3.1", generated))
+
+	// new section goes above the previous release, below any title
+	existing := "# Changelog\n\n# Release 0.3.0\n\n* older\n"
+	req.Equal("# Changelog\n\n# Release 0.3.1\n\n"+ChangelogMarker+"\n"+
+		"* github.com/openziti/edge: v0.1.0 -> v0.1.1\n    * [Issue #12](url) - Widgets\n\n"+
+		"# Release 0.3.0\n\n* older\n", update(ziti, existing, "0.3.1", generated))
+
+	// prose above the marker is kept, generated notes are replaced, and updating again changes nothing
+	withProse := "# Release 0.3.1 (unreleased)\n\nWidgets are faster.\n\n" + ChangelogMarker + "\n* stale\n\n# Release 0.3.0\n\n* older\n"
+	updated := update(ziti, withProse, "0.3.1", "* fresh\n")
+	req.Equal("# Release 0.3.1 (unreleased)\n\nWidgets are faster.\n\n"+ChangelogMarker+"\n* fresh\n\n# Release 0.3.0\n\n* older\n", updated)
+	req.Equal(updated, update(ziti, updated, "0.3.1", "* fresh\n"))
+
+	// a section without a marker keeps all of its text as prose
+	updated = update(ziti, "# Release 0.3.1\n\nHand written.\n", "0.3.1", "* fresh\n")
+	req.Equal("# Release 0.3.1\n\nHand written.\n\n"+ChangelogMarker+"\n* fresh\n", updated)
+
+	// keep a changelog sections go below Unreleased
+	kac, err := newChangelogFormat(ChangelogFormatKeepAChangelog, "")
+	req.NoError(err)
+	req.Equal("# Changelog\n\n## [Unreleased]\n\n## [0.3.1] - 2024-05-01\n\n"+ChangelogMarker+"\n* fresh\n\n## [0.3.0] - 2024-04-01\n",
+		update(kac, "# Changelog\n\n## [Unreleased]\n\n## [0.3.0] - 2024-04-01\n", "0.3.1", "* fresh\n"))
+
+	custom, err := newChangelogFormat(ChangelogFormatRegex, `^=== (?P<version>\S+)`)
+	req.NoError(err)
+	_, err = custom.updateChangelogSection("=== 0.3.0\n", "0.3.1", "* fresh\n", date)
+	req.Error(err)
+}
+
+func TestCheckChangelog(t *testing.T) {
+	req := require.New(t)
+	r := chdirTestRepo(t, "0.3", "v0.3.0")
+
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out}).newBaseCommand("update-changelog")
+	req.NoError(err)
+	cmd := &updateChangelogCmd{baseBuildReleaseNotesCmd: baseBuildReleaseNotesCmd{BaseCommand: *base}}
+	ziti, err := cmd.getChangelogFormat()
+	req.NoError(err)
+
+	err = cmd.checkChangelog(ziti, "CHANGELOG.md")
+	req.Error(err)
+	req.Equal(ExitCodeGit, ExitCode(err))
+
+	// only the committed changelog counts
+	req.NoError(os.WriteFile("CHANGELOG.md", []byte("# Release 0.3.1\n\n* notes\n"), 0644))
+	err = cmd.checkChangelog(ziti, "CHANGELOG.md")
+	req.Error(err)
+
+	r.commitAll("changelog")
+
+	req.NoError(cmd.checkChangelog(ziti, "CHANGELOG.md"))
+
+	r.commit("CHANGELOG.md", "# Release 0.3.0\n\n* notes\n", "changelog")
+	err = cmd.checkChangelog(ziti, "CHANGELOG.md")
+	req.Error(err)
+	req.Equal(ExitCodeVersion, ExitCode(err))
+}
@@ -58,8 +58,10 @@ type SdkBuildInfoCmd struct {
 	BaseCommand
 }
 
-func (cmd *SdkBuildInfoCmd) Execute() {
-	cmd.EvalCurrentAndNextVersion()
+func (cmd *SdkBuildInfoCmd) Execute() error {
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
+	}
 
 	tagVersion := fmt.Sprintf("v%v", cmd.NextVersion)
 
@@ -67,20 +69,23 @@ func (cmd *SdkBuildInfoCmd) Execute() {
 		Version: tagVersion,
 	}
 
+	outputFile := cmd.Config.Build.SdkBuildInfoFile
 	compiledTemplate, err := template.New("buildInfo").Parse(goSdkBuildInfoTemplate)
 	if err != nil {
-		cmd.Failf("failure compiling build info template %+v\n", err)
+		return fmt.Errorf("failure compiling build info template: %w", err)
 	}
 
-	file, err := os.Create("ziti/sdkinfo/build_info.go")
+	file, err := os.Create(outputFile)
 	if err != nil {
-		cmd.Failf("failure opening build info output file %v. err: %+v\n", cmd.Args[0], err)
+		return fmt.Errorf("failure opening build info output file %v. err: %w", outputFile, err)
 	}
+	defer cmd.close(file, outputFile)
 
 	err = compiledTemplate.Execute(file, buildInfo)
 	if err != nil {
-		cmd.Failf("failure executing build template to output file %v. err: %+v\n", cmd.Args[0], err)
+		return fmt.Errorf("failure executing build template to output file %v. err: %w", outputFile, err)
 	}
+	return nil
 }
 
 func newSdkBuildInfoCmd(root *RootCommand) *cobra.Command {
@@ -97,5 +102,5 @@ func newSdkBuildInfoCmd(root *RootCommand) *cobra.Command {
 		},
 	}
 
-	return Finalize(result)
+	return FinalizeErroringCmd(result)
 }
@@ -18,7 +18,6 @@
 package cmd
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
 )
 
@@ -27,14 +26,13 @@ type verifyCurrentVersionCmd struct {
 }
 
 func (cmd *verifyCurrentVersionCmd) Execute() error {
-	cmd.EvalCurrentAndNextVersion()
-
-	tagVersion := fmt.Sprintf("%v", cmd.CurrentVersion)
-	if cmd.isGoLang() {
-		tagVersion = "v" + tagVersion
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
 	}
+
+	tagVersion := cmd.getVersionTag(cmd.CurrentVersion)
 	if cmd.Args[0] != tagVersion {
-		return fmt.Errorf("version check failed: expected %v, got %v", tagVersion, cmd.Args[0])
+		return versionErrorf("version check failed: expected %v, got %v", tagVersion, cmd.Args[0])
 	}
 	return nil
 }
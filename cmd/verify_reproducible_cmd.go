@@ -0,0 +1,361 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileHashes maps file paths to the hex encoded sha256 of their contents
+type fileHashes map[string]string
+
+// hashFiles returns the hashes of the given file, or of all files below it if it's a directory
+func hashFiles(path string) (fileHashes, error) {
+	result := fileHashes{}
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return err
+		}
+		result[filePath] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash %v: %w", path, err)
+	}
+	return result, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// compareHashes returns a description of every file which differs between the two runs, sorted by path
+func compareHashes(first, second fileHashes) []string {
+	var result []string
+	for path, hash := range first {
+		if secondHash, found := second[path]; !found {
+			result = append(result, fmt.Sprintf("%v: only produced by the first run", path))
+		} else if hash != secondHash {
+			result = append(result, fmt.Sprintf("%v: sha256 %v != %v", path, hash, secondHash))
+		}
+	}
+	for path := range second {
+		if _, found := first[path]; !found {
+			result = append(result, fmt.Sprintf("%v: only produced by the second run", path))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (cmd *BaseCommand) reportReproducible(first, second fileHashes) error {
+	if diffs := compareHashes(first, second); len(diffs) > 0 {
+		for _, diff := range diffs {
+			cmd.Errorf("%v\n", diff)
+		}
+		return fmt.Errorf("outputs differ between runs in %v file(s), the build isn't reproducible", len(diffs))
+	}
+	var paths []string
+	for path := range first {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		cmd.Printf("%v  %v\n", first[path], path)
+	}
+	cmd.Infof("outputs are identical across runs\n")
+	return nil
+}
+
+type verifyReproducibleBuildCmd struct {
+	BaseCommand
+	outputs []string
+}
+
+func (cmd *verifyReproducibleBuildCmd) Execute() error {
+	if len(cmd.outputs) == 0 {
+		return configErrorf("no build outputs given, use --output to name the files or directories the build produces")
+	}
+	if err := checkBuildOutputs(cmd.outputs); err != nil {
+		return err
+	}
+
+	buildTime, _, err := cmd.getBuildTime()
+	if err != nil {
+		return err
+	}
+	// both runs see the same build time, even if it would otherwise be the current time
+	env := append(os.Environ(), fmt.Sprintf("%v=%v", SourceDateEpochEnvVar, buildTime.Unix()))
+
+	var runs []fileHashes
+	for run := 1; run <= 2; run++ {
+		cmd.Infof("build run %v of 2: %v\n", run, strings.Join(cmd.Args, " "))
+		command := exec.Command(cmd.Args[0], cmd.Args[1:]...)
+		command.Env = env
+		command.Stdout = cmd.Cmd.ErrOrStderr()
+		command.Stderr = cmd.Cmd.ErrOrStderr()
+		if err = command.Run(); err != nil {
+			return fmt.Errorf("build run %v failed: %w", run, err)
+		}
+
+		hashes := fileHashes{}
+		for _, output := range cmd.outputs {
+			outputHashes, err := hashFiles(output)
+			if err != nil {
+				return err
+			}
+			for path, hash := range outputHashes {
+				hashes[path] = hash
+			}
+		}
+		runs = append(runs, hashes)
+
+		if run == 1 {
+			// the second run starts without the outputs of the first, which are kept until the end
+			dirs, err := moveBuildOutputs(cmd.outputs)
+			defer func() {
+				for _, dir := range dirs {
+					_ = os.RemoveAll(dir)
+				}
+			}()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return cmd.reportReproducible(runs[0], runs[1])
+}
+
+// checkBuildOutputs makes sure the build outputs don't exist yet, so only files the build creates are moved. The
+// working directory, its parents and the repository root are rejected outright
+func checkBuildOutputs(outputs []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := findRepoRoot()
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		path, err := filepath.Abs(output)
+		if err != nil {
+			return configErrorf("invalid build output %v: %w", output, err)
+		}
+		if rel, err := filepath.Rel(path, wd); path == root || (err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return configErrorf("build output %v is the working directory, one of its parents or the repository root", output)
+		}
+		if _, err = os.Lstat(path); err == nil {
+			return configErrorf("build output %v already exists, remove it before verifying the build", output)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("unable to check build output %v: %w", output, err)
+		}
+	}
+	return nil
+}
+
+// moveBuildOutputs moves each build output into a new temporary directory next to it, returning the directories
+// created, including when it fails part way
+func moveBuildOutputs(outputs []string) ([]string, error) {
+	var dirs []string
+	for _, output := range outputs {
+		dir, err := os.MkdirTemp(filepath.Dir(output), ".ziti-ci-verify-")
+		if err != nil {
+			return dirs, fmt.Errorf("unable to create temporary directory for build output %v: %w", output, err)
+		}
+		dirs = append(dirs, dir)
+		if err = os.Rename(output, filepath.Join(dir, filepath.Base(output))); err != nil {
+			return dirs, fmt.Errorf("unable to move build output %v aside: %w", output, err)
+		}
+	}
+	return dirs, nil
+}
+
+type verifyReproduciblePackageCmd struct {
+	BaseCommand
+	zip bool
+}
+
+func (cmd *verifyReproduciblePackageCmd) Execute() error {
+	if _, reproducible, err := cmd.getBuildTime(); err != nil {
+		return err
+	} else if !reproducible {
+		cmd.Warnf("neither %v nor --build-time %v is set, archives carry the modification times of the files\n", SourceDateEpochEnvVar, BuildTimeCommit)
+	}
+
+	dir, err := os.MkdirTemp("", "ziti-ci-verify-")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	nameMap := map[string]string{}
+	for _, file := range cmd.Args {
+		nameMap[file] = filepath.Base(file)
+	}
+
+	var runs []fileHashes
+	for run := 1; run <= 2; run++ {
+		if run == 2 {
+			// a fresh checkout differs in modification times and umask, so the second run packages copies
+			// which differ in both. Otherwise the runs see identical inputs and always match
+			if nameMap, err = copyPackageInputs(filepath.Join(dir, "inputs"), nameMap); err != nil {
+				return err
+			}
+		}
+		archiveFile := filepath.Join(dir, fmt.Sprintf("run-%v.tar.gz", run))
+		if cmd.zip {
+			archiveFile = filepath.Join(dir, fmt.Sprintf("run-%v.zip", run))
+			err = cmd.BaseCommand.zip(archiveFile, nameMap)
+		} else {
+			err = cmd.tarGz(archiveFile, nameMap)
+		}
+		if err != nil {
+			return err
+		}
+		hash, err := hashFile(archiveFile)
+		if err != nil {
+			return fmt.Errorf("unable to hash %v: %w", archiveFile, err)
+		}
+		// compared by a common name, as each run writes its own file
+		runs = append(runs, fileHashes{"archive" + filepath.Ext(archiveFile): hash})
+	}
+
+	return cmd.reportReproducible(runs[0], runs[1])
+}
+
+// copyPackageInputs copies the files in the name map to the given directory, with later modification times and
+// flipped group and other write permissions, and returns the name map of the copies
+func copyPackageInputs(dir string, nameMap map[string]string) (map[string]string, error) {
+	result := map[string]string{}
+	for i, file := range sortedArchiveEntries(nameMap) {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to stat %v: %w", file, err)
+		}
+		// each file gets its own directory, as files from different directories may share a name
+		target := filepath.Join(dir, strconv.Itoa(i), filepath.Base(file))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("unable to create directory for copy of %v: %w", file, err)
+		}
+		if err = copyFile(file, target); err != nil {
+			return nil, err
+		}
+		if err = os.Chmod(target, info.Mode().Perm()^0022); err != nil {
+			return nil, fmt.Errorf("unable to change permissions of %v: %w", target, err)
+		}
+		modTime := info.ModTime().Add(25 * time.Hour)
+		if err = os.Chtimes(target, modTime, modTime); err != nil {
+			return nil, fmt.Errorf("unable to change modification time of %v: %w", target, err)
+		}
+		result[target] = nameMap[file]
+	}
+	return result, nil
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("unable to open %v: %w", source, err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("unable to create %v: %w", target, err)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("unable to copy %v to %v: %w", source, target, err)
+	}
+	return out.Close()
+}
+
+func newVerifyReproducibleCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "verify-reproducible",
+		Short: "Verify that builds or archives produce identical results when run twice",
+	}
+
+	cobraCmd.AddCommand(newVerifyReproducibleBuildCmd(root))
+	cobraCmd.AddCommand(newVerifyReproduciblePackageCmd(root))
+	return cobraCmd
+}
+
+func newVerifyReproducibleBuildCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "build --output <path> -- <command> [args]",
+		Short: "Run a build command twice and compare the outputs. SOURCE_DATE_EPOCH is set to the build time for both runs",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	result := &verifyReproducibleBuildCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().StringSliceVarP(&result.outputs, "output", "o", nil, "file or directory produced by the build, which mustn't exist yet. May be given more than once")
+
+	return FinalizeErroringCmd(result)
+}
+
+func newVerifyReproduciblePackageCmd(root *RootCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "package <files>",
+		Short: "Package the given files twice, as the package command does, and compare the archives",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	result := &verifyReproduciblePackageCmd{
+		BaseCommand: BaseCommand{
+			RootCommand: root,
+			Cmd:         cobraCmd,
+		},
+	}
+
+	cobraCmd.Flags().BoolVar(&result.zip, "zip", false, "create zip archives instead of gzipped tar archives")
+
+	return FinalizeErroringCmd(result)
+}
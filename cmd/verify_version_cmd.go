@@ -18,7 +18,6 @@
 package cmd
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
 )
 
@@ -27,14 +26,13 @@ type verifyVersionCmd struct {
 }
 
 func (cmd *verifyVersionCmd) Execute() error {
-	cmd.EvalCurrentAndNextVersion()
-
-	tagVersion := fmt.Sprintf("%v", cmd.NextVersion)
-	if cmd.isGoLang() {
-		tagVersion = "v" + tagVersion
+	if err := cmd.EvalCurrentAndNextVersion(); err != nil {
+		return err
 	}
+
+	tagVersion := cmd.getVersionTag(cmd.NextVersion)
 	if cmd.Args[0] != tagVersion {
-		return fmt.Errorf("version check failed: expected %v, got %v", tagVersion, cmd.Args[0])
+		return versionErrorf("version check failed: expected %v, got %v", tagVersion, cmd.Args[0])
 	}
 	return nil
 }
@@ -0,0 +1,483 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	VersionSourceAuto   = "auto"
+	VersionSourceFile   = "file"
+	VersionSourceMaven  = "maven"
+	VersionSourceGradle = "gradle"
+	VersionSourceNpm    = "npm"
+	VersionSourceCargo  = "cargo"
+	VersionSourceCMake  = "cmake"
+	VersionSourceHelm   = "helm"
+)
+
+var versionSourceKinds = []string{
+	VersionSourceAuto, VersionSourceFile, VersionSourceMaven, VersionSourceGradle, VersionSourceNpm,
+	VersionSourceCargo, VersionSourceCMake, VersionSourceHelm,
+}
+
+// versionSource reads and writes the base version of a project from wherever the project's build keeps it
+type versionSource interface {
+	Kind() string
+	Path() string
+	ReadVersion() (string, error)
+	WriteVersion(v string) error
+}
+
+// getVersionSourceCandidates returns the files which may hold the base version for the given kind of version source
+func getVersionSourceCandidates(kind string, dir string) []versionSource {
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	switch kind {
+	case VersionSourceMaven:
+		return []versionSource{&pomVersionSource{path: file("pom.xml")}}
+	case VersionSourceGradle:
+		return []versionSource{
+			newRegexVersionSource(VersionSourceGradle, file("gradle.properties"), `(?m)^\s*version\s*[=:]\s*([^\s#]+)`),
+			newRegexVersionSource(VersionSourceGradle, file("build.gradle"), `(?m)^\s*version\s*=?\s*['"]([^'"]+)['"]`),
+			newRegexVersionSource(VersionSourceGradle, file("build.gradle.kts"), `(?m)^\s*version\s*=\s*"([^"]+)"`),
+		}
+	case VersionSourceNpm:
+		return []versionSource{&packageJsonVersionSource{path: file("package.json")}}
+	case VersionSourceCargo:
+		return []versionSource{&cargoVersionSource{path: file("Cargo.toml")}}
+	case VersionSourceCMake:
+		return []versionSource{
+			newRegexVersionSource(VersionSourceCMake, file("CMakeLists.txt"), `(?is)\bproject\s*\([^)]*?\bVERSION\s+([0-9][0-9A-Za-z.+-]*)`),
+		}
+	case VersionSourceHelm:
+		return []versionSource{
+			newRegexVersionSource(VersionSourceHelm, file("Chart.yaml"), `(?m)^version:\s*["']?([^"'\s#]+)["']?`),
+		}
+	}
+	return nil
+}
+
+// getLanguageVersionSourceKinds returns the kinds of version sources to try for a language, in order of preference
+func getLanguageVersionSourceKinds(lang langType) []string {
+	switch lang {
+	case LangJava:
+		return []string{VersionSourceMaven, VersionSourceGradle}
+	case LangC:
+		return []string{VersionSourceCMake}
+	case LangRust:
+		return []string{VersionSourceCargo}
+	case LangNode:
+		return []string{VersionSourceNpm}
+	}
+	return nil
+}
+
+// findVersionSource returns the first of the given kinds of version sources whose file exists in dir
+func findVersionSource(dir string, kinds ...string) versionSource {
+	for _, kind := range kinds {
+		for _, candidate := range getVersionSourceCandidates(kind, dir) {
+			if _, err := candidate.ReadVersion(); err == nil {
+				return candidate
+			}
+		}
+	}
+	return nil
+}
+
+// getVersionSource returns the version source selected with --version-source. In auto mode the plain version
+// file wins if it exists, then the build files for the current language. Projects which aren't go projects
+// also have any other recognized build file probed
+func (cmd *BaseCommand) getVersionSource() (versionSource, error) {
+	if cmd.versionSourceKind == VersionSourceFile {
+		return &plainFileVersionSource{path: cmd.baseVersionFile}, nil
+	}
+
+	dir := cmd.getModuleFile(".")
+	if cmd.versionSourceKind != VersionSourceAuto {
+		if source := findVersionSource(dir, cmd.versionSourceKind); source != nil {
+			return source, nil
+		}
+		return nil, configErrorf("no %v version found in %v", cmd.versionSourceKind, dir)
+	}
+
+	if _, err := os.Stat(cmd.baseVersionFile); err == nil {
+		return &plainFileVersionSource{path: cmd.baseVersionFile}, nil
+	}
+
+	if source := findVersionSource(dir, getLanguageVersionSourceKinds(cmd.lang)...); source != nil {
+		return source, nil
+	}
+
+	if !cmd.isGoLang() {
+		if source := findVersionSource(dir, versionSourceKinds[2:]...); source != nil {
+			return source, nil
+		}
+	}
+
+	// fall back to the plain file, so the existing error reporting and fallbacks apply
+	return &plainFileVersionSource{path: cmd.baseVersionFile}, nil
+}
+
+func (cmd *BaseCommand) validateVersionSourceKind() error {
+	for _, kind := range versionSourceKinds {
+		if kind == cmd.versionSourceKind {
+			return nil
+		}
+	}
+	return configErrorf("unsupported version source: '%v'. Valid values: %v", cmd.versionSourceKind, versionSourceKinds)
+}
+
+// trimSnapshot removes the maven style -SNAPSHOT suffix, as a snapshot version names the release being worked toward
+func trimSnapshot(v string) string {
+	return strings.TrimSuffix(v, "-SNAPSHOT")
+}
+
+// keepSnapshot adds the -SNAPSHOT suffix to a version being written if the version it replaces had it, so that
+// maven and gradle builds keep producing snapshots
+func keepSnapshot(current, v string) string {
+	if strings.HasSuffix(current, "-SNAPSHOT") && !strings.HasSuffix(v, "-SNAPSHOT") {
+		return v + "-SNAPSHOT"
+	}
+	return v
+}
+
+// replaceRange writes the file with the bytes between start and end replaced by the given value
+func replaceRange(path string, data []byte, start, end int, value string) error {
+	var result []byte
+	result = append(result, data[:start]...)
+	result = append(result, value...)
+	result = append(result, data[end:]...)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, result, info.Mode())
+}
+
+type plainFileVersionSource struct {
+	path string
+}
+
+func (s *plainFileVersionSource) Kind() string {
+	return VersionSourceFile
+}
+
+func (s *plainFileVersionSource) Path() string {
+	return s.path
+}
+
+func (s *plainFileVersionSource) ReadVersion() (string, error) {
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (s *plainFileVersionSource) WriteVersion(v string) error {
+	return os.WriteFile(s.path, []byte(v+"\n"), 0644)
+}
+
+// regexVersionSource handles build files where the version can be found with a regular expression. The first
+// capture group of the expression must match the version
+type regexVersionSource struct {
+	kind  string
+	path  string
+	regex *regexp.Regexp
+}
+
+func newRegexVersionSource(kind, path, regex string) *regexVersionSource {
+	return &regexVersionSource{
+		kind:  kind,
+		path:  path,
+		regex: regexp.MustCompile(regex),
+	}
+}
+
+func (s *regexVersionSource) Kind() string {
+	return s.kind
+}
+
+func (s *regexVersionSource) Path() string {
+	return s.path
+}
+
+func (s *regexVersionSource) find() ([]byte, []int, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	match := s.regex.FindSubmatchIndex(data)
+	if match == nil {
+		return nil, nil, errors.Errorf("no version found in %v", s.path)
+	}
+	return data, match, nil
+}
+
+func (s *regexVersionSource) ReadVersion() (string, error) {
+	data, match, err := s.find()
+	if err != nil {
+		return "", err
+	}
+	return trimSnapshot(string(data[match[2]:match[3]])), nil
+}
+
+func (s *regexVersionSource) WriteVersion(v string) error {
+	data, match, err := s.find()
+	if err != nil {
+		return err
+	}
+	return replaceRange(s.path, data, match[2], match[3], keepSnapshot(string(data[match[2]:match[3]]), v))
+}
+
+// pomVersionSource handles the project version of a maven pom.xml. The version of the parent pom is ignored
+type pomVersionSource struct {
+	path string
+}
+
+func (s *pomVersionSource) Kind() string {
+	return VersionSourceMaven
+}
+
+func (s *pomVersionSource) Path() string {
+	return s.path
+}
+
+func (s *pomVersionSource) find() ([]byte, int, int, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, 0, 0, errors.Errorf("no project version found in %v", s.path)
+		}
+		if err != nil {
+			return nil, 0, 0, errors.Wrapf(err, "unable to parse %v", s.path)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if len(stack) == 2 && stack[0] == "project" && stack[1] == "version" {
+				start := int(decoder.InputOffset())
+				token, err = decoder.Token()
+				if err != nil {
+					return nil, 0, 0, errors.Wrapf(err, "unable to parse %v", s.path)
+				}
+				if _, ok := token.(xml.CharData); !ok {
+					return nil, 0, 0, errors.Errorf("empty project version in %v", s.path)
+				}
+				end := int(decoder.InputOffset())
+				return data, start, end, nil
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func (s *pomVersionSource) ReadVersion() (string, error) {
+	data, start, end, err := s.find()
+	if err != nil {
+		return "", err
+	}
+	return trimSnapshot(strings.TrimSpace(string(data[start:end]))), nil
+}
+
+func (s *pomVersionSource) WriteVersion(v string) error {
+	data, start, end, err := s.find()
+	if err != nil {
+		return err
+	}
+	return replaceRange(s.path, data, start, end, keepSnapshot(strings.TrimSpace(string(data[start:end])), v))
+}
+
+// packageJsonVersionSource handles the top level version field of a package.json
+type packageJsonVersionSource struct {
+	path string
+}
+
+func (s *packageJsonVersionSource) Kind() string {
+	return VersionSourceNpm
+}
+
+func (s *packageJsonVersionSource) Path() string {
+	return s.path
+}
+
+func (s *packageJsonVersionSource) find() ([]byte, string, int, int, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	expectKey := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, "", 0, 0, errors.Errorf("no version found in %v", s.path)
+		}
+		if err != nil {
+			return nil, "", 0, 0, errors.Wrapf(err, "unable to parse %v", s.path)
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+				expectKey = depth == 1
+			} else {
+				depth--
+				expectKey = depth == 1
+			}
+			continue
+		}
+
+		if depth != 1 {
+			continue
+		}
+
+		if !expectKey {
+			expectKey = true
+			continue
+		}
+
+		expectKey = false
+		if key, ok := token.(string); ok && key == "version" {
+			keyEnd := int(decoder.InputOffset())
+			value, err := decoder.Token()
+			if err != nil {
+				return nil, "", 0, 0, errors.Wrapf(err, "unable to parse %v", s.path)
+			}
+			versionStr, ok := value.(string)
+			if !ok {
+				return nil, "", 0, 0, errors.Errorf("version in %v is not a string", s.path)
+			}
+			end := int(decoder.InputOffset())
+			start := keyEnd + bytes.IndexByte(data[keyEnd:end], '"')
+			return data, versionStr, start, end, nil
+		}
+	}
+}
+
+func (s *packageJsonVersionSource) ReadVersion() (string, error) {
+	_, v, _, _, err := s.find()
+	return v, err
+}
+
+func (s *packageJsonVersionSource) WriteVersion(v string) error {
+	data, _, start, end, err := s.find()
+	if err != nil {
+		return err
+	}
+	return replaceRange(s.path, data, start, end, strconv.Quote(v))
+}
+
+// cargoVersionSource handles the version in the [package] or [workspace.package] section of a Cargo.toml
+type cargoVersionSource struct {
+	path string
+}
+
+var cargoVersionRegex = regexp.MustCompile(`^\s*version\s*=\s*"([^"]*)"`)
+
+func (s *cargoVersionSource) Kind() string {
+	return VersionSourceCargo
+}
+
+func (s *cargoVersionSource) Path() string {
+	return s.path
+}
+
+func (s *cargoVersionSource) find() ([]byte, int, int, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(scanLinesWithEndings)
+	offset := 0
+	inPackage := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inPackage = trimmed == "[package]" || trimmed == "[workspace.package]"
+		} else if inPackage {
+			if match := cargoVersionRegex.FindStringSubmatchIndex(line); match != nil {
+				return data, offset + match[2], offset + match[3], nil
+			}
+		}
+		offset += len(line)
+	}
+	return nil, 0, 0, errors.Errorf("no package version found in %v", s.path)
+}
+
+func (s *cargoVersionSource) ReadVersion() (string, error) {
+	data, start, end, err := s.find()
+	if err != nil {
+		return "", err
+	}
+	return string(data[start:end]), nil
+}
+
+func (s *cargoVersionSource) WriteVersion(v string) error {
+	data, start, end, err := s.find()
+	if err != nil {
+		return err
+	}
+	return replaceRange(s.path, data, start, end, v)
+}
+
+// scanLinesWithEndings is like bufio.ScanLines, but keeps the line endings so offsets can be tracked
+func scanLinesWithEndings(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func describeVersionSource(source versionSource) string {
+	return fmt.Sprintf("%v (%v)", source.Path(), source.Kind())
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPom = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <parent>
+        <groupId>org.openziti</groupId>
+        <artifactId>parent</artifactId>
+        <version>9.9.9</version>
+    </parent>
+    <artifactId>ziti-sdk</artifactId>
+    <version>0.25.1-SNAPSHOT</version>
+    <dependencies>
+        <dependency>
+            <version>1.0.0</version>
+        </dependency>
+    </dependencies>
+</project>
+`
+
+const testPackageJson = `{
+  "name": "ziti-sdk",
+  "config": { "version": "0.0.1" },
+  "files": ["a", {"version": "0.0.2"}],
+  "version": "1.4.2",
+  "dependencies": {}
+}
+`
+
+const testCargoToml = `[workspace]
+members = ["a"]
+
+[package]
+name = "ziti"
+authors = ["openziti"]
+version = "0.3.1"
+
+[dependencies]
+serde = { version = "1.0" }
+`
+
+const testCMakeLists = `cmake_minimum_required(VERSION 3.14)
+project(ziti-sdk
+        DESCRIPTION "OpenZiti C SDK"
+        VERSION 0.35.2
+        LANGUAGES C CXX)
+`
+
+const testChartYaml = `apiVersion: v2
+name: ziti-router
+appVersion: "1.1.3"
+version: 1.0.7
+`
+
+func TestVersionSources(t *testing.T) {
+	tests := []struct {
+		kind     string
+		file     string
+		contents string
+		raw      string
+		version  string
+	}{
+		{VersionSourceMaven, "pom.xml", testPom, "0.25.1-SNAPSHOT", "0.25.1"},
+		{VersionSourceNpm, "package.json", testPackageJson, "1.4.2", "1.4.2"},
+		{VersionSourceCargo, "Cargo.toml", testCargoToml, "0.3.1", "0.3.1"},
+		{VersionSourceCMake, "CMakeLists.txt", testCMakeLists, "0.35.2", "0.35.2"},
+		{VersionSourceHelm, "Chart.yaml", testChartYaml, "1.0.7", "1.0.7"},
+		{VersionSourceGradle, "gradle.properties", "group=org.openziti\nversion=0.26.0-SNAPSHOT\n", "0.26.0-SNAPSHOT", "0.26.0"},
+		{VersionSourceGradle, "build.gradle", "plugins {}\n\nversion = '0.27.3'\n", "0.27.3", "0.27.3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			req := require.New(t)
+			dir := t.TempDir()
+			req.NoError(os.WriteFile(filepath.Join(dir, test.file), []byte(test.contents), 0644))
+
+			source := findVersionSource(dir, test.kind)
+			req.NotNil(source)
+			req.Equal(test.kind, source.Kind())
+
+			v, err := source.ReadVersion()
+			req.NoError(err)
+			req.Equal(test.version, v)
+
+			req.NoError(source.WriteVersion("2.0.0"))
+			v, err = source.ReadVersion()
+			req.NoError(err)
+			req.Equal("2.0.0", v)
+
+			// everything other than the version must be left untouched, including a -SNAPSHOT suffix
+			written := "2.0.0"
+			if strings.HasSuffix(test.raw, "-SNAPSHOT") {
+				written += "-SNAPSHOT"
+			}
+			data, err := os.ReadFile(filepath.Join(dir, test.file))
+			req.NoError(err)
+			req.Equal(strings.Replace(test.contents, test.raw, written, 1), string(data))
+		})
+	}
+}
@@ -18,17 +18,22 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/hashicorp/go-version"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 const (
+	Major = 0
 	Minor = 1
 	Patch = 2
 )
 
-func setPatch(v *version.Version, patch int) *version.Version {
+func setPatch(v *version.Version, patch int) (*version.Version, error) {
 	parts := v.Segments()
 	for len(parts) < 3 {
 		parts = append(parts, 0)
@@ -37,28 +42,93 @@ func setPatch(v *version.Version, patch int) *version.Version {
 	return newVersion(parts)
 }
 
-func getNext(index int, v *version.Version) *version.Version {
+func getNext(index int, v *version.Version) (*version.Version, error) {
+	if index < 0 {
+		return nil, fmt.Errorf("invalid version segment %v", index)
+	}
 	parts := v.Segments()
 	for len(parts) < 3 && len(parts) < (index+1) {
 		parts = append(parts, 0)
 	}
 	parts[index] = parts[index] + 1
+	for i := index + 1; i < len(parts); i++ {
+		parts[i] = 0
+	}
 	return newVersion(parts)
 }
 
-func newVersion(parts []int) *version.Version {
+func newVersion(parts []int) (*version.Version, error) {
 	var stringParts []string
 	for _, part := range parts {
 		stringParts = append(stringParts, strconv.Itoa(part))
 	}
-	versionString := strings.Join(stringParts, ".")
-	result, err := version.NewVersion(versionString)
+	return version.NewVersion(strings.Join(stringParts, "."))
+}
+
+// nextPrerelease returns the next pre-release of the given release version on the given channel, along with the
+// most recent existing pre-release of that release version on any channel, if there is one
+func nextPrerelease(release *version.Version, channel string, versions []*version.Version) (*version.Version, *version.Version, error) {
+	channelRegex := regexp.MustCompile(`^` + regexp.QuoteMeta(channel) + `\.(\d+)$`)
+	core := release.Core()
+
+	var latest *version.Version
+	maxN := 0
+	for _, v := range versions {
+		if v.Prerelease() == "" || !v.Core().Equal(core) {
+			continue
+		}
+		if latest == nil || latest.LessThan(v) {
+			latest = v
+		}
+		if match := channelRegex.FindStringSubmatch(v.Prerelease()); match != nil {
+			if n, err := strconv.Atoi(match[1]); err == nil && n > maxN {
+				maxN = n
+			}
+		}
+	}
+
+	next, err := version.NewVersion(fmt.Sprintf("%v-%v.%v", core, channel, maxN+1))
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+	return next, latest, nil
+}
+
+func isSameMinorLine(a, b *version.Version) bool {
+	aParts := a.Segments()
+	bParts := b.Segments()
+	return aParts[0] == bParts[0] && aParts[1] == bParts[1]
+}
+
+func filterReleaseVersions(versions []*version.Version) []*version.Version {
+	var result []*version.Version
+	for _, v := range versions {
+		if v.Prerelease() == "" {
+			result = append(result, v)
+		}
 	}
 	return result
 }
 
+var majorVersionDirRegex = regexp.MustCompile(`^v\d+$`)
+
+// moduleTagPrefix returns the prefix of the version tags for the module in the given directory, relative to the
+// repository root. As with go, a trailing major version directory isn't part of it, ex: sdk/ for sdk/v2
+func moduleTagPrefix(dir string) string {
+	dir = strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+	if dir == "." {
+		return ""
+	}
+	parts := strings.Split(dir, "/")
+	if majorVersionDirRegex.MatchString(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
 type versionList []*version.Version
 
 func (list versionList) Len() int {
@@ -0,0 +1,116 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNextPrerelease(t *testing.T) {
+	req := require.New(t)
+	v := func(s ...string) []*version.Version {
+		var result []*version.Version
+		for _, str := range s {
+			result = append(result, version.Must(version.NewVersion(str)))
+		}
+		return result
+	}
+
+	release := v("1.2.0")[0]
+
+	next, latest, err := nextPrerelease(release, "rc", v("1.1.0", "1.1.1"))
+	req.NoError(err)
+	req.Equal("1.2.0-rc.1", next.String())
+	req.Nil(latest)
+
+	next, latest, err = nextPrerelease(release, "rc", v("1.1.0", "1.2.0-rc.1", "1.2.0-rc.2", "1.1.1-rc.7"))
+	req.NoError(err)
+	req.Equal("1.2.0-rc.3", next.String())
+	req.Equal("1.2.0-rc.2", latest.String())
+
+	next, latest, err = nextPrerelease(release, "beta", v("1.2.0-beta.9", "1.2.0-beta.10", "1.2.0-rc.1"))
+	req.NoError(err)
+	req.Equal("1.2.0-beta.11", next.String())
+	req.Equal("1.2.0-rc.1", latest.String())
+
+	_, _, err = nextPrerelease(release, "rc 1", nil)
+	req.Error(err)
+
+	req.Len(filterReleaseVersions(v("1.1.0", "1.2.0-rc.1", "1.2.0")), 2)
+}
+
+func TestModuleTagPrefix(t *testing.T) {
+	req := require.New(t)
+	v2 := version.Must(version.NewVersion("2.0.0"))
+	for dir, tag := range map[string]string{
+		"":              "v2.0.0",
+		".":             "v2.0.0",
+		"v2":            "v2.0.0",
+		"v2/":           "v2.0.0",
+		"sdk":           "sdk/v2.0.0",
+		"./sdk/":        "sdk/v2.0.0",
+		"sdk/v2":        "sdk/v2.0.0",
+		"sdk/v2/":       "sdk/v2.0.0",
+		"tunnel/core":   "tunnel/core/v2.0.0",
+		"tunnel/v2/api": "tunnel/v2/api/v2.0.0",
+		"v2beta":        "v2beta/v2.0.0",
+	} {
+		cmd := &BaseCommand{RootCommand: &RootCommand{lang: LangGo, moduleDir: dir}}
+		req.Equal(tag, cmd.getVersionTag(v2), dir)
+	}
+
+	root := t.TempDir()
+	for dir, modulePath := range map[string]string{
+		"v2":     "github.com/openziti/foo/v2",
+		"sdk/v2": "github.com/openziti/foo/sdk/v2",
+	} {
+		req.NoError(os.MkdirAll(filepath.Join(root, dir), 0755))
+		req.NoError(os.WriteFile(filepath.Join(root, dir, "go.mod"), []byte("module "+modulePath+"\n"), 0644))
+	}
+	modules, err := findModules(root)
+	req.NoError(err)
+	prefixes := map[string]string{}
+	for _, module := range modules {
+		prefixes[module.Dir] = module.TagPrefix
+	}
+	req.Equal(map[string]string{"v2": "", "sdk/v2": "sdk/"}, prefixes)
+}
+
+func TestGetModuleTagPrefix(t *testing.T) {
+	req := require.New(t)
+	req.Equal("", getModuleTagPrefix("github.com/openziti/ziti"))
+	req.Equal("", getModuleTagPrefix("github.com/openziti/sdk-golang/v2"))
+	req.Equal("sdk/", getModuleTagPrefix("github.com/openziti/foo/sdk"))
+	req.Equal("sdk/", getModuleTagPrefix("github.com/openziti/foo/sdk/v2"))
+	req.Equal("tunnel/core/", getModuleTagPrefix("github.com/openziti/foo/tunnel/core"))
+}
+
+func TestGetModuleProject(t *testing.T) {
+	req := require.New(t)
+	project, err := getModuleProject("github.com/openziti/foo/sdk/v2")
+	req.NoError(err)
+	req.Equal("foo", project)
+
+	_, err = getModuleProject("go.uber.org/zap")
+	req.Error(err)
+	req.Equal(ExitCodeConfig, ExitCode(err))
+}
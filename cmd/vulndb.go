@@ -0,0 +1,355 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/mod/semver"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultVulnDb is the Go vulnerability database, which audit-deps uses if vulnerabilities.db isn't set
+const DefaultVulnDb = "https://vuln.go.dev"
+
+// vulnTemplateDefinitions render the advisories of a component or module change, and are available to all release
+// notes templates
+const vulnTemplateDefinitions = `
+{{- define "advisory" -}}
+[{{.ID}}]({{.Url}}){{with .Aliases}} ({{join . ", "}}){{end}}{{with .Summary}} - {{.}}{{end}}{{with .FixedIn}}, fixed in {{.}}{{end}}
+{{- end}}
+
+{{- define "vulns" -}}
+{{- range .FixedVulns}}    * fixes {{template "advisory" .}}
+{{end}}
+{{- range .OpenVulns}}    * still affected by {{template "advisory" .}}
+{{end}}
+{{- end}}`
+
+// Advisory is a published vulnerability affecting a module
+type Advisory struct {
+	ID      string   `json:"id" yaml:"id"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Url     string   `json:"url,omitempty" yaml:"url,omitempty"`
+	// FixedIn is the earliest later version of the module which isn't affected, for advisories affecting a version
+	FixedIn string `json:"fixedIn,omitempty" yaml:"fixedIn,omitempty"`
+}
+
+// osvEntry is a vulnerability in the OSV format, see https://ossf.github.io/osv-schema/
+type osvEntry struct {
+	ID               string        `json:"id"`
+	Aliases          []string      `json:"aliases"`
+	Summary          string        `json:"summary"`
+	Details          string        `json:"details"`
+	Withdrawn        string        `json:"withdrawn"`
+	Affected         []osvAffected `json:"affected"`
+	DatabaseSpecific struct {
+		Url string `json:"url"`
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string     `json:"type"`
+		Events []osvEvent `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+type osvEvent struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// version returns the event's version in Go's semver form, ex: v1.2.3 for 1.2.3, and an empty string for the
+// start of history
+func (e osvEvent) version() string {
+	v := e.Fixed
+	if v == "" {
+		v = e.Introduced
+	}
+	if v == "" || v == "0" {
+		return ""
+	}
+	return "v" + strings.TrimPrefix(v, "v")
+}
+
+// advisory returns the entry's summary
+func (e *osvEntry) advisory() *Advisory {
+	result := &Advisory{ID: e.ID, Aliases: e.Aliases, Summary: e.Summary, Url: e.DatabaseSpecific.Url}
+	if result.Summary == "" {
+		result.Summary = strings.SplitN(strings.TrimSpace(e.Details), "\n", 2)[0]
+	}
+	if result.Url == "" {
+		result.Url = "https://osv.dev/vulnerability/" + e.ID
+	}
+	return result
+}
+
+// affects returns true if the given version of a module is vulnerable
+func (e *osvEntry) affects(modulePath, version string) bool {
+	if e.Withdrawn != "" || version == "" {
+		return false
+	}
+	for _, affected := range e.Affected {
+		if affected.Package.Name != modulePath || !strings.EqualFold(affected.Package.Ecosystem, "Go") {
+			continue
+		}
+		for _, v := range affected.Versions {
+			if semver.Compare("v"+strings.TrimPrefix(v, "v"), version) == 0 {
+				return true
+			}
+		}
+		for _, r := range affected.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			events := append([]osvEvent(nil), r.Events...)
+			sort.SliceStable(events, func(i, j int) bool {
+				return semver.Compare(events[i].version(), events[j].version()) < 0
+			})
+			vulnerable := false
+			for _, event := range events {
+				if semver.Compare(version, event.version()) < 0 {
+					break
+				}
+				vulnerable = event.Introduced != ""
+			}
+			if vulnerable {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fixedIn returns the earliest version of a module after the given one in which the vulnerability is fixed, or an
+// empty string if there's none
+func (e *osvEntry) fixedIn(modulePath, version string) string {
+	result := ""
+	for _, affected := range e.Affected {
+		if affected.Package.Name != modulePath {
+			continue
+		}
+		for _, r := range affected.Ranges {
+			for _, event := range r.Events {
+				if event.Fixed == "" {
+					continue
+				}
+				v := event.version()
+				if semver.Compare(v, version) > 0 && (result == "" || semver.Compare(v, result) < 0) {
+					result = v
+				}
+			}
+		}
+	}
+	return result
+}
+
+// vulnDb reads an OSV vulnerability database. A url or directory laid out as https://vuln.go.dev is, with an
+// index/modules.json listing the advisories of each module and an ID/<id>.json per advisory, is read as needed.
+// A directory without an index is read in full, from every .json file under it, ex: an export from osv.dev
+type vulnDb struct {
+	source string
+	local  bool
+	http   *resty.Client
+	// index maps module paths to the ids of the advisories affecting them
+	index   map[string][]string
+	entries map[string]*osvEntry
+}
+
+func (cmd *BaseCommand) openVulnDb(source string) (*vulnDb, error) {
+	result := &vulnDb{
+		source:  strings.TrimSuffix(source, "/"),
+		http:    resty.New().SetTimeout(time.Minute),
+		index:   map[string][]string{},
+		entries: map[string]*osvEntry{},
+	}
+	if path, found := strings.CutPrefix(result.source, "file://"); found {
+		result.source = path
+		result.local = true
+	} else if !strings.Contains(result.source, "://") {
+		result.local = true
+	}
+
+	data, err := result.read("index/modules.json")
+	if result.local && errors.Is(err, fs.ErrNotExist) {
+		return result, result.loadDir()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var modules []struct {
+		Path  string `json:"path"`
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	}
+	if err = json.Unmarshal(data, &modules); err != nil {
+		return nil, configErrorf("invalid vulnerability database index in %v: %w", source, err)
+	}
+	for _, m := range modules {
+		for _, vuln := range m.Vulns {
+			result.index[m.Path] = append(result.index[m.Path], vuln.ID)
+		}
+	}
+	return result, nil
+}
+
+// read returns a file of the database, given its path relative to the database root
+func (db *vulnDb) read(name string) ([]byte, error) {
+	if db.local {
+		data, err := os.ReadFile(filepath.Join(db.source, filepath.FromSlash(name)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, configErrorf("unable to read vulnerability database: %w", err)
+		}
+		return data, err
+	}
+	resp, err := db.http.R().Get(db.source + "/" + name)
+	if err != nil {
+		return nil, networkErrorf("unable to read vulnerability database: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, networkErrorf("vulnerability database request to %v returned %v", resp.Request.URL, resp.Status())
+	}
+	return resp.Body(), nil
+}
+
+// loadDir reads every OSV entry in a directory without an index
+func (db *vulnDb) loadDir() error {
+	err := filepath.WalkDir(db.source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		entry := &osvEntry{}
+		if err = json.Unmarshal(data, entry); err != nil || entry.ID == "" {
+			return nil
+		}
+		db.entries[entry.ID] = entry
+		for _, affected := range entry.Affected {
+			db.index[affected.Package.Name] = append(db.index[affected.Package.Name], entry.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return configErrorf("unable to read vulnerability database %v: %w", db.source, err)
+	}
+	return nil
+}
+
+// getEntries returns the advisories which list the given module
+func (db *vulnDb) getEntries(modulePath string) ([]*osvEntry, error) {
+	var result []*osvEntry
+	for _, id := range db.index[modulePath] {
+		entry, found := db.entries[id]
+		if !found {
+			data, err := db.read("ID/" + id + ".json")
+			if err != nil {
+				return nil, err
+			}
+			entry = &osvEntry{}
+			if err = json.Unmarshal(data, entry); err != nil {
+				return nil, configErrorf("invalid vulnerability database entry %v: %w", id, err)
+			}
+			db.entries[id] = entry
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// checkUpdate returns the advisories affecting oldVersion of a module but not newVersion, and those affecting
+// newVersion. An empty oldVersion is a newly added module
+func (db *vulnDb) checkUpdate(modulePath, oldVersion, newVersion string) ([]*Advisory, []*Advisory, error) {
+	entries, err := db.getEntries(modulePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fixed, open []*Advisory
+	for _, entry := range entries {
+		if entry.affects(modulePath, newVersion) {
+			advisory := entry.advisory()
+			advisory.FixedIn = entry.fixedIn(modulePath, newVersion)
+			open = append(open, advisory)
+		} else if entry.affects(modulePath, oldVersion) {
+			fixed = append(fixed, entry.advisory())
+		}
+	}
+	return fixed, open, nil
+}
+
+// annotateVulnerabilities adds the advisories fixed by, and still affecting, each dependency update in the notes,
+// if a vulnerability database is configured
+func (cmd *baseBuildReleaseNotesCmd) annotateVulnerabilities(notes *ReleaseNotes) error {
+	if cmd.Config.Vulnerabilities.Db == "" {
+		return nil
+	}
+	db, err := cmd.openVulnDb(cmd.Config.Vulnerabilities.Db)
+	if err != nil {
+		return err
+	}
+	for _, component := range notes.Components {
+		if component.Current {
+			continue
+		}
+		if component.FixedVulns, component.OpenVulns, err = db.checkUpdate(component.Module, component.OldVersion, component.NewVersion); err != nil {
+			return err
+		}
+	}
+	if notes.GoMod != nil {
+		for _, changes := range [][]*ModuleChange{notes.GoMod.Direct, notes.GoMod.Indirect} {
+			for _, change := range changes {
+				if change.FixedVulns, change.OpenVulns, err = db.checkUpdate(change.Path, change.OldVersion, change.NewVersion); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func formatAdvisory(advisory *Advisory) string {
+	result := advisory.ID
+	if len(advisory.Aliases) > 0 {
+		result += fmt.Sprintf(" (%v)", strings.Join(advisory.Aliases, ", "))
+	}
+	if advisory.Summary != "" {
+		result += ": " + advisory.Summary
+	}
+	if advisory.FixedIn != "" {
+		result += ", fixed in " + advisory.FixedIn
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright NetFoundry, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testAdvisories = map[string]string{
+	"GO-2024-0001": `{
+  "id": "GO-2024-0001",
+  "aliases": ["CVE-2024-1111"],
+  "summary": "Panic parsing widgets",
+  "affected": [{
+    "package": {"ecosystem": "Go", "name": "example.com/widgets"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.1"}, {"introduced": "1.1.0"}, {"fixed": "1.1.2"}]}]
+  }],
+  "database_specific": {"url": "https://pkg.go.dev/vuln/GO-2024-0001"}
+}`,
+	"GO-2024-0002": `{
+  "id": "GO-2024-0002",
+  "details": "Widgets leak memory.\nMore details.",
+  "affected": [{
+    "package": {"ecosystem": "Go", "name": "example.com/widgets"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.2.0"}]}]
+  }]
+}`,
+}
+
+// writeVulnDb writes the test advisories to a directory, laid out as vuln.go.dev if indexed
+func writeVulnDb(t *testing.T, indexed bool) string {
+	req := require.New(t)
+	dir := t.TempDir()
+	req.NoError(os.MkdirAll(filepath.Join(dir, "ID"), 0755))
+	for id, entry := range testAdvisories {
+		req.NoError(os.WriteFile(filepath.Join(dir, "ID", id+".json"), []byte(entry), 0644))
+	}
+	if indexed {
+		req.NoError(os.MkdirAll(filepath.Join(dir, "index"), 0755))
+		index := `[{"path": "example.com/widgets", "vulns": [{"id": "GO-2024-0001"}, {"id": "GO-2024-0002"}]}]`
+		req.NoError(os.WriteFile(filepath.Join(dir, "index", "modules.json"), []byte(index), 0644))
+	}
+	return dir
+}
+
+func TestVulnDb(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	out := &bytes.Buffer{}
+	cmd, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+
+	server := httptest.NewServer(http.FileServer(http.Dir(writeVulnDb(t, true))))
+	defer server.Close()
+
+	for _, source := range []string{writeVulnDb(t, true), "file://" + writeVulnDb(t, false), server.URL} {
+		db, err := cmd.openVulnDb(source)
+		req.NoError(err, source)
+
+		ids := func(advisories []*Advisory) []string {
+			var result []string
+			for _, advisory := range advisories {
+				result = append(result, advisory.ID)
+			}
+			return result
+		}
+		fixed, open, err := db.checkUpdate("example.com/widgets", "v1.0.0", "v1.1.1")
+		req.NoError(err)
+		req.Equal([]string(nil), ids(fixed))
+		req.ElementsMatch([]string{"GO-2024-0001", "GO-2024-0002"}, ids(open))
+
+		fixed, open, err = db.checkUpdate("example.com/widgets", "v1.0.0", "v1.2.0")
+		req.NoError(err)
+		req.ElementsMatch([]string{"GO-2024-0001", "GO-2024-0002"}, ids(fixed))
+		req.Empty(open)
+
+		fixed, open, err = db.checkUpdate("example.com/widgets", "v1.0.1", "v1.1.0")
+		req.NoError(err)
+		req.Empty(fixed)
+		req.ElementsMatch([]string{"GO-2024-0001", "GO-2024-0002"}, ids(open))
+		for _, advisory := range open {
+			if advisory.ID == "GO-2024-0001" {
+				req.Equal("v1.1.2", advisory.FixedIn)
+				req.Equal("https://pkg.go.dev/vuln/GO-2024-0001", advisory.Url)
+			} else {
+				req.Equal("v1.2.0", advisory.FixedIn)
+				req.Equal("Widgets leak memory.", advisory.Summary)
+				req.Equal("https://osv.dev/vulnerability/GO-2024-0002", advisory.Url)
+			}
+		}
+
+		_, open, err = db.checkUpdate("example.com/gadgets", "v1.0.0", "v1.1.0")
+		req.NoError(err)
+		req.Empty(open)
+	}
+}
+
+func TestAnnotateVulnerabilities(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	out := &bytes.Buffer{}
+	base, err := (&Options{Out: out, Err: out}).newBaseCommand("test")
+	req.NoError(err)
+	base.Config.Vulnerabilities.Db = writeVulnDb(t, true)
+	cmd := &baseBuildReleaseNotesCmd{BaseCommand: *base}
+	cmd.quiet = true
+
+	notes := &ReleaseNotes{Version: "0.3.1", Components: []*ReleaseNotesComponent{
+		newComponent("example.com/widgets", ComponentStatusChanged, "v1.0.0", "v1.1.5"),
+	}}
+	req.NoError(cmd.annotateVulnerabilities(notes))
+
+	data, err := json.Marshal(notes.Components[0].FixedVulns)
+	req.NoError(err)
+	req.JSONEq(`[{"id": "GO-2024-0001", "aliases": ["CVE-2024-1111"], "summary": "Panic parsing widgets", "url": "https://pkg.go.dev/vuln/GO-2024-0001"}]`, string(data))
+
+	req.NoError(cmd.writeMarkdown(out, notes, defaultReleaseNotesTemplate))
+	req.Equal("* example.com/widgets: v1.0.0 -> v1.1.5\n"+
+		"    * fixes [GO-2024-0001](https://pkg.go.dev/vuln/GO-2024-0001) (CVE-2024-1111) - Panic parsing widgets\n"+
+		"    * still affected by [GO-2024-0002](https://osv.dev/vulnerability/GO-2024-0002) - Widgets leak memory., fixed in v1.2.0\n",
+		out.String())
+}
+
+func TestAuditDeps(t *testing.T) {
+	req := require.New(t)
+	chdirTestRepo(t, "0.3")
+	req.NoError(os.WriteFile("go.mod", []byte(`module example.com/app
+
+require (
+	example.com/gadgets v1.0.0
+	example.com/widgets v1.0.0 // indirect
+)
+`), 0644))
+
+	audit := func(goMod string, directOnly bool, ignore ...string) (string, error) {
+		out := &bytes.Buffer{}
+		base, err := (&Options{Out: out, Err: out}).newBaseCommand("audit-deps")
+		req.NoError(err)
+		base.Config.Vulnerabilities.Db = writeVulnDb(t, false)
+		if goMod != "" {
+			base.Args = []string{goMod}
+		}
+		err = (&auditDepsCmd{BaseCommand: *base, directOnly: directOnly, ignore: ignore}).Execute()
+		return out.String(), err
+	}
+
+	out, err := audit("", false)
+	req.Error(err)
+	req.Equal(ExitCodeVulnerability, ExitCode(err))
+	req.Contains(out, "example.com/widgets v1.0.0: GO-2024-0001 (CVE-2024-1111): Panic parsing widgets, fixed in v1.0.1\n")
+	req.Contains(out, "example.com/widgets v1.0.0: GO-2024-0002: Widgets leak memory., fixed in v1.2.0\n")
+
+	_, err = audit("", true)
+	req.NoError(err)
+
+	_, err = audit("", false, "CVE-2024-1111", "GO-2024-0002")
+	req.NoError(err)
+
+	// a replacement decides the version shipped
+	req.NoError(os.WriteFile("replaced.mod", []byte(`module example.com/app
+
+require example.com/widgets v1.0.0
+
+replace example.com/widgets => example.com/widgets v1.2.0
+`), 0644))
+	_, err = audit("replaced.mod", false)
+	req.NoError(err)
+}